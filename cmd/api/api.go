@@ -8,15 +8,21 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/AlfanDutaPamungkas/Go-Social/docs"
 	"github.com/AlfanDutaPamungkas/Go-Social/internal/auth"
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/events"
 	"github.com/AlfanDutaPamungkas/Go-Social/internal/mailer"
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/markdown"
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/media"
 	ratelimiter "github.com/AlfanDutaPamungkas/Go-Social/internal/rate_limiter"
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/spam"
 	"github.com/AlfanDutaPamungkas/Go-Social/internal/store"
 	"github.com/AlfanDutaPamungkas/Go-Social/internal/store/cache"
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/unfurl"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
@@ -32,6 +38,25 @@ type application struct {
 	mailer        *mailer.SMTPMailer
 	authenticator auth.Authenticator
 	rateLimiter   ratelimiter.Limiter
+	explore       exploreCache
+	media         media.Storage
+	unfurler      *unfurl.Unfurler
+	markdown      *markdown.Renderer
+	spam          *spam.Pipeline
+	// linkPreviews queues the URLs whose preview should be fetched.
+	linkPreviews chan string
+	// views queues the post views to be aggregated by the stats worker.
+	views chan store.PostView
+	// timelineEvents queues the changes to be written to home timelines.
+	timelineEvents chan timelineEvent
+	// events carries the events streamed to users, and streams counts the
+	// streams they have open.
+	events  events.Broker
+	streams streamCounts
+	// presence tracks the users connected to the gateway, and gateway
+	// their connections to this instance.
+	presence events.Presence
+	gateway  gatewayConns
 }
 
 type config struct {
@@ -44,6 +69,171 @@ type config struct {
 	auth        authConfig
 	redisCfg    redisConfig
 	rateLimiter ratelimiter.Config
+	scheduler   schedulerConfig
+	explore     exploreConfig
+	media       mediaConfig
+	linkPreview linkPreviewConfig
+	deletion    deletionConfig
+	stats       statsConfig
+	idempotency idempotencyConfig
+	spam        spamConfig
+	timeline    timelineConfig
+	ranking     store.FeedRanking
+	events      eventsConfig
+	gateway     gatewayConfig
+	// maxPinnedPosts is how many posts a user can pin to their profile.
+	maxPinnedPosts int
+	// maxCommentDepth is how deeply replies to comments can be nested.
+	maxCommentDepth int
+	// Comments must be between minCommentLength and maxCommentLength
+	// characters long once trimmed.
+	minCommentLength int
+	maxCommentLength int
+}
+
+type exploreConfig struct {
+	refreshInterval  time.Duration
+	trendingRecent   time.Duration
+	trendingWindow   time.Duration
+	trendingMinCount int
+	popularWindow    time.Duration
+	limit            int
+}
+
+type mediaConfig struct {
+	dir            string
+	baseURL        string
+	maxUploadBytes int64
+	maxPixels      int
+	thumbnailSize  int
+	// Uploads that are not attached to a post within orphanTTL are deleted.
+	orphanTTL       time.Duration
+	cleanupInterval time.Duration
+}
+
+type linkPreviewConfig struct {
+	enabled      bool
+	timeout      time.Duration
+	maxBodyBytes int64
+	maxRedirects int
+	workers      int
+	queueSize    int
+	// The sweep fetches previews that were never queued or failed more than
+	// retryAfter ago.
+	sweepInterval time.Duration
+	retryAfter    time.Duration
+	batchSize     int
+}
+
+type deletionConfig struct {
+	// Deleted posts can be restored for retention, after which they are
+	// purged for good.
+	retention     time.Duration
+	purgeInterval time.Duration
+	batchSize     int
+}
+
+type idempotencyConfig struct {
+	// Idempotency keys can be replayed for ttl. A request that has not
+	// completed within lease is taken to have failed, and its key can be
+	// used again.
+	ttl             time.Duration
+	lease           time.Duration
+	cleanupInterval time.Duration
+}
+
+type spamConfig struct {
+	enabled bool
+	// Content is held for review, hidden from everyone but its author, or
+	// rejected once the scores of the spam checks add up to these.
+	holdScore   float64
+	hideScore   float64
+	rejectScore float64
+	// Every copy of the same content the author published within
+	// duplicateWindow scores duplicateScore.
+	duplicateWindow time.Duration
+	duplicateScore  float64
+	// Content with more than maxLinks links, or with fewer than wordsPerLink
+	// words per link, scores linkScore.
+	maxLinks     int
+	wordsPerLink int
+	linkScore    float64
+	// Accounts younger than newAccountAge that publish velocityLimit times
+	// within velocityWindow score velocityScore.
+	newAccountAge  time.Duration
+	velocityWindow time.Duration
+	velocityLimit  int
+	velocityScore  float64
+	blockedWords   []string
+	blockedScore   float64
+}
+
+type timelineConfig struct {
+	enabled bool
+	// Posts of accounts with at least fanoutLimit followers are merged into
+	// the timelines of their followers as they are read rather than written
+	// to each of them.
+	fanoutLimit int
+	// Timelines keep their newest maxLength posts. Following an account
+	// backfills up to backfill of its posts.
+	maxLength    int
+	backfill     int
+	workers      int
+	queueSize    int
+	batchSize    int
+	trimInterval time.Duration
+}
+
+type eventsConfig struct {
+	enabled bool
+	// heartbeat is how often an idle stream is written to, and retry how
+	// long clients wait before reconnecting.
+	heartbeat time.Duration
+	retry     time.Duration
+	// Streams buffer bufferSize events; a stream that falls further behind
+	// is closed. The last historySize events of every user are kept for
+	// historyTTL so that clients can resume.
+	bufferSize  int
+	historySize int
+	historyTTL  time.Duration
+	// maxStreams is how many streams a user can have open at once.
+	maxStreams int
+}
+
+type gatewayConfig struct {
+	enabled bool
+	// Connections are pinged every pingInterval and closed when no pong
+	// arrives within pongWait. Writes give up after writeWait.
+	pingInterval time.Duration
+	pongWait     time.Duration
+	writeWait    time.Duration
+	// Each connection may send messageLimit messages of up to
+	// maxMessageBytes per messageWindow and subscribe to maxTopics topics.
+	maxMessageBytes int64
+	messageLimit    int
+	messageWindow   time.Duration
+	maxTopics       int
+	// A connection that has sendBuffer messages waiting is closed for
+	// falling behind.
+	sendBuffer int
+}
+
+type statsConfig struct {
+	enabled bool
+	// A user's repeated impressions or views of a post within dedupWindow
+	// count once.
+	dedupWindow   time.Duration
+	queueSize     int
+	flushInterval time.Duration
+	batchSize     int
+	// topPosts is how many posts the user stats list.
+	topPosts int
+}
+
+type schedulerConfig struct {
+	enabled   bool
+	interval  time.Duration
+	batchSize int
 }
 
 type redisConfig struct {
@@ -96,60 +286,117 @@ func (app *application) mount() http.Handler {
 	r.Use(middleware.Recoverer)
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"http://localhost:5173"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "If-Match", "If-None-Match"},
+		ExposedHeaders:   []string{"Link", "ETag"},
 		AllowCredentials: false,
 		MaxAge:           300,
 	}))
 	r.Use(app.RateLimiterMiddleware)
 
-	r.Use(middleware.Timeout(60 * time.Second))
-
 	r.Route("/v1", func(r chi.Router) {
-		r.With(app.BasicAuthMiddleware()).Get("/health", app.healthCheckHandler)
-		r.With(app.BasicAuthMiddleware()).Get("/debug/vars", expvar.Handler().ServeHTTP)
+		// Event streams and gateway connections stay open for as long as
+		// the client listens.
+		if app.config.events.enabled {
+			r.With(app.AuthTokenMiddleware).Get("/users/me/events", app.streamEventsHandler)
+		}
+		if app.config.gateway.enabled {
+			r.Get("/gateway", app.gatewayHandler)
+		}
+
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.Timeout(60 * time.Second))
 
-		docsURL := fmt.Sprintf("%s/swagger/doc.json", app.config.addr)
-		r.Get("/swagger/*", httpSwagger.Handler(httpSwagger.URL(docsURL)))
+			r.With(app.BasicAuthMiddleware()).Get("/health", app.healthCheckHandler)
+			r.With(app.BasicAuthMiddleware()).Get("/debug/vars", expvar.Handler().ServeHTTP)
 
-		r.Route("/posts", func(r chi.Router) {
-			r.Use(app.AuthTokenMiddleware)
-			r.Post("/", app.createPostHandler)
+			docsURL := fmt.Sprintf("%s/swagger/doc.json", app.config.addr)
+			r.Get("/swagger/*", httpSwagger.Handler(httpSwagger.URL(docsURL)))
 
-			r.Route("/{postID}", func(r chi.Router) {
-				r.Use(app.postsContextMiddleware)
+			r.Route("/posts", func(r chi.Router) {
+				r.Use(app.AuthTokenMiddleware)
+				r.Use(app.IdempotencyMiddleware)
+				r.Post("/", app.createPostHandler)
 
-				r.Get("/", app.getPostHandler)
-				r.Patch("/", app.checkPostOwnership("moderator", app.updatePostHandler))
-				r.Delete("/", app.checkPostOwnership("admin", app.deletePostHandler))
+				r.Route("/{postID}", func(r chi.Router) {
+					r.Use(app.postsContextMiddleware)
 
-				r.Post("/comment", app.createCommentHandler)
-			})
-		})
+					r.Get("/", app.getPostHandler)
+					r.Patch("/", app.checkPostOwnership("moderator", app.updatePostHandler))
+					r.Delete("/", app.checkPostOwnership("admin", app.deletePostHandler))
 
-		r.Route("/users", func(r chi.Router) {
-			r.Put("/activate/{token}", app.activateUserHandler)
+					r.Post("/comment", app.createCommentHandler)
+					r.Get("/comments", app.getCommentsHandler)
+					r.Route("/comments/{commentID}", func(r chi.Router) {
+						r.Get("/", app.getCommentSubtreeHandler)
+						r.With(app.commentsContextMiddleware).Patch("/", app.updateCommentHandler)
+						r.With(app.commentsContextMiddleware).Delete("/", app.deleteCommentHandler)
+					})
 
-			r.Route("/{userID}", func(r chi.Router) {
-				r.Use(app.AuthTokenMiddleware)
+					r.Post("/poll/votes", app.votePollHandler)
+
+					r.Get("/stats", app.getPostStatsHandler)
+
+					r.Put("/content-warning", app.checkPostOwnership("moderator", app.setContentWarningHandler))
+					r.Put("/comment-lock", app.setCommentLockHandler)
 
-				r.Get("/", app.getUserHandler)
-				r.Route("/follow", func(r chi.Router) {
-					r.Put("/", app.followUserHandler)
-					r.Delete("/", app.unfollowUserHandler)
+					r.Put("/pin", app.pinPostHandler)
+					r.Delete("/pin", app.unpinPostHandler)
 				})
+
+				r.Post("/{postID}/restore", app.restorePostHandler)
+			})
+
+			r.Route("/media", func(r chi.Router) {
+				r.Get("/files/{key}", app.getMediaFileHandler)
+				r.With(app.AuthTokenMiddleware, app.IdempotencyMiddleware).Post("/", app.uploadMediaHandler)
 			})
 
-			r.Group(func(r chi.Router) {
+			r.Route("/moderation", func(r chi.Router) {
 				r.Use(app.AuthTokenMiddleware)
-				r.Get("/feed", app.getUserFeedHandler)
+				r.Use(app.IdempotencyMiddleware)
+				r.Get("/held", app.requireRole("moderator", app.getHeldContentHandler))
+				r.Put("/held/{kind}/{id}", app.requireRole("moderator", app.reviewHeldContentHandler))
+			})
+
+			r.Route("/explore", func(r chi.Router) {
+				r.Use(app.AuthTokenMiddleware)
+				r.Get("/tags/trending", app.getTrendingTagsHandler)
+				r.Get("/posts", app.getExplorePostsHandler)
+			})
+
+			r.Route("/users", func(r chi.Router) {
+				r.Put("/activate/{token}", app.activateUserHandler)
+
+				r.Route("/{userID}", func(r chi.Router) {
+					r.Use(app.AuthTokenMiddleware)
+					r.Use(app.IdempotencyMiddleware)
+
+					r.Get("/", app.getUserHandler)
+					r.Get("/posts", app.getUserPostsHandler)
+					r.Route("/follow", func(r chi.Router) {
+						r.Put("/", app.followUserHandler)
+						r.Delete("/", app.unfollowUserHandler)
+					})
+				})
+
+				r.Group(func(r chi.Router) {
+					r.Use(app.AuthTokenMiddleware)
+					r.Use(app.IdempotencyMiddleware)
+					r.Get("/feed", app.getUserFeedHandler)
+					r.Get("/drafts", app.getUserDraftsHandler)
+					r.Get("/notifications", app.getNotificationsHandler)
+					r.Post("/notifications/read", app.markNotificationsReadHandler)
+					r.Get("/me/preferences", app.getPreferencesHandler)
+					r.Patch("/me/preferences", app.updatePreferencesHandler)
+					r.Get("/me/stats", app.getUserStatsHandler)
+				})
 			})
-		})
 
-		r.Route("/authentication", func(r chi.Router) {
-			r.Post("/user", app.registerUserHandler)
-			r.Post("/token", app.createTokenHandler)
+			r.Route("/authentication", func(r chi.Router) {
+				r.Post("/user", app.registerUserHandler)
+				r.Post("/token", app.createTokenHandler)
+			})
 		})
 	})
 
@@ -169,9 +416,16 @@ func (app *application) run(mux http.Handler) error {
 		ReadTimeout:  time.Second * 10,
 		IdleTimeout:  time.Minute,
 	}
+	srv.RegisterOnShutdown(app.streams.closeAll)
 
 	shutdown := make(chan error)
 
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+
+	var jobs sync.WaitGroup
+	app.startBackgroundJobs(jobsCtx, &jobs)
+
 	go func() {
 		quit := make(chan os.Signal, 1)
 
@@ -183,7 +437,13 @@ func (app *application) run(mux http.Handler) error {
 
 		app.logger.Infow("signal caught", "signal", s.String())
 
-		shutdown <- srv.Shutdown(ctx)
+		stopJobs()
+		err := srv.Shutdown(ctx)
+		// Shutdown does not wait for hijacked connections.
+		app.gateway.drain(ctx)
+		jobs.Wait()
+
+		shutdown <- err
 	}()
 
 	app.logger.Infow("server has started", "addr", app.config.addr, "env", app.config.env)
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/store"
+)
+
+func restrictedPost(policy string, locked bool) store.Post {
+	return store.Post{
+		UserID:         42,
+		Status:         store.PostStatusPublished,
+		Visibility:     store.PostVisibilityPublic,
+		CommentPolicy:  policy,
+		CommentsLocked: locked,
+	}
+}
+
+func TestCommentPolicy(t *testing.T) {
+	app := newTestApplication(t)
+	posts := store.NewMockPostStore(restrictedPost(store.CommentPolicyEveryone, false))
+	app.store.Posts = posts
+	mux := app.mount()
+
+	comment := func() int {
+		req := newAuthenticatedRequest(t, app, http.MethodPost, "/v1/posts/1/comment", `{"content":"hello"}`)
+
+		return executeRequest(req, mux).Code
+	}
+
+	t.Run("should allow comments from everyone by default", func(t *testing.T) {
+		posts.SetPost(restrictedPost(store.CommentPolicyEveryone, false))
+
+		checkResponseCode(t, http.StatusCreated, comment())
+	})
+
+	t.Run("should reject comments when the author allows nobody", func(t *testing.T) {
+		posts.SetPost(restrictedPost(store.CommentPolicyNobody, false))
+
+		checkResponseCode(t, http.StatusForbidden, comment())
+	})
+
+	t.Run("should reject comments from users the post does not mention", func(t *testing.T) {
+		posts.SetPost(restrictedPost(store.CommentPolicyMentioned, false))
+
+		checkResponseCode(t, http.StatusForbidden, comment())
+	})
+
+	t.Run("should reject comments on a locked thread", func(t *testing.T) {
+		posts.SetPost(restrictedPost(store.CommentPolicyEveryone, true))
+
+		checkResponseCode(t, http.StatusForbidden, comment())
+	})
+
+	t.Run("should not allow a regular user to lock comments", func(t *testing.T) {
+		req := newAuthenticatedRequest(t, app, http.MethodPut, "/v1/posts/1/comment-lock", `{"locked":true}`)
+
+		checkResponseCode(t, http.StatusForbidden, executeRequest(req, mux).Code)
+	})
+
+	t.Run("should reject an unknown comment policy", func(t *testing.T) {
+		req := newAuthenticatedRequest(t, app, http.MethodPost, "/v1/posts", `{"title":"t","content":"c","comment_policy":"friends"}`)
+
+		checkResponseCode(t, http.StatusBadRequest, executeRequest(req, mux).Code)
+	})
+}
+
+type moderatorUserStore struct {
+	store.MockUserStore
+}
+
+func (s *moderatorUserStore) GetByID(_ context.Context, id int64) (*store.User, error) {
+	return &store.User{ID: id, Role: store.Role{Name: "moderator", Level: 2}}, nil
+}
+
+type lockingPostStore struct {
+	*store.MockPostStore
+	err error
+}
+
+func (s *lockingPostStore) SetCommentsLocked(_ context.Context, post *store.Post, _ *store.ModerationEntry) error {
+	if s.err != nil {
+		return s.err
+	}
+
+	post.Version++
+	return nil
+}
+
+func TestCommentLock(t *testing.T) {
+	app := newTestApplication(t)
+	app.store.Users = &moderatorUserStore{}
+	posts := &lockingPostStore{MockPostStore: store.NewMockPostStore(restrictedPost(store.CommentPolicyEveryone, false))}
+	app.store.Posts = posts
+	mux := app.mount()
+
+	lock := func(ifMatch string) *http.Response {
+		req := newAuthenticatedRequest(t, app, http.MethodPut, "/v1/posts/1/comment-lock", `{"locked":true}`)
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+
+		return executeRequest(req, mux).Result()
+	}
+
+	t.Run("should bump the post version", func(t *testing.T) {
+		res := lock(`"1-0"`)
+
+		checkResponseCode(t, http.StatusOK, res.StatusCode)
+
+		if etag := res.Header.Get("ETag"); !strings.HasPrefix(etag, `"1-1.`) {
+			t.Errorf("Expected a representation ETag of version 1. Got %q", etag)
+		}
+	})
+
+	t.Run("should reject a stale If-Match", func(t *testing.T) {
+		checkResponseCode(t, http.StatusPreconditionFailed, lock(`"1-3"`).StatusCode)
+	})
+
+	t.Run("should report a concurrent change", func(t *testing.T) {
+		posts.err = store.ErrVersionConflict
+
+		checkResponseCode(t, http.StatusConflict, lock("").StatusCode)
+	})
+
+	t.Run("should not find a post deleted while it is locked", func(t *testing.T) {
+		posts.err = store.ErrNotFound
+
+		checkResponseCode(t, http.StatusNotFound, lock("").StatusCode)
+	})
+}
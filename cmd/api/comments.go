@@ -1,19 +1,33 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
 
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/spam"
 	"github.com/AlfanDutaPamungkas/Go-Social/internal/store"
+	"github.com/go-chi/chi/v5"
 )
 
+type commentKey string
+
+const commentCtx commentKey = "comment"
+
 type CreateCommentPayload struct {
-	Content string `json:"content" validate:"required"`
+	Content  string `json:"content" validate:"required"`
+	ParentID *int64 `json:"parent_id" validate:"omitempty,gt=0"`
 }
 
 // CreateComment godoc
 //
 //	@Summary		Creates a new comment
-//	@Description	Creates a new comment in post
+//	@Description	Creates a new comment in post, or a reply to one of its comments when parent_id is set. Replies can be nested up to a maximum depth. The post's comment policy decides who may comment, and locked threads only accept comments from moderators. Comments must fit the configured length limits and go through the spam filter, which may reject them or hold them for review.
 //	@Tags			comments
 //	@Accept			json
 //	@Produce		json
@@ -21,6 +35,8 @@ type CreateCommentPayload struct {
 //	@Param			body	body		CreateCommentPayload	true	"Created comment data"
 //	@Success		201		{object}	store.Comment
 //	@Failure		400		{object}	error	"Invalid request payload"
+//	@Failure		403		{object}	error	"Comments are restricted or locked"
+//	@Failure		422		{object}	error	"Rejected as spam"
 //	@Failure		404		{object}	error	"Post not found"
 //	@Failure		500		{object}	error
 //	@Security		ApiKeyAuth
@@ -40,19 +56,427 @@ func (app *application) createCommentHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	allowed, err := app.canComment(r.Context(), user, post)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if !allowed {
+		app.forbiddenResponse(w, r)
+		return
+	}
+
+	content, err := app.commentContent(payload.Content)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
 	comment := &store.Comment{
-		UserID:  user.ID,
-		PostID:  post.ID,
-		Content: payload.Content,
+		UserID:   user.ID,
+		PostID:   post.ID,
+		Content:  content,
+		Entities: store.ParseEntities(content),
+		Replies:  []store.Comment{},
+	}
+
+	// The authors of the post and of the comment replied to are told about
+	// the comment, unless they wrote it.
+	recipients := []int64{post.UserID}
+
+	if payload.ParentID != nil {
+		parent, err := app.store.Comments.GetByID(r.Context(), post.ID, *payload.ParentID)
+		if err == nil && parent.ReviewStatus != store.ReviewApproved && parent.UserID != user.ID {
+			err = store.ErrNotFound
+		}
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.badRequestResponse(w, r, fmt.Errorf("comment %d does not exist on this post", *payload.ParentID))
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		if parent.Depth >= app.config.maxCommentDepth {
+			app.badRequestResponse(w, r, fmt.Errorf("replies can be nested at most %d levels deep", app.config.maxCommentDepth))
+			return
+		}
+
+		comment.ParentID = &parent.ID
+		comment.Depth = parent.Depth + 1
+
+		if parent.UserID != post.UserID {
+			recipients = append(recipients, parent.UserID)
+		}
+	}
+
+	reviewStatus, reasons, ok := app.screenContent(w, r, comment.UserID, spamContent("", comment.Content, comment.Entities))
+	if !ok {
+		return
 	}
+	comment.ReviewStatus = reviewStatus
+	comment.SpamReasons = reasons
 
 	if err := app.store.Comments.Create(r.Context(), comment); err != nil {
-		app.internalServerError(w, r, err)
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.badRequestResponse(w, r, errors.New("the parent comment no longer exists"))
+		default:
+			app.internalServerError(w, r, err)
+		}
 		return
 	}
 
+	comment.ContentHTML = app.markdown.Render(comment.Content, comment.Entities)
+	// Comments are shown with the same author fields as when they are listed.
+	comment.User = store.User{ID: user.ID, Username: user.Username, CreatedAt: user.CreatedAt}
+
+	if comment.ReviewStatus == store.ReviewApproved {
+		recipients = slices.DeleteFunc(recipients, func(id int64) bool { return id == user.ID })
+		app.publishEvent(r.Context(), eventComment, commentEvent{PostID: post.ID, CommentID: comment.ID, AuthorID: user.ID}, recipients...)
+		app.broadcastEvent(r.Context(), postTopic(post.ID), eventComment, user.ID, comment)
+	}
+
 	if err := app.jsonResponse(w, http.StatusCreated, comment); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 }
+
+// commentContent trims content and checks it against the configured
+// comment length limits.
+func (app *application) commentContent(content string) (string, error) {
+	content = strings.TrimSpace(content)
+
+	length := utf8.RuneCountInString(content)
+	if length < app.config.minCommentLength || length > app.config.maxCommentLength {
+		return "", fmt.Errorf("comments must be between %d and %d characters long", app.config.minCommentLength, app.config.maxCommentLength)
+	}
+
+	return content, nil
+}
+
+func newCommentQuery() *store.PaginatedCommentQuery {
+	return &store.PaginatedCommentQuery{
+		Limit:   20,
+		Sort:    store.CommentSortNewest,
+		Replies: 3,
+	}
+}
+
+// GetComments godoc
+//
+//	@Summary		List a post's comments
+//	@Description	Lists the top-level comments of a post with cursor pagination, each with its oldest direct replies. Replies beyond those, and replies to replies, are fetched with the comment endpoint; every comment carries its reply count. Top comments are the ones with the most replies.
+//	@Tags			comments
+//	@Produce		json
+//	@Param			postID	path		int		true	"Post ID"
+//	@Param			limit	query		int		false	"Number of comments to retrieve (1-50)"	default(20)
+//	@Param			sort	query		string	false	"Sort order"							default(newest)	Enums(newest, oldest, top)
+//	@Param			cursor	query		string	false	"Cursor returned as next_cursor by the previous page, with the same sort"
+//	@Param			replies	query		int		false	"Number of replies to include under each comment (0-10)"	default(3)
+//	@Success		200		{array}		store.Comment
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error	"Post not found"
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{postID}/comments [get]
+func (app *application) getCommentsHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromCtx(r)
+	post := getPostFromCtx(r)
+
+	q, err := newCommentQuery().Parse(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(q); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	comments, err := app.store.Comments.GetPage(r.Context(), post.ID, user.ID, q)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.renderComments(comments)
+
+	var next string
+	if cursor := q.NextCursor(comments); cursor != nil {
+		next = cursor.Encode()
+	}
+
+	if err := app.paginatedJSONResponse(w, r, http.StatusOK, comments, next, ""); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// GetCommentSubtree godoc
+//
+//	@Summary		Get a comment and its replies
+//	@Description	Returns a comment of a post with all of its nested replies, oldest first
+//	@Tags			comments
+//	@Produce		json
+//	@Param			postID		path		int	true	"Post ID"
+//	@Param			commentID	path		int	true	"Comment ID"
+//	@Success		200			{object}	store.Comment
+//	@Header			200			{string}	ETag	"Version of the comment, for If-Match"
+//	@Failure		400			{object}	error
+//	@Failure		404			{object}	error	"Comment not found"
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{postID}/comments/{commentID} [get]
+func (app *application) getCommentSubtreeHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromCtx(r)
+	post := getPostFromCtx(r)
+
+	commentID, err := strconv.ParseInt(chi.URLParam(r, "commentID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	comment, err := app.store.Comments.GetSubtree(r.Context(), post.ID, commentID, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	comments := []store.Comment{*comment}
+	app.renderComments(comments)
+
+	w.Header().Set("ETag", commentETag(comment))
+
+	if err := app.jsonResponse(w, http.StatusOK, comments[0]); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+type UpdateCommentPayload struct {
+	Content string `json:"content" validate:"required"`
+}
+
+// UpdateComment godoc
+//
+//	@Summary		Edit a comment
+//	@Description	Edits the content of a comment. Authors can edit their own comments and moderators anyone's; moderator edits are recorded in the moderation log. Edited content is screened by the spam filter again, which can hold or hide the comment.
+//	@Tags			comments
+//	@Accept			json
+//	@Produce		json
+//	@Param			postID		path		int						true	"Post ID"
+//	@Param			commentID	path		int						true	"Comment ID"
+//	@Param			If-Match	header		string					false	"ETag the client last saw"
+//	@Param			body		body		UpdateCommentPayload	true	"Updated comment"
+//	@Success		200			{object}	store.Comment
+//	@Failure		400			{object}	error	"Invalid request payload"
+//	@Failure		403			{object}	error
+//	@Failure		404			{object}	error	"Comment not found"
+//	@Failure		409			{object}	error	"Comment was modified concurrently"
+//	@Failure		412			{object}	error	"Comment was modified"
+//	@Failure		422			{object}	error	"Rejected as spam"
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{postID}/comments/{commentID} [patch]
+func (app *application) updateCommentHandler(w http.ResponseWriter, r *http.Request) {
+	comment := getCommentFromCtx(r)
+
+	entry, ok := app.authorizeCommentAction(w, r, store.ModerationCommentEdit, false)
+	if !ok {
+		return
+	}
+
+	if !app.checkCommentPrecondition(w, r, comment) {
+		return
+	}
+
+	var payload UpdateCommentPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if entry != nil {
+		entry.Details = map[string]any{"previous_content": comment.Content}
+	}
+
+	content, err := app.commentContent(payload.Content)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	edited := content != comment.Content
+	reviewStatus := comment.ReviewStatus
+
+	comment.Content = content
+	comment.Entities = store.ParseEntities(content)
+
+	// Like edited posts, edited comments are screened again.
+	if edited {
+		content := spamContent("", comment.Content, comment.Entities)
+		content.Edit = spam.Edit{CommentID: comment.ID, TextChanged: true}
+
+		screened, reasons, ok := app.screenContent(w, r, comment.UserID, content)
+		if !ok {
+			return
+		}
+		comment.ReviewStatus = screened
+		comment.SpamReasons = reasons
+	}
+
+	if err := app.store.Comments.Update(r.Context(), comment, entry); err != nil {
+		app.commentWriteError(w, r, err)
+		return
+	}
+
+	comment.ContentHTML = app.markdown.Render(comment.Content, comment.Entities)
+	comment.Replies = []store.Comment{}
+
+	userID := getUserFromCtx(r).ID
+	switch {
+	case comment.ReviewStatus == store.ReviewApproved:
+		app.broadcastEvent(r.Context(), postTopic(comment.PostID), eventCommentUpdated, userID, comment)
+	case reviewStatus == store.ReviewApproved:
+		// Viewers of the thread drop the comment the filter just held.
+		app.broadcastEvent(r.Context(), postTopic(comment.PostID), eventCommentDeleted, userID, commentDeletedEvent{CommentID: comment.ID})
+	}
+
+	w.Header().Set("ETag", commentETag(comment))
+
+	if err := app.jsonResponse(w, http.StatusOK, comment); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// DeleteComment godoc
+//
+//	@Summary		Delete a comment
+//	@Description	Deletes a comment along with its replies. Authors can delete their own comments, post authors the comments on their posts and moderators anyone's; moderator deletions are recorded in the moderation log.
+//	@Tags			comments
+//	@Param			postID		path	int		true	"Post ID"
+//	@Param			commentID	path	int		true	"Comment ID"
+//	@Param			If-Match	header	string	false	"ETag the client last saw"
+//	@Success		204
+//	@Failure		403	{object}	error
+//	@Failure		404	{object}	error	"Comment not found"
+//	@Failure		409	{object}	error	"Comment was modified concurrently"
+//	@Failure		412	{object}	error	"Comment was modified"
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{postID}/comments/{commentID} [delete]
+func (app *application) deleteCommentHandler(w http.ResponseWriter, r *http.Request) {
+	comment := getCommentFromCtx(r)
+
+	entry, ok := app.authorizeCommentAction(w, r, store.ModerationCommentDelete, true)
+	if !ok {
+		return
+	}
+
+	if !app.checkCommentPrecondition(w, r, comment) {
+		return
+	}
+
+	if err := app.store.Comments.Delete(r.Context(), comment, entry); err != nil {
+		app.commentWriteError(w, r, err)
+		return
+	}
+
+	app.broadcastEvent(r.Context(), postTopic(comment.PostID), eventCommentDeleted, getUserFromCtx(r).ID, commentDeletedEvent{CommentID: comment.ID})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// authorizeCommentAction lets the comment author act on the comment, and
+// the post author too when allowPostAuthor is set. Anyone else needs the
+// moderator role, in which case the returned entry records the action for
+// the moderation log. It responds with 403 and returns false otherwise.
+func (app *application) authorizeCommentAction(w http.ResponseWriter, r *http.Request, action string, allowPostAuthor bool) (*store.ModerationEntry, bool) {
+	user := getUserFromCtx(r)
+	post := getPostFromCtx(r)
+	comment := getCommentFromCtx(r)
+
+	if comment.UserID == user.ID || (allowPostAuthor && post.UserID == user.ID) {
+		return nil, true
+	}
+
+	allowed, err := app.checkRolePrecedence(r.Context(), user, "moderator")
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return nil, false
+	}
+
+	if !allowed {
+		app.forbiddenResponse(w, r)
+		return nil, false
+	}
+
+	return &store.ModerationEntry{ModeratorID: user.ID, Action: action}, true
+}
+
+func (app *application) commentWriteError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		app.notFoundResponse(w, r, err)
+	case errors.Is(err, store.ErrVersionConflict) && r.Header.Get("If-Match") != "":
+		app.preconditionFailedResponse(w, r, err)
+	case errors.Is(err, store.ErrVersionConflict):
+		app.conflictResponse(w, r, err)
+	default:
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *application) checkCommentPrecondition(w http.ResponseWriter, r *http.Request, comment *store.Comment) bool {
+	return app.checkPrecondition(w, r, commentETag(comment), fmt.Errorf("comment %d is at version %d", comment.ID, comment.Version))
+}
+
+func commentETag(comment *store.Comment) string {
+	return fmt.Sprintf(`"c%d-%d"`, comment.ID, comment.Version)
+}
+
+func (app *application) commentsContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		post := getPostFromCtx(r)
+
+		commentID, err := strconv.ParseInt(chi.URLParam(r, "commentID"), 10, 64)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		comment, err := app.store.Comments.GetByID(r.Context(), post.ID, commentID)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notFoundResponse(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), commentCtx, comment)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func getCommentFromCtx(r *http.Request) *store.Comment {
+	comment, _ := r.Context().Value(commentCtx).(*store.Comment)
+	return comment
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/events"
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/store"
+)
+
+type deepCommentStore struct {
+	store.MockCommentStore
+}
+
+func (s *deepCommentStore) GetByID(_ context.Context, postID, commentID int64) (*store.Comment, error) {
+	if commentID == 404 {
+		return nil, store.ErrNotFound
+	}
+
+	// Comment 2 sits at the maximum depth of the test config.
+	return &store.Comment{ID: commentID, PostID: postID, Depth: int(commentID)}, nil
+}
+
+func TestCommentReplies(t *testing.T) {
+	app := newTestApplication(t)
+	app.store.Comments = &deepCommentStore{}
+	mux := app.mount()
+
+	reply := func(body string) int {
+		req := newAuthenticatedRequest(t, app, http.MethodPost, "/v1/posts/1/comment", body)
+
+		return executeRequest(req, mux).Code
+	}
+
+	t.Run("should create a reply", func(t *testing.T) {
+		checkResponseCode(t, http.StatusCreated, reply(`{"content":"hi","parent_id":1}`))
+	})
+
+	t.Run("should reject a reply beyond the maximum depth", func(t *testing.T) {
+		checkResponseCode(t, http.StatusBadRequest, reply(`{"content":"hi","parent_id":2}`))
+	})
+
+	t.Run("should reject a reply to a missing comment", func(t *testing.T) {
+		checkResponseCode(t, http.StatusBadRequest, reply(`{"content":"hi","parent_id":404}`))
+	})
+
+	t.Run("should return a comment's subtree", func(t *testing.T) {
+		req := newAuthenticatedRequest(t, app, http.MethodGet, "/v1/posts/1/comments/1", "")
+
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("should cap the replies listed under each comment", func(t *testing.T) {
+		for query, want := range map[string]int{"replies=0": http.StatusOK, "replies=10": http.StatusOK, "replies=11": http.StatusBadRequest} {
+			req := newAuthenticatedRequest(t, app, http.MethodGet, "/v1/posts/1/comments?"+query, "")
+
+			checkResponseCode(t, want, executeRequest(req, mux).Code)
+		}
+	})
+}
+
+type otherUsersCommentStore struct {
+	store.MockCommentStore
+}
+
+func (s *otherUsersCommentStore) GetByID(_ context.Context, postID, commentID int64) (*store.Comment, error) {
+	return &store.Comment{ID: commentID, PostID: postID, UserID: 42, Version: 3}, nil
+}
+
+func TestCommentEdits(t *testing.T) {
+	app := newTestApplication(t)
+	mux := app.mount()
+
+	request := func(method, body, ifMatch string) int {
+		req := newAuthenticatedRequest(t, app, method, "/v1/posts/1/comments/7", body)
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+
+		return executeRequest(req, mux).Code
+	}
+
+	t.Run("should allow the author to edit their comment", func(t *testing.T) {
+		checkResponseCode(t, http.StatusOK, request(http.MethodPatch, `{"content":"fixed"}`, `"c7-0"`))
+	})
+
+	t.Run("should reject edits of a stale version", func(t *testing.T) {
+		checkResponseCode(t, http.StatusPreconditionFailed, request(http.MethodPatch, `{"content":"fixed"}`, `"c7-1"`))
+	})
+
+	app.store.Comments = &otherUsersCommentStore{}
+
+	t.Run("should not allow editing someone else's comment", func(t *testing.T) {
+		checkResponseCode(t, http.StatusForbidden, request(http.MethodPatch, `{"content":"fixed"}`, ""))
+	})
+
+	t.Run("should allow the post author to delete comments on their post", func(t *testing.T) {
+		checkResponseCode(t, http.StatusNoContent, request(http.MethodDelete, "", `"c7-3"`))
+	})
+}
+
+func TestCommentListing(t *testing.T) {
+	app := newTestApplication(t)
+	mux := app.mount()
+
+	list := func(query string) int {
+		req := newAuthenticatedRequest(t, app, http.MethodGet, "/v1/posts/1/comments"+query, "")
+
+		return executeRequest(req, mux).Code
+	}
+
+	t.Run("should list comments", func(t *testing.T) {
+		checkResponseCode(t, http.StatusOK, list("?sort=top&limit=10"))
+	})
+
+	t.Run("should reject an unknown sort", func(t *testing.T) {
+		checkResponseCode(t, http.StatusBadRequest, list("?sort=best"))
+	})
+
+	t.Run("should reject a cursor from another sort order", func(t *testing.T) {
+		cursor := store.CommentCursor{Sort: store.CommentSortTop, Key: 3, ID: 9}.Encode()
+
+		checkResponseCode(t, http.StatusBadRequest, list("?sort=oldest&cursor="+cursor))
+	})
+}
+
+type namedUserStore struct {
+	store.MockUserStore
+}
+
+func (s *namedUserStore) GetByID(_ context.Context, id int64) (*store.User, error) {
+	return &store.User{ID: id, Username: "alice", Email: "alice@example.com"}, nil
+}
+
+func TestCommentBroadcast(t *testing.T) {
+	app := newTestApplication(t)
+	app.store.Users = &namedUserStore{}
+	app.events = events.NewMemoryBroker(4, 0)
+	mux := app.mount()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	subscription := app.events.Join(ctx, postTopic(1))
+	defer subscription.Close()
+
+	t.Run("should broadcast the comment with its author's public fields", func(t *testing.T) {
+		req := newAuthenticatedRequest(t, app, http.MethodPost, "/v1/posts/1/comment", `{"content":"hello"}`)
+
+		checkResponseCode(t, http.StatusCreated, executeRequest(req, mux).Code)
+
+		select {
+		case event := <-subscription.Events():
+			data := string(event.Data)
+			if !strings.Contains(data, `"username":"alice"`) || strings.Contains(data, "alice@example.com") {
+				t.Errorf("Expected the author's username without their email. Got %s", data)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Expected the comment to be broadcast")
+		}
+	})
+}
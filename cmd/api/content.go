@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/markdown"
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/store"
+)
+
+// newMarkdownRenderer links mentions and hashtags to their pages on the
+// frontend.
+func newMarkdownRenderer(frontendURL string) *markdown.Renderer {
+	return markdown.New(markdown.Links{
+		Mention: func(e store.Entity) string {
+			return fmt.Sprintf("%s/users/%d", frontendURL, e.UserID)
+		},
+		Hashtag: func(tag string) string {
+			return frontendURL + "/tags/" + url.PathEscape(tag)
+		},
+	})
+}
+
+// renderPost fills in the HTML of the content of post and of its comments.
+func (app *application) renderPost(post *store.Post) {
+	post.ContentHTML = app.markdown.Render(post.Content, post.Entities)
+	app.renderComments(post.Comments)
+}
+
+// renderComments fills in the HTML of the content of comments and of their
+// replies.
+func (app *application) renderComments(comments []store.Comment) {
+	for i := range comments {
+		c := &comments[i]
+		c.ContentHTML = app.markdown.Render(c.Content, c.Entities)
+		app.renderComments(c.Replies)
+	}
+}
+
+func (app *application) renderPosts(posts []store.PostWithMetadata) {
+	for i := range posts {
+		app.renderPost(&posts[i].Post)
+	}
+}
+
+// collapsePosts marks the posts viewer should see behind their content
+// warning.
+func collapsePosts(posts []store.PostWithMetadata, viewer *store.User) {
+	for i := range posts {
+		posts[i].Collapsed = posts[i].CollapsedFor(viewer)
+	}
+}
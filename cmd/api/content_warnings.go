@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/store"
+)
+
+type ContentWarningPayload struct {
+	ContentWarning string `json:"content_warning" validate:"max=200"`
+	Sensitive      bool   `json:"sensitive"`
+	Reason         string `json:"reason" validate:"max=500"`
+}
+
+// SetContentWarning godoc
+//
+//	@Summary		Set a post's content warning
+//	@Description	Sets or clears the content warning and sensitive flag of a post. Moderators can apply them to other users' posts; those changes are recorded in the moderation log along with the reason.
+//	@Tags			posts
+//	@Accept			json
+//	@Produce		json
+//	@Param			postID		path		int						true	"Post ID"
+//	@Param			If-Match	header		string					false	"ETag the client last saw"
+//	@Param			body		body		ContentWarningPayload	true	"Content warning"
+//	@Success		200			{object}	store.Post
+//	@Failure		400			{object}	error	"Invalid request payload"
+//	@Failure		403			{object}	error
+//	@Failure		404			{object}	error	"Post not found"
+//	@Failure		409			{object}	error	"Post was modified concurrently"
+//	@Failure		412			{object}	error	"Post was modified"
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{postID}/content-warning [put]
+func (app *application) setContentWarningHandler(w http.ResponseWriter, r *http.Request) {
+	post := getPostFromCtx(r)
+	user := getUserFromCtx(r)
+
+	if !app.checkPostPrecondition(w, r, post) {
+		return
+	}
+
+	var payload ContentWarningPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var entry *store.ModerationEntry
+	if post.UserID != user.ID {
+		entry = &store.ModerationEntry{
+			ModeratorID: user.ID,
+			Action:      store.ModerationContentWarning,
+			Reason:      strings.TrimSpace(payload.Reason),
+			Details: map[string]any{
+				"previous_content_warning": post.ContentWarning,
+				"previous_sensitive":       post.Sensitive,
+				"content_warning":          strings.TrimSpace(payload.ContentWarning),
+				"sensitive":                payload.Sensitive,
+			},
+		}
+	}
+
+	post.ContentWarning = strings.TrimSpace(payload.ContentWarning)
+	post.Sensitive = payload.Sensitive
+
+	if err := app.store.Posts.SetContentWarning(r.Context(), post, entry); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		case errors.Is(err, store.ErrVersionConflict) && r.Header.Get("If-Match") != "":
+			app.preconditionFailedResponse(w, r, err)
+		case errors.Is(err, store.ErrVersionConflict):
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.renderPost(post)
+	post.Collapsed = post.CollapsedFor(user)
+
+	app.postResponse(w, r, post)
+}
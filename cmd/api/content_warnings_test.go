@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/store"
+)
+
+// deletingPostStore deletes the post while a content warning is set.
+type deletingPostStore struct {
+	store.MockPostStore
+}
+
+func (s *deletingPostStore) SetContentWarning(context.Context, *store.Post, *store.ModerationEntry) error {
+	return store.ErrNotFound
+}
+
+func TestContentWarnings(t *testing.T) {
+	app := newTestApplication(t)
+	warned := store.NewMockPostStore(store.Post{
+		UserID:         42,
+		Status:         store.PostStatusPublished,
+		Visibility:     store.PostVisibilityPublic,
+		ContentWarning: "spoilers",
+	})
+	app.store.Posts = warned
+	mux := app.mount()
+
+	t.Run("should collapse a post with a content warning", func(t *testing.T) {
+		req := newAuthenticatedRequest(t, app, http.MethodGet, "/v1/posts/1", "")
+
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		var res struct {
+			Data store.Post `json:"data"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&res); err != nil {
+			t.Fatal(err)
+		}
+
+		if !res.Data.Collapsed {
+			t.Error("Expected the post to be collapsed")
+		}
+	})
+
+	t.Run("should not allow a regular user to warn someone else's post", func(t *testing.T) {
+		req := newAuthenticatedRequest(t, app, http.MethodPut, "/v1/posts/1/content-warning", `{"sensitive":true}`)
+
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, http.StatusForbidden, rr.Code)
+	})
+
+	t.Run("should not find a post deleted while it is warned", func(t *testing.T) {
+		app.store.Posts = &deletingPostStore{}
+		defer func() { app.store.Posts = warned }()
+
+		req := newAuthenticatedRequest(t, app, http.MethodPut, "/v1/posts/1/content-warning", `{"sensitive":true}`)
+
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("should reject an unknown sensitive content preference", func(t *testing.T) {
+		req := newAuthenticatedRequest(t, app, http.MethodPatch, "/v1/users/me/preferences", `{"sensitive_content":"blur"}`)
+
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should update the sensitive content preference", func(t *testing.T) {
+		req := newAuthenticatedRequest(t, app, http.MethodPatch, "/v1/users/me/preferences", `{"sensitive_content":"expand"}`)
+
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, http.StatusOK, rr.Code)
+	})
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/events"
+)
+
+// The types of the events streamed to users.
+const (
+	eventPost    = "post"
+	eventComment = "comment"
+	eventFollow  = "follow"
+)
+
+// postEvent tells a follower that AuthorID published a post.
+type postEvent struct {
+	PostID   int64 `json:"post_id"`
+	AuthorID int64 `json:"author_id"`
+}
+
+// commentEvent tells the author of a post or of the comment replied to that
+// AuthorID commented.
+type commentEvent struct {
+	PostID    int64 `json:"post_id"`
+	CommentID int64 `json:"comment_id"`
+	AuthorID  int64 `json:"author_id"`
+}
+
+// followEvent tells a user that FollowerID followed them.
+type followEvent struct {
+	FollowerID int64 `json:"follower_id"`
+}
+
+// publishEvent sends an event of type kind carrying data to each of
+// userIDs. Events are best-effort, so failures are logged rather than
+// returned.
+func (app *application) publishEvent(ctx context.Context, kind string, data any, userIDs ...int64) {
+	if !app.config.events.enabled || app.events == nil || len(userIDs) == 0 {
+		return
+	}
+
+	batch := make([]events.Event, 0, len(userIDs))
+	for _, userID := range userIDs {
+		event, err := events.New(userID, kind, data)
+		if err != nil {
+			app.logger.Errorw("failed to encode event", "event", kind, "error", err.Error())
+			return
+		}
+		batch = append(batch, event)
+	}
+
+	if err := app.events.Publish(ctx, batch...); err != nil {
+		app.logger.Errorw("failed to publish events", "event", kind, "error", err.Error())
+	}
+}
+
+// broadcastEvent sends an event of type kind carrying data, caused by
+// userID, to the gateway connections subscribed to topic. Like
+// publishEvent, it logs failures.
+func (app *application) broadcastEvent(ctx context.Context, topic, kind string, userID int64, data any) {
+	if app.events == nil {
+		return
+	}
+
+	event, err := events.New(userID, kind, data)
+	if err != nil {
+		app.logger.Errorw("failed to encode event", "event", kind, "error", err.Error())
+		return
+	}
+	event.Topic = topic
+
+	if err := app.events.Broadcast(ctx, event); err != nil {
+		app.logger.Errorw("failed to broadcast event", "event", kind, "topic", topic, "error", err.Error())
+	}
+}
+
+// streamCounts counts the open event streams of every user and ends them
+// when the server shuts down, which would otherwise wait for them.
+type streamCounts struct {
+	mu     sync.Mutex
+	counts map[int64]int
+	done   chan struct{}
+	closed bool
+}
+
+// closing returns a channel that is closed once the streams should end.
+func (s *streamCounts) closing() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.done == nil {
+		s.done = make(chan struct{})
+	}
+
+	return s.done
+}
+
+// closeAll ends the open streams and refuses new ones.
+func (s *streamCounts) closeAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.done == nil {
+		s.done = make(chan struct{})
+	}
+
+	if !s.closed {
+		s.closed = true
+		close(s.done)
+	}
+}
+
+// acquire counts a stream for userID unless they already have limit open.
+func (s *streamCounts) acquire(userID int64, limit int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.counts == nil {
+		s.counts = make(map[int64]int)
+	}
+
+	if s.closed || s.counts[userID] >= limit {
+		return false
+	}
+	s.counts[userID]++
+
+	return true
+}
+
+func (s *streamCounts) release(userID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.counts[userID]--
+	if s.counts[userID] <= 0 {
+		delete(s.counts, userID)
+	}
+}
+
+// StreamEvents godoc
+//
+//	@Summary		Stream events
+//	@Description	Streams the events relevant to the authenticated user as server-sent events: new posts of the accounts they follow (except accounts too large to be fanned out), comments on their posts and replies to their comments, and new followers. Each event has an id, a type (post, comment or follow) and JSON data. A client that reconnects with the Last-Event-ID header receives the events it missed that are still kept. A comment line is sent every heartbeat interval to keep the connection open. A client that falls too far behind is disconnected and should reconnect with the last ID it received.
+//	@Tags			users
+//	@Produce		text/event-stream
+//	@Param			Last-Event-ID	header	string	false	"ID of the last event received"
+//	@Success		200
+//	@Failure		400	{object}	error	"Unknown Last-Event-ID"
+//	@Failure		429	{object}	error	"Too many open streams"
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/events [get]
+func (app *application) streamEventsHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromCtx(r)
+	cfg := app.config.events
+
+	if !app.streams.acquire(user.ID, cfg.maxStreams) {
+		app.rateLimiterExceededResponse(w, r, cfg.retry.String())
+		return
+	}
+	defer app.streams.release(user.ID)
+
+	subscription, err := app.events.Subscribe(r.Context(), user.ID, strings.TrimSpace(r.Header.Get("Last-Event-ID")))
+	if err != nil {
+		switch {
+		case errors.Is(err, events.ErrInvalidEventID):
+			app.badRequestResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+	defer subscription.Close()
+
+	// The stream outlives the write timeout of the server.
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := fmt.Fprintf(w, "retry: %d\n\n", cfg.retry.Milliseconds()); err != nil {
+		return
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	closing := app.streams.closing()
+
+	heartbeat := time.NewTicker(cfg.heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-closing:
+			return
+		case <-heartbeat.C:
+			_, err = fmt.Fprint(w, ": heartbeat\n\n")
+		case event, ok := <-subscription.Events():
+			if !ok {
+				if errors.Is(subscription.Err(), events.ErrLagged) {
+					app.logger.Infow("closed lagging event stream", "user", user.ID)
+				}
+				return
+			}
+			_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Data)
+		}
+
+		if err != nil {
+			return
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
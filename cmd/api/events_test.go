@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/events"
+)
+
+func TestStreamEvents(t *testing.T) {
+	app := newTestApplication(t)
+	app.config.events = eventsConfig{enabled: true, heartbeat: time.Hour, retry: time.Second, maxStreams: 1}
+
+	broker := events.NewMemoryBroker(4, 10)
+	app.events = broker
+
+	server := httptest.NewServer(app.mount())
+	defer server.Close()
+
+	open := func(ctx context.Context, lastEventID string) *http.Response {
+		req := newAuthenticatedRequest(t, app, http.MethodGet, server.URL+"/v1/users/me/events", "").WithContext(ctx)
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return res
+	}
+
+	// readEvent returns the next event of the stream as its field lines.
+	readEvent := func(scanner *bufio.Scanner) []string {
+		var lines []string
+		for scanner.Scan() {
+			line := scanner.Text()
+			if line == "" {
+				if len(lines) > 0 {
+					return lines
+				}
+				continue
+			}
+			if !strings.HasPrefix(line, "retry:") {
+				lines = append(lines, line)
+			}
+		}
+		t.Fatalf("Expected an event. The stream ended: %v", scanner.Err())
+		return nil
+	}
+
+	// The mock store authenticates every token as user 0.
+	app.publishEvent(context.Background(), eventFollow, followEvent{FollowerID: 7}, 0)
+
+	t.Run("should reject an unknown Last-Event-ID", func(t *testing.T) {
+		res := open(context.Background(), "abc")
+		defer res.Body.Close()
+
+		checkResponseCode(t, http.StatusBadRequest, res.StatusCode)
+	})
+
+	t.Run("should stream the events after Last-Event-ID", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		res := open(ctx, "0")
+		defer res.Body.Close()
+
+		checkResponseCode(t, http.StatusOK, res.StatusCode)
+
+		if contentType := res.Header.Get("Content-Type"); contentType != "text/event-stream" {
+			t.Errorf("Expected an event stream. Got %q", contentType)
+		}
+
+		scanner := bufio.NewScanner(res.Body)
+
+		want := []string{"id: 1", "event: follow", `data: {"follower_id":7}`}
+		if got := readEvent(scanner); strings.Join(got, "\n") != strings.Join(want, "\n") {
+			t.Errorf("Expected %q. Got %q", want, got)
+		}
+
+		app.publishEvent(context.Background(), eventComment, commentEvent{PostID: 1, CommentID: 2, AuthorID: 7}, 0)
+
+		if got := readEvent(scanner); got[0] != "id: 2" || got[1] != "event: comment" {
+			t.Errorf("Expected the comment. Got %q", got)
+		}
+
+		t.Run("should limit the open streams of a user", func(t *testing.T) {
+			res := open(context.Background(), "")
+			defer res.Body.Close()
+
+			checkResponseCode(t, http.StatusTooManyRequests, res.StatusCode)
+		})
+	})
+}
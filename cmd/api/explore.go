@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/store"
+)
+
+// exploreCache holds the last computed explore results. They are shared by
+// every user, so they are refreshed in the background rather than per
+// request.
+type exploreCache struct {
+	sync.RWMutex
+	trendingTags []store.TrendingTag
+	popularPosts []store.PostWithMetadata
+	refreshedAt  time.Time
+
+	// refreshing is held while the results are computed, so that they are
+	// only computed once at a time.
+	refreshing sync.Mutex
+}
+
+// GetTrendingTags godoc
+//
+//	@Summary		List trending tags
+//	@Description	Lists the tags whose usage in the last hour grew the most compared to the last day
+//	@Tags			explore
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{array}		store.TrendingTag
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/explore/tags/trending [get]
+func (app *application) getTrendingTagsHandler(w http.ResponseWriter, r *http.Request) {
+	if err := app.ensureExploreFresh(r.Context()); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.explore.RLock()
+	tags := app.explore.trendingTags
+	app.explore.RUnlock()
+
+	if err := app.jsonResponse(w, http.StatusOK, tags); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// GetExplorePosts godoc
+//
+//	@Summary		List popular posts
+//	@Description	Lists popular public posts ranked by comments with time decay
+//	@Tags			explore
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{array}		store.PostWithMetadata
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/explore/posts [get]
+func (app *application) getExplorePostsHandler(w http.ResponseWriter, r *http.Request) {
+	if err := app.ensureExploreFresh(r.Context()); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.explore.RLock()
+	// The cached posts are shared between requests, so they are copied
+	// before being tailored to the user.
+	posts := slices.Clone(app.explore.popularPosts)
+	app.explore.RUnlock()
+
+	user := getUserFromCtx(r)
+	collapsePosts(posts, user)
+	app.recordImpressions(user, posts)
+
+	if err := app.jsonResponse(w, http.StatusOK, posts); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// ensureExploreFresh computes the explore results on the spot when they
+// were never computed. When the background refresh has fallen behind, the
+// stale results are served while they are refreshed.
+func (app *application) ensureExploreFresh(ctx context.Context) error {
+	refreshedAt := app.exploreRefreshedAt()
+	if time.Since(refreshedAt) <= 2*app.config.explore.refreshInterval {
+		return nil
+	}
+
+	if refreshedAt.IsZero() {
+		return app.refreshExplore(ctx)
+	}
+
+	// A refresh that is already running will do.
+	if app.explore.refreshing.TryLock() {
+		go func() {
+			defer app.explore.refreshing.Unlock()
+
+			if err := app.computeExplore(context.WithoutCancel(ctx)); err != nil {
+				app.logger.Errorw("failed to refresh explore", "error", err.Error())
+			}
+		}()
+	}
+
+	return nil
+}
+
+func (app *application) exploreRefreshedAt() time.Time {
+	app.explore.RLock()
+	defer app.explore.RUnlock()
+
+	return app.explore.refreshedAt
+}
+
+// refreshExplore computes the explore results, or waits for the refresh
+// that is already running.
+func (app *application) refreshExplore(ctx context.Context) error {
+	started := time.Now()
+
+	app.explore.refreshing.Lock()
+	defer app.explore.refreshing.Unlock()
+
+	if app.exploreRefreshedAt().After(started) {
+		return nil
+	}
+
+	return app.computeExplore(ctx)
+}
+
+func (app *application) computeExplore(ctx context.Context) error {
+	cfg := app.config.explore
+
+	tags, err := app.store.Explore.GetTrendingTags(ctx, store.TrendingQuery{
+		Recent:    cfg.trendingRecent,
+		Window:    cfg.trendingWindow,
+		MinRecent: cfg.trendingMinCount,
+		Limit:     cfg.limit,
+	})
+	if err != nil {
+		return err
+	}
+
+	posts, err := app.store.Explore.GetPopularPosts(ctx, cfg.popularWindow, cfg.limit)
+	if err != nil {
+		return err
+	}
+
+	app.renderPosts(posts)
+
+	app.explore.Lock()
+	app.explore.trendingTags = tags
+	app.explore.popularPosts = posts
+	app.explore.refreshedAt = time.Now()
+	app.explore.Unlock()
+
+	return nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/store"
+)
+
+type countingExploreStore struct {
+	store.MockExploreStore
+	refreshes int
+}
+
+func (s *countingExploreStore) GetTrendingTags(context.Context, store.TrendingQuery) ([]store.TrendingTag, error) {
+	s.refreshes++
+
+	return []store.TrendingTag{{Tag: "go"}}, nil
+}
+
+func TestEnsureExploreFresh(t *testing.T) {
+	app := newTestApplication(t)
+	app.config.explore.refreshInterval = time.Minute
+
+	tests := []struct {
+		name        string
+		refreshedAt time.Time
+		refreshes   int
+	}{
+		{"never refreshed", time.Time{}, 1},
+		{"refreshed within two intervals", time.Now().Add(-90 * time.Second), 0},
+		{"refreshed more than two intervals ago", time.Now().Add(-3 * time.Minute), 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			explore := &countingExploreStore{}
+			app.store.Explore = explore
+			app.explore.refreshedAt = tt.refreshedAt
+
+			if err := app.ensureExploreFresh(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			// Stale results are refreshed in the background.
+			app.explore.refreshing.Lock()
+			app.explore.refreshing.Unlock()
+
+			if explore.refreshes != tt.refreshes {
+				t.Fatalf("Expected %d refreshes. Got %d", tt.refreshes, explore.refreshes)
+			}
+
+			if tt.refreshes > 0 && time.Since(app.explore.refreshedAt) > time.Second {
+				t.Errorf("Expected the refresh time to be updated. Got %v", app.explore.refreshedAt)
+			}
+		})
+	}
+}
+
+func TestEnsureExploreFreshOnce(t *testing.T) {
+	app := newTestApplication(t)
+	app.config.explore.refreshInterval = time.Minute
+	explore := &countingExploreStore{}
+	app.store.Explore = explore
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if err := app.ensureExploreFresh(context.Background()); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if explore.refreshes != 1 {
+		t.Errorf("Expected concurrent requests to compute explore once. Got %d refreshes", explore.refreshes)
+	}
+}
+
+func TestExplorePosts(t *testing.T) {
+	app := newTestApplication(t)
+	app.config.explore.refreshInterval = time.Minute
+	mux := app.mount()
+
+	app.explore.popularPosts = []store.PostWithMetadata{
+		{Post: store.Post{ID: 1, UserID: 42, ContentWarning: "spoilers"}},
+	}
+	app.explore.refreshedAt = time.Now()
+
+	t.Run("should collapse posts without changing the shared cache", func(t *testing.T) {
+		req := newAuthenticatedRequest(t, app, http.MethodGet, "/v1/explore/posts", "")
+
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		var response struct {
+			Data []store.PostWithMetadata `json:"data"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(response.Data) != 1 || !response.Data[0].Collapsed {
+			t.Fatalf("Expected the post to be collapsed for the viewer. Got %+v", response.Data)
+		}
+
+		if app.explore.popularPosts[0].Collapsed {
+			t.Error("Expected the cached post not to be collapsed")
+		}
+	})
+}
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"context"
 	"net/http"
+	"time"
 
 	"github.com/AlfanDutaPamungkas/Go-Social/internal/store"
 )
@@ -9,18 +11,22 @@ import (
 // GetUserFeed godoc
 //
 //	@Summary		Get user feed
-//	@Description	Retrieves a paginated feed of posts for the user, with filtering options
+//	@Description	Retrieves a feed of posts for the user, with filtering options and cursor pagination. The next and previous pages are also linked from the Link header. Offset pagination is deprecated and cannot be combined with a cursor. The ranked mode cannot be sorted or paged by offset, and only links the next page.
 //	@Tags			users
 //	@Accept			json
 //	@Produce		json
 //	@Param			limit	query		int			false	"Number of posts to retrieve (1-20)"	default(20)
-//	@Param			offset	query		int			false	"Pagination offset (>=0)"				default(0)
+//	@Param			cursor	query		string		false	"Cursor returned as next_cursor or prev_cursor by another page"
+//	@Param			offset	query		int			false	"Deprecated: pagination offset (>=0)"	default(0)
 //	@Param			sort	query		string		false	"Sort order (asc or desc)"				default(desc)	Enums(asc, desc)
+//	@Param			mode	query		string		false	"Chronological, or ranked by recency, affinity with the author, engagement and tags"	default(chronological)	Enums(chronological, ranked)
 //	@Param			tags	query		[]string	false	"Filter by up to 5 tags"
 //	@Param			search	query		string		false	"Search query (max 100 chars)"
 //	@Param			since	query		string		false	"Start date (RFC3339 format)"
 //	@Param			until	query		string		false	"End date (RFC3339 format)"
-//	@Success		200		{array}		store.Post
+//	@Success		200		{array}		store.PostWithMetadata
+//	@Header			200		{string}	Link		"Next and previous pages"
+//	@Header			200		{string}	Deprecation	"Set when the deprecated offset is used"
 //	@Failure		400		{object}	error	"Invalid request parameters"
 //	@Failure		500		{object}	error
 //	@Security		ApiKeyAuth
@@ -43,13 +49,85 @@ func (app *application) getUserFeedHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 	
-	feed, err := app.store.Posts.GetUserFeed(r.Context(), int64(21), p)
+	user := getUserFromCtx(r)
+
+	var (
+		feed       []store.PostWithMetadata
+		next, prev string
+	)
+	if p.Mode == store.FeedModeRanked {
+		feed, next, err = app.rankedFeed(r.Context(), user, p)
+	} else {
+		feed, next, prev, err = app.chronologicalFeed(r.Context(), user, p)
+	}
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 
-	if err := app.jsonResponse(w, http.StatusOK, feed); err != nil {
+	app.renderPosts(feed)
+	collapsePosts(feed, user)
+	app.recordImpressions(user, feed)
+
+	if r.URL.Query().Has("offset") {
+		w.Header().Set("Deprecation", "true")
+	}
+
+	if err := app.paginatedJSONResponse(w, r, http.StatusOK, feed, next, prev); err != nil {
 		app.internalServerError(w, r, err)
 	}
 }
+
+// chronologicalFeed returns a page of the feed of user from their timeline
+// or, when the timeline cannot serve it, from the posts of the users they
+// follow.
+func (app *application) chronologicalFeed(ctx context.Context, user *store.User, p *store.PaginatedFeedQuery) (feed []store.PostWithMetadata, next, prev string, err error) {
+	feed, next, prev, ok, err := app.timelineFeed(ctx, user, p)
+	if err != nil || ok {
+		return feed, next, prev, err
+	}
+
+	feed, err = app.store.Posts.GetUserFeed(ctx, user.ID, p)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	next, prev = pageCursors(p, feed)
+	return feed, next, prev, nil
+}
+
+// rankedFeed returns a page of the ranked feed of user. The first page is
+// ranked as of now and its cursor carries that time to the pages after it,
+// which rank the same snapshot. Ranked pages only link forward.
+func (app *application) rankedFeed(ctx context.Context, user *store.User, p *store.PaginatedFeedQuery) ([]store.PostWithMetadata, string, error) {
+	asOf := time.Now()
+	if p.Ranked != nil {
+		asOf = p.Ranked.AsOf
+	}
+
+	feed, err := app.store.Posts.GetRankedFeed(ctx, user.ID, asOf, app.config.ranking, p)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var next string
+	if cursor := p.NextRankedCursor(asOf, feed); cursor != nil {
+		next = cursor.Encode()
+	}
+
+	return feed, next, nil
+}
+
+// pageCursors encodes the cursors of the pages around page, leaving out
+// the ones that do not exist.
+func pageCursors(p *store.PaginatedFeedQuery, page []store.PostWithMetadata) (next, prev string) {
+	if cursor := p.NextCursor(page); cursor != nil {
+		next = cursor.Encode()
+	}
+
+	if cursor := p.PrevCursor(page); cursor != nil {
+		prev = cursor.Encode()
+	}
+
+	return next, prev
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/store"
+)
+
+type fullFeedPostStore struct {
+	store.MockPostStore
+	asOf time.Time
+}
+
+func (s *fullFeedPostStore) GetUserFeed(_ context.Context, _ int64, p *store.PaginatedFeedQuery) ([]store.PostWithMetadata, error) {
+	feed := []store.PostWithMetadata{}
+	for i := range p.Limit {
+		feed = append(feed, store.PostWithMetadata{Post: store.Post{
+			ID:        int64(100 - i),
+			CreatedAt: time.Now().Add(-time.Minute * time.Duration(i)),
+		}})
+	}
+	return feed, nil
+}
+
+func (s *fullFeedPostStore) GetRankedFeed(_ context.Context, _ int64, asOf time.Time, _ store.FeedRanking, p *store.PaginatedFeedQuery) ([]store.PostWithMetadata, error) {
+	s.asOf = asOf
+
+	feed := []store.PostWithMetadata{}
+	for i := range p.Limit {
+		feed = append(feed, store.PostWithMetadata{Post: store.Post{ID: int64(100 - i)}, Score: 1 / float64(i+1)})
+	}
+	return feed, nil
+}
+
+func TestGetUserFeed(t *testing.T) {
+	app := newTestApplication(t)
+	posts := &fullFeedPostStore{}
+	app.store.Posts = posts
+	mux := app.mount()
+
+	get := func(query string) *http.Response {
+		req := newAuthenticatedRequest(t, app, http.MethodGet, "/v1/users/feed"+query, "")
+
+		return executeRequest(req, mux).Result()
+	}
+
+	cursor := store.Cursor{CreatedAt: time.Now(), ID: 101}.Encode()
+
+	t.Run("should link the next page of the first page", func(t *testing.T) {
+		res := get("?limit=2")
+
+		checkResponseCode(t, http.StatusOK, res.StatusCode)
+
+		link := res.Header.Get("Link")
+		if !strings.Contains(link, `rel="next"`) || strings.Contains(link, `rel="prev"`) {
+			t.Errorf("Expected only a next link. Got %q", link)
+		}
+	})
+
+	t.Run("should link both pages around a cursor", func(t *testing.T) {
+		res := get("?limit=2&cursor=" + cursor)
+
+		checkResponseCode(t, http.StatusOK, res.StatusCode)
+
+		link := res.Header.Get("Link")
+		if !strings.Contains(link, `rel="next"`) || !strings.Contains(link, `rel="prev"`) {
+			t.Errorf("Expected next and prev links. Got %q", link)
+		}
+	})
+
+	t.Run("should flag the offset as deprecated", func(t *testing.T) {
+		res := get("?offset=20")
+
+		checkResponseCode(t, http.StatusOK, res.StatusCode)
+
+		if res.Header.Get("Deprecation") == "" {
+			t.Error("Expected a Deprecation header")
+		}
+
+		if link := res.Header.Get("Link"); strings.Contains(link, "offset") {
+			t.Errorf("Expected the links to drop the offset. Got %q", link)
+		}
+	})
+
+	t.Run("should reject an offset combined with a cursor", func(t *testing.T) {
+		checkResponseCode(t, http.StatusBadRequest, get("?offset=20&cursor="+cursor).StatusCode)
+	})
+
+	t.Run("should reject an invalid cursor", func(t *testing.T) {
+		checkResponseCode(t, http.StatusBadRequest, get("?cursor=!!").StatusCode)
+	})
+
+	t.Run("should rank every page as of the first one", func(t *testing.T) {
+		res := get("?mode=ranked&limit=2")
+
+		checkResponseCode(t, http.StatusOK, res.StatusCode)
+
+		first := posts.asOf
+
+		link := res.Header.Get("Link")
+		if !strings.Contains(link, "mode=ranked") || strings.Contains(link, `rel="prev"`) {
+			t.Fatalf("Expected only a ranked next link. Got %q", link)
+		}
+
+		next := store.RankedCursor{AsOf: first, Score: 0.5, ID: 99}.Encode()
+		if !strings.Contains(link, next) {
+			t.Errorf("Expected the next cursor %q. Got %q", next, link)
+		}
+
+		time.Sleep(time.Millisecond)
+		checkResponseCode(t, http.StatusOK, get("?mode=ranked&limit=2&cursor="+next).StatusCode)
+
+		if !posts.asOf.Equal(first) {
+			t.Errorf("Expected the second page as of %v. Got %v", first, posts.asOf)
+		}
+	})
+
+	t.Run("should reject a sorted or offset ranked feed", func(t *testing.T) {
+		checkResponseCode(t, http.StatusBadRequest, get("?mode=ranked&sort=asc").StatusCode)
+		checkResponseCode(t, http.StatusBadRequest, get("?mode=ranked&offset=20").StatusCode)
+		checkResponseCode(t, http.StatusBadRequest, get("?mode=popular").StatusCode)
+	})
+
+	t.Run("should reject a chronological cursor in the ranked feed", func(t *testing.T) {
+		checkResponseCode(t, http.StatusBadRequest, get("?mode=ranked&cursor="+cursor).StatusCode)
+	})
+}
@@ -0,0 +1,681 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/events"
+	ratelimiter "github.com/AlfanDutaPamungkas/Go-Social/internal/rate_limiter"
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/store"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+// The gateway protocol. Clients send subscribe, unsubscribe and typing
+// messages naming a topic. The server acknowledges subscriptions, reports
+// failures as error messages and forwards the events broadcast to the
+// topics a connection is subscribed to.
+const (
+	gatewaySubscribe    = "subscribe"
+	gatewayUnsubscribe  = "unsubscribe"
+	gatewayTyping       = "typing"
+	gatewaySubscribed   = "subscribed"
+	gatewayUnsubscribed = "unsubscribed"
+	gatewayError        = "error"
+)
+
+// The types of the events broadcast to topics, next to eventComment.
+const (
+	eventCommentUpdated = "comment_updated"
+	eventCommentDeleted = "comment_deleted"
+	eventPostUpdated    = "post_updated"
+	eventPostDeleted    = "post_deleted"
+	eventTyping         = "typing"
+	eventPresence       = "presence"
+)
+
+// Topics are named <kind>:<id>. A post topic carries the comments of the
+// post and who is typing one; a user topic carries the presence of the
+// user.
+const (
+	topicPost         = "post"
+	topicUser         = "user"
+	topicConversation = "conversation"
+)
+
+// gatewayTokenProtocol is the subprotocol browsers, which cannot set the
+// Authorization header on WebSocket requests, pass their token after.
+const gatewayTokenProtocol = "access_token"
+
+// gatewayRequestError is a request the client got wrong. Other errors are
+// logged and reported to the client without their details.
+type gatewayRequestError string
+
+func (e gatewayRequestError) Error() string {
+	return string(e)
+}
+
+const (
+	errUnknownTopic  = gatewayRequestError("topics must be post:<id>, user:<id> or conversation:<id>")
+	errTopicNotFound = gatewayRequestError("topic not found")
+)
+
+func postTopic(postID int64) string {
+	return fmt.Sprintf("%s:%d", topicPost, postID)
+}
+
+func userTopic(userID int64) string {
+	return fmt.Sprintf("%s:%d", topicUser, userID)
+}
+
+func parseTopic(topic string) (kind string, id int64, err error) {
+	kind, rawID, ok := strings.Cut(topic, ":")
+	if !ok {
+		return "", 0, errUnknownTopic
+	}
+
+	id, err = strconv.ParseInt(rawID, 10, 64)
+	if err != nil || id < 1 {
+		return "", 0, errUnknownTopic
+	}
+
+	switch kind {
+	case topicPost, topicUser, topicConversation:
+		return kind, id, nil
+	}
+
+	return "", 0, errUnknownTopic
+}
+
+type gatewayRequest struct {
+	Type  string `json:"type"`
+	Topic string `json:"topic"`
+}
+
+type gatewayMessage struct {
+	Type  string          `json:"type"`
+	Topic string          `json:"topic,omitempty"`
+	Data  json.RawMessage `json:"data,omitempty"`
+	Error string          `json:"error,omitempty"`
+}
+
+// typingEvent tells the subscribers of a post that UserID is writing a
+// comment.
+type typingEvent struct {
+	UserID int64 `json:"user_id"`
+}
+
+// presenceEvent tells the subscribers of a user whether they are connected.
+type presenceEvent struct {
+	UserID int64 `json:"user_id"`
+	Online bool  `json:"online"`
+}
+
+// commentDeletedEvent tells the subscribers of a post that a comment and
+// its replies were deleted.
+type commentDeletedEvent struct {
+	CommentID int64 `json:"comment_id"`
+}
+
+// postUpdatedEvent tells the subscribers of a post that it was edited.
+type postUpdatedEvent struct {
+	PostID  int64 `json:"post_id"`
+	Version int   `json:"version"`
+}
+
+// postDeletedEvent tells the subscribers of a post that it was deleted,
+// which ends their subscriptions.
+type postDeletedEvent struct {
+	PostID int64 `json:"post_id"`
+}
+
+// authorizeTopic checks that user may subscribe to topic. It returns
+// errUnknownTopic or errTopicNotFound for topics the user cannot have.
+func (app *application) authorizeTopic(ctx context.Context, user *store.User, topic string) error {
+	kind, id, err := parseTopic(topic)
+	if err != nil {
+		return err
+	}
+
+	switch kind {
+	case topicPost:
+		post, err := app.store.Posts.GetByID(ctx, id)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				return errTopicNotFound
+			}
+			return err
+		}
+
+		visible, err := app.canViewPost(ctx, user, post)
+		if err != nil {
+			return err
+		}
+		if !visible {
+			return errTopicNotFound
+		}
+	case topicUser:
+		if _, err := app.store.Users.GetByID(ctx, id); err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				return errTopicNotFound
+			}
+			return err
+		}
+	case topicConversation:
+		// There are no conversations to subscribe to yet.
+		return errTopicNotFound
+	}
+
+	return nil
+}
+
+// gatewayConns tracks the gateway connections of this instance so that
+// they can be drained on shutdown.
+type gatewayConns struct {
+	mu      sync.Mutex
+	conns   map[*gatewayConn]bool
+	closing bool
+	wg      sync.WaitGroup
+}
+
+func (g *gatewayConns) draining() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.closing
+}
+
+// add tracks c unless the gateway is draining.
+func (g *gatewayConns) add(c *gatewayConn) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.closing {
+		return false
+	}
+
+	if g.conns == nil {
+		g.conns = make(map[*gatewayConn]bool)
+	}
+	g.conns[c] = true
+	g.wg.Add(1)
+
+	return true
+}
+
+func (g *gatewayConns) remove(c *gatewayConn) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	delete(g.conns, c)
+	g.wg.Done()
+}
+
+// drain asks every connection to close, so that clients reconnect to
+// another instance, and waits for them until ctx is done, after which the
+// remaining ones are dropped.
+func (g *gatewayConns) drain(ctx context.Context) {
+	g.mu.Lock()
+	g.closing = true
+	conns := make([]*gatewayConn, 0, len(g.conns))
+	for c := range g.conns {
+		conns = append(conns, c)
+	}
+	g.mu.Unlock()
+
+	for _, c := range conns {
+		c.close(websocket.CloseGoingAway, "server is shutting down")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		g.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		for _, c := range conns {
+			c.conn.Close()
+		}
+	}
+}
+
+type gatewayConn struct {
+	app     *application
+	conn    *websocket.Conn
+	user    *store.User
+	id      string
+	limiter ratelimiter.Limiter
+
+	// send queues the messages for the writer, which writes a close frame
+	// with closeCode once done is closed.
+	send      chan gatewayMessage
+	done      chan struct{}
+	closeOnce sync.Once
+	closeCode int
+	closeText string
+
+	mu            sync.Mutex
+	subscriptions map[string]*events.Subscription
+}
+
+// Gateway godoc
+//
+//	@Summary		Open a WebSocket gateway connection
+//	@Description	Upgrades to a WebSocket connection for typing indicators, presence and live comment threads. Authenticate with the Authorization header or, from browsers, by offering the subprotocols "access_token" and the token. Clients send JSON messages {"type": "subscribe" | "unsubscribe" | "typing", "topic": "post:<id>" | "user:<id>" | "conversation:<id>"}. Subscriptions are acknowledged with a subscribed message and failures reported with an error message. Post topics carry comment, comment_updated, comment_deleted, post_updated, post_deleted and typing events; typing may only be sent to a subscribed post topic. The server ends a post subscription with an unsubscribed message carrying an error once the post is deleted or the user may no longer see it. User topics carry presence events, starting with the current presence. Conversations do not exist yet, so conversation topics are not found. Each connection may send a limited number of messages per window, is pinged to keep it alive, and is closed with code 1001 when the server shuts down and 1013 when it falls behind.
+//	@Tags			gateway
+//	@Success		101
+//	@Failure		401	{object}	error
+//	@Failure		503	{object}	error	"The server is shutting down"
+//	@Security		ApiKeyAuth
+//	@Router			/gateway [get]
+func (app *application) gatewayHandler(w http.ResponseWriter, r *http.Request) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		protocols := websocket.Subprotocols(r)
+		if len(protocols) != 2 || protocols[0] != gatewayTokenProtocol {
+			app.unauthorizedResponse(w, r, errors.New("authorization header is missing"))
+			return
+		}
+		token = protocols[1]
+	}
+
+	user, err := app.authenticateToken(r.Context(), token)
+	if err != nil {
+		app.unauthorizedResponse(w, r, err)
+		return
+	}
+
+	cfg := app.config.gateway
+	c := &gatewayConn{
+		app:           app,
+		user:          user,
+		id:            uuid.NewString(),
+		limiter:       ratelimiter.NewFixedWindowLimiter(cfg.messageLimit, cfg.messageWindow),
+		send:          make(chan gatewayMessage, cfg.sendBuffer),
+		done:          make(chan struct{}),
+		subscriptions: make(map[string]*events.Subscription),
+	}
+
+	if app.gateway.draining() {
+		app.serviceUnavailableResponse(w, r, errors.New("the server is shutting down"))
+		return
+	}
+
+	upgrader := websocket.Upgrader{
+		Subprotocols: []string{gatewayTokenProtocol},
+		CheckOrigin:  app.checkGatewayOrigin,
+	}
+
+	// The upgrader writes the error response itself.
+	c.conn, err = upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer c.conn.Close()
+
+	if !app.gateway.add(c) {
+		message := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server is shutting down")
+		c.conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(cfg.writeWait))
+		return
+	}
+	defer app.gateway.remove(c)
+
+	// The request context lasts until the handler returns, even though the
+	// connection was hijacked.
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	c.run(ctx)
+}
+
+// checkGatewayOrigin lets the web app and clients that send no Origin, such
+// as mobile apps, connect.
+func (app *application) checkGatewayOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || origin == app.config.frontendURL {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+func (c *gatewayConn) run(ctx context.Context) {
+	cfg := c.app.config.gateway
+
+	c.connect(ctx)
+	defer c.disconnect(ctx)
+
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		c.write(ctx)
+	}()
+
+	c.read(ctx)
+	c.close(websocket.CloseNormalClosure, "")
+
+	select {
+	case <-written:
+	case <-time.After(cfg.writeWait):
+	}
+}
+
+// connect marks the user online, telling their subscribers when they were
+// not connected before.
+func (c *gatewayConn) connect(ctx context.Context) {
+	online, err := c.app.presence.Online(ctx, c.user.ID)
+	if err != nil {
+		c.app.logger.Errorw("failed to read presence", "user", c.user.ID, "error", err.Error())
+	}
+
+	if !c.touch(ctx) || online {
+		return
+	}
+
+	c.app.broadcastEvent(ctx, userTopic(c.user.ID), eventPresence, c.user.ID, presenceEvent{UserID: c.user.ID, Online: true})
+}
+
+// disconnect ends the subscriptions and marks the user offline unless they
+// are still connected elsewhere.
+func (c *gatewayConn) disconnect(ctx context.Context) {
+	c.mu.Lock()
+	for _, subscription := range c.subscriptions {
+		subscription.Close()
+	}
+	c.mu.Unlock()
+
+	online, err := c.app.presence.Leave(ctx, c.user.ID, c.id)
+	if err != nil {
+		c.app.logger.Errorw("failed to update presence", "user", c.user.ID, "error", err.Error())
+		return
+	}
+
+	if !online {
+		c.app.broadcastEvent(ctx, userTopic(c.user.ID), eventPresence, c.user.ID, presenceEvent{UserID: c.user.ID, Online: false})
+	}
+}
+
+// touch keeps the connection counted as present until a little after the
+// next ping.
+func (c *gatewayConn) touch(ctx context.Context) bool {
+	if err := c.app.presence.Touch(ctx, c.user.ID, c.id, c.app.config.gateway.pongWait); err != nil {
+		c.app.logger.Errorw("failed to update presence", "user", c.user.ID, "error", err.Error())
+		return false
+	}
+
+	return true
+}
+
+// close makes the writer end the connection with code. Only the first
+// call has an effect.
+func (c *gatewayConn) close(code int, text string) {
+	c.closeOnce.Do(func() {
+		c.closeCode = code
+		c.closeText = text
+		close(c.done)
+	})
+}
+
+// queue hands message to the writer, closing connections that fall behind
+// instead of blocking.
+func (c *gatewayConn) queue(message gatewayMessage) {
+	select {
+	case <-c.done:
+	case c.send <- message:
+	default:
+		c.close(websocket.CloseTryAgainLater, "connection fell behind")
+	}
+}
+
+func (c *gatewayConn) queueError(topic string, err error) {
+	c.queue(gatewayMessage{Type: gatewayError, Topic: topic, Error: err.Error()})
+}
+
+// read handles the messages of the client until the connection fails or
+// the close handshake ends.
+func (c *gatewayConn) read(ctx context.Context) {
+	cfg := c.app.config.gateway
+
+	c.conn.SetReadLimit(cfg.maxMessageBytes)
+	c.conn.SetReadDeadline(time.Now().Add(cfg.pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(cfg.pongWait))
+	})
+
+	for {
+		var request gatewayRequest
+		if err := c.conn.ReadJSON(&request); err != nil {
+			var syntaxErr *json.SyntaxError
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+				c.close(websocket.CloseUnsupportedData, "messages must be JSON requests")
+			}
+			return
+		}
+
+		if allowed, _ := c.limiter.Allow(c.id); !allowed {
+			c.queueError(request.Topic, errors.New("rate limit exceeded"))
+			continue
+		}
+
+		if err := c.handle(ctx, request); err != nil {
+			var requestErr gatewayRequestError
+			if !errors.As(err, &requestErr) {
+				c.app.logger.Errorw("gateway request failed", "type", request.Type, "topic", request.Topic, "error", err.Error())
+				err = errors.New("the server encountered a problem")
+			}
+			c.queueError(request.Topic, err)
+		}
+	}
+}
+
+func (c *gatewayConn) handle(ctx context.Context, request gatewayRequest) error {
+	switch request.Type {
+	case gatewaySubscribe:
+		return c.subscribe(ctx, request.Topic)
+	case gatewayUnsubscribe:
+		c.unsubscribe(request.Topic)
+		return nil
+	case gatewayTyping:
+		return c.typing(ctx, request.Topic)
+	}
+
+	return gatewayRequestError(fmt.Sprintf("unknown message type %q", request.Type))
+}
+
+func (c *gatewayConn) subscribe(ctx context.Context, topic string) error {
+	c.mu.Lock()
+	_, subscribed := c.subscriptions[topic]
+	count := len(c.subscriptions)
+	c.mu.Unlock()
+
+	if subscribed {
+		c.queue(gatewayMessage{Type: gatewaySubscribed, Topic: topic})
+		return nil
+	}
+
+	if count >= c.app.config.gateway.maxTopics {
+		return gatewayRequestError(fmt.Sprintf("connections can subscribe to at most %d topics", c.app.config.gateway.maxTopics))
+	}
+
+	if err := c.app.authorizeTopic(ctx, c.user, topic); err != nil {
+		return err
+	}
+
+	subscription := c.app.events.Join(ctx, topic)
+
+	c.mu.Lock()
+	if _, ok := c.subscriptions[topic]; ok {
+		c.mu.Unlock()
+		subscription.Close()
+		return nil
+	}
+	c.subscriptions[topic] = subscription
+	c.mu.Unlock()
+
+	c.queue(gatewayMessage{Type: gatewaySubscribed, Topic: topic})
+
+	if kind, id, _ := parseTopic(topic); kind == topicUser {
+		online, err := c.app.presence.Online(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		data, err := json.Marshal(presenceEvent{UserID: id, Online: online})
+		if err != nil {
+			return err
+		}
+		c.queue(gatewayMessage{Type: eventPresence, Topic: topic, Data: data})
+	}
+
+	go c.forward(ctx, topic, subscription)
+
+	return nil
+}
+
+// forward queues the events of subscription until it ends. Connections
+// whose subscriptions fall behind are closed so that the client reconnects
+// and catches up.
+//
+// A post can be deleted, or stop being visible to the user, after they
+// subscribed to it. The subscription ends after a post_deleted event, and
+// the user's access is checked again on post_updated, which follows every
+// change to who can see the post. Other events are forwarded unchecked.
+func (c *gatewayConn) forward(ctx context.Context, topic string, subscription *events.Subscription) {
+	kind, _, _ := parseTopic(topic)
+
+	for event := range subscription.Events() {
+		// Users are not told about their own typing.
+		if event.Type == eventTyping && event.UserID == c.user.ID {
+			continue
+		}
+
+		if kind == topicPost && event.Type == eventPostUpdated {
+			if err := c.app.authorizeTopic(ctx, c.user, topic); err != nil {
+				if errors.Is(err, errTopicNotFound) {
+					c.end(topic, subscription, err)
+					return
+				}
+
+				// Events are best-effort, so this one is dropped rather than
+				// sent unchecked.
+				if ctx.Err() == nil {
+					c.app.logger.Errorw("failed to authorize gateway event", "topic", topic, "error", err.Error())
+				}
+				continue
+			}
+		}
+
+		c.queue(gatewayMessage{Type: event.Type, Topic: event.Topic, Data: event.Data})
+
+		if event.Type == eventPostDeleted {
+			c.end(topic, subscription, errTopicNotFound)
+			return
+		}
+	}
+
+	if errors.Is(subscription.Err(), events.ErrLagged) {
+		c.close(websocket.CloseTryAgainLater, "connection fell behind")
+	}
+}
+
+// end ends subscription to topic on behalf of the server, telling the
+// client why.
+func (c *gatewayConn) end(topic string, subscription *events.Subscription, err error) {
+	c.mu.Lock()
+	// The client may have unsubscribed, and subscribed again, meanwhile.
+	if c.subscriptions[topic] == subscription {
+		delete(c.subscriptions, topic)
+	}
+	c.mu.Unlock()
+
+	subscription.Close()
+
+	c.queue(gatewayMessage{Type: gatewayUnsubscribed, Topic: topic, Error: err.Error()})
+}
+
+func (c *gatewayConn) unsubscribe(topic string) {
+	c.mu.Lock()
+	subscription, ok := c.subscriptions[topic]
+	delete(c.subscriptions, topic)
+	c.mu.Unlock()
+
+	if ok {
+		subscription.Close()
+	}
+
+	c.queue(gatewayMessage{Type: gatewayUnsubscribed, Topic: topic})
+}
+
+// typing tells the other subscribers of a post that the user is writing a
+// comment on it.
+func (c *gatewayConn) typing(ctx context.Context, topic string) error {
+	if kind, _, err := parseTopic(topic); err != nil || kind != topicPost {
+		return gatewayRequestError("typing can only be sent to post topics")
+	}
+
+	c.mu.Lock()
+	_, subscribed := c.subscriptions[topic]
+	c.mu.Unlock()
+
+	if !subscribed {
+		return gatewayRequestError("subscribe to the topic before typing in it")
+	}
+
+	c.app.broadcastEvent(ctx, topic, eventTyping, c.user.ID, typingEvent{UserID: c.user.ID})
+
+	return nil
+}
+
+// write writes the queued messages and the pings until the connection is
+// closed, then starts the close handshake.
+func (c *gatewayConn) write(ctx context.Context) {
+	cfg := c.app.config.gateway
+
+	ping := time.NewTicker(cfg.pingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case message := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(cfg.writeWait))
+			if err := c.conn.WriteJSON(message); err != nil {
+				c.close(websocket.CloseAbnormalClosure, "")
+				return
+			}
+		case <-ping.C:
+			c.touch(ctx)
+
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(cfg.writeWait)); err != nil {
+				c.close(websocket.CloseAbnormalClosure, "")
+				return
+			}
+		case <-c.done:
+			if c.closeCode == websocket.CloseAbnormalClosure {
+				return
+			}
+
+			message := websocket.FormatCloseMessage(c.closeCode, c.closeText)
+			if err := c.conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(cfg.writeWait)); err != nil {
+				return
+			}
+
+			// Wait for the client to answer the close frame, but not for
+			// as long as a pong.
+			c.conn.NetConn().SetReadDeadline(time.Now().Add(cfg.writeWait))
+			return
+		}
+	}
+}
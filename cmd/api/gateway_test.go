@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/events"
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/store"
+	"github.com/gorilla/websocket"
+)
+
+func TestGateway(t *testing.T) {
+	app := newTestApplication(t)
+	app.config.gateway = gatewayConfig{
+		enabled:         true,
+		pingInterval:    time.Hour,
+		pongWait:        time.Hour,
+		writeWait:       time.Second,
+		maxMessageBytes: 1024,
+		messageLimit:    4,
+		messageWindow:   time.Hour,
+		maxTopics:       2,
+		sendBuffer:      16,
+	}
+	app.events = events.NewMemoryBroker(16, 0)
+	app.presence = events.NewMemoryPresence()
+
+	server := httptest.NewServer(app.mount())
+	defer server.Close()
+
+	testToken := newTestToken(t, app)
+
+	gatewayURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/v1/gateway"
+
+	dial := func(t *testing.T) *websocket.Conn {
+		t.Helper()
+
+		header := http.Header{}
+		header.Set("Authorization", "Bearer "+testToken)
+
+		conn, _, err := websocket.DefaultDialer.Dial(gatewayURL, header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Close() })
+
+		return conn
+	}
+
+	send := func(t *testing.T, conn *websocket.Conn, kind, topic string) {
+		t.Helper()
+
+		if err := conn.WriteJSON(gatewayRequest{Type: kind, Topic: topic}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	receive := func(t *testing.T, conn *websocket.Conn) gatewayMessage {
+		t.Helper()
+
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+
+		var message gatewayMessage
+		if err := conn.ReadJSON(&message); err != nil {
+			t.Fatal(err)
+		}
+
+		return message
+	}
+
+	t.Run("should require a token", func(t *testing.T) {
+		_, res, err := websocket.DefaultDialer.Dial(gatewayURL, nil)
+		if err == nil {
+			t.Fatal("Expected the connection to be refused")
+		}
+
+		checkResponseCode(t, http.StatusUnauthorized, res.StatusCode)
+	})
+
+	t.Run("should accept the token as a subprotocol", func(t *testing.T) {
+		dialer := websocket.Dialer{Subprotocols: []string{gatewayTokenProtocol, testToken}}
+
+		conn, _, err := dialer.Dial(gatewayURL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+
+		if conn.Subprotocol() != gatewayTokenProtocol {
+			t.Errorf("Expected the %q subprotocol. Got %q", gatewayTokenProtocol, conn.Subprotocol())
+		}
+	})
+
+	t.Run("should forward the events of subscribed topics", func(t *testing.T) {
+		conn := dial(t)
+
+		send(t, conn, gatewaySubscribe, "post:1")
+		if message := receive(t, conn); message.Type != gatewaySubscribed || message.Topic != "post:1" {
+			t.Fatalf("Expected the subscription to be acknowledged. Got %+v", message)
+		}
+
+		app.broadcastEvent(context.Background(), "post:2", eventComment, 7, commentDeletedEvent{CommentID: 2})
+		app.broadcastEvent(context.Background(), postTopic(1), eventCommentDeleted, 7, commentDeletedEvent{CommentID: 3})
+
+		message := receive(t, conn)
+		if message.Type != eventCommentDeleted || message.Topic != "post:1" || string(message.Data) != `{"comment_id":3}` {
+			t.Errorf("Expected the deleted comment of post 1. Got %+v", message)
+		}
+	})
+
+	t.Run("should report the presence of users", func(t *testing.T) {
+		conn := dial(t)
+
+		send(t, conn, gatewaySubscribe, "user:5")
+		receive(t, conn)
+
+		if message := receive(t, conn); message.Type != eventPresence || string(message.Data) != `{"user_id":5,"online":false}` {
+			t.Errorf("Expected user 5 to be offline. Got %+v", message)
+		}
+	})
+
+	t.Run("should reject topics that cannot be subscribed to", func(t *testing.T) {
+		conn := dial(t)
+
+		for _, topic := range []string{"post", "group:1", "conversation:1"} {
+			send(t, conn, gatewaySubscribe, topic)
+
+			if message := receive(t, conn); message.Type != gatewayError || message.Topic != topic {
+				t.Errorf("Expected an error for %q. Got %+v", topic, message)
+			}
+		}
+	})
+
+	t.Run("should reject typing outside subscribed posts", func(t *testing.T) {
+		conn := dial(t)
+
+		send(t, conn, gatewayTyping, "post:1")
+
+		if message := receive(t, conn); message.Type != gatewayError {
+			t.Errorf("Expected an error. Got %+v", message)
+		}
+	})
+
+	t.Run("should rate limit messages", func(t *testing.T) {
+		conn := dial(t)
+
+		for range 5 {
+			send(t, conn, gatewayUnsubscribe, "post:1")
+		}
+
+		for range 4 {
+			receive(t, conn)
+		}
+
+		if message := receive(t, conn); message.Type != gatewayError || message.Error != "rate limit exceeded" {
+			t.Errorf("Expected the rate limit to be exceeded. Got %+v", message)
+		}
+	})
+
+	t.Run("should end post subscriptions the user can no longer see", func(t *testing.T) {
+		previous := app.store.Posts
+		posts := store.NewMockPostStore(visiblePost(store.PostVisibilityPublic))
+		app.store.Posts = posts
+		defer func() { app.store.Posts = previous }()
+
+		conn := dial(t)
+
+		send(t, conn, gatewaySubscribe, "post:9")
+		receive(t, conn)
+
+		posts.SetPost(visiblePost(store.PostVisibilityPrivate))
+		app.broadcastEvent(context.Background(), postTopic(9), eventPostUpdated, 42, postUpdatedEvent{PostID: 9, Version: 2})
+
+		if message := receive(t, conn); message.Type != gatewayUnsubscribed || message.Topic != "post:9" || message.Error != errTopicNotFound.Error() {
+			t.Errorf("Expected the subscription to end. Got %+v", message)
+		}
+
+		send(t, conn, gatewayTyping, "post:9")
+		if message := receive(t, conn); message.Type != gatewayError {
+			t.Errorf("Expected the topic to be unsubscribed. Got %+v", message)
+		}
+	})
+
+	t.Run("should only check access to a post again when it is updated", func(t *testing.T) {
+		previous := app.store.Posts
+		posts := store.NewMockPostStore(visiblePost(store.PostVisibilityPublic))
+		app.store.Posts = posts
+		defer func() { app.store.Posts = previous }()
+
+		conn := dial(t)
+
+		send(t, conn, gatewaySubscribe, "post:9")
+		receive(t, conn)
+		loads := posts.Loads()
+
+		app.broadcastEvent(context.Background(), postTopic(9), eventTyping, 7, typingEvent{UserID: 7})
+		app.broadcastEvent(context.Background(), postTopic(9), eventCommentDeleted, 7, commentDeletedEvent{CommentID: 3})
+		receive(t, conn)
+		receive(t, conn)
+
+		if got := posts.Loads(); got != loads {
+			t.Errorf("Expected the post not to be loaded for every event. Got %d loads", got-loads)
+		}
+
+		app.broadcastEvent(context.Background(), postTopic(9), eventPostUpdated, 42, postUpdatedEvent{PostID: 9, Version: 2})
+		receive(t, conn)
+
+		if got := posts.Loads(); got != loads+1 {
+			t.Errorf("Expected the post to be loaded again once it was updated. Got %d loads", got-loads)
+		}
+	})
+
+	t.Run("should end post subscriptions when the post is deleted", func(t *testing.T) {
+		conn := dial(t)
+
+		send(t, conn, gatewaySubscribe, "post:1")
+		receive(t, conn)
+
+		app.broadcastEvent(context.Background(), postTopic(1), eventPostDeleted, 42, postDeletedEvent{PostID: 1})
+
+		if message := receive(t, conn); message.Type != eventPostDeleted || string(message.Data) != `{"post_id":1}` {
+			t.Errorf("Expected the post to be deleted. Got %+v", message)
+		}
+
+		if message := receive(t, conn); message.Type != gatewayUnsubscribed || message.Topic != "post:1" {
+			t.Errorf("Expected the subscription to end. Got %+v", message)
+		}
+	})
+
+	t.Run("should close connections when draining", func(t *testing.T) {
+		conn := dial(t)
+
+		send(t, conn, gatewaySubscribe, "post:1")
+		receive(t, conn)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		drained := make(chan struct{})
+		go func() {
+			app.gateway.drain(ctx)
+			close(drained)
+		}()
+
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		_, _, err := conn.ReadMessage()
+
+		var closeErr *websocket.CloseError
+		if !errors.As(err, &closeErr) || closeErr.Code != websocket.CloseGoingAway {
+			t.Errorf("Expected the connection to go away. Got %v", err)
+		}
+
+		<-drained
+
+		header := http.Header{}
+		header.Set("Authorization", "Bearer "+testToken)
+
+		_, res, err := websocket.DefaultDialer.Dial(gatewayURL, header)
+		if err == nil {
+			t.Fatal("Expected the connection to be refused")
+		}
+
+		checkResponseCode(t, http.StatusServiceUnavailable, res.StatusCode)
+	})
+}
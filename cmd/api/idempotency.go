@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/store"
+)
+
+const (
+	idempotencyKeyHeader = "Idempotency-Key"
+	maxIdempotencyKeyLen = 255
+)
+
+// replayedHeaders are the response headers stored with an idempotency key
+// and sent again on replays.
+var replayedHeaders = []string{"Content-Type", "ETag", "Location", "Link"}
+
+// IdempotencyMiddleware makes mutating requests sent with an
+// Idempotency-Key header safe to retry. The first request with a key is
+// processed and its response saved; retries with the same key and body get
+// that response back instead of repeating the request. Reusing a key for a
+// different request is rejected with 422. Server errors are not saved, so
+// requests that failed that way can be retried. It must run after
+// AuthTokenMiddleware since keys are scoped to the user.
+func (app *application) IdempotencyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyKeyHeader)
+		if key == "" || r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLen {
+			app.badRequestResponse(w, r, fmt.Errorf("%s must be at most %d characters", idempotencyKeyHeader, maxIdempotencyKeyLen))
+			return
+		}
+
+		maxBytes := app.config.media.maxUploadBytes + 1<<20
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxBytes+1))
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+		if int64(len(body)) > maxBytes {
+			app.payloadTooLargeResponse(w, r, errors.New("request body is too large"))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		user := getUserFromCtx(r)
+		rec := &store.IdempotencyRecord{
+			UserID:      user.ID,
+			Key:         key,
+			Fingerprint: requestFingerprint(r, body),
+		}
+
+		existing, err := app.store.Idempotency.Reserve(r.Context(), rec, app.config.idempotency.ttl, app.config.idempotency.lease)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		if existing != nil {
+			app.replayIdempotentResponse(w, r, rec, existing)
+			return
+		}
+
+		// The response is saved even if the client goes away, since that is
+		// when it is most likely to retry.
+		ctx := context.WithoutCancel(r.Context())
+
+		recorder := &responseRecorder{ResponseWriter: w}
+		completed := false
+		defer func() {
+			if !completed {
+				if err := app.store.Idempotency.Release(ctx, rec.UserID, rec.Key); err != nil {
+					app.logger.Errorw("failed to release idempotency key", "key", rec.Key, "error", err.Error())
+				}
+			}
+		}()
+
+		next.ServeHTTP(recorder, r)
+
+		if recorder.status == 0 || recorder.status >= http.StatusInternalServerError {
+			return
+		}
+
+		rec.StatusCode = recorder.status
+		rec.Body = recorder.body.Bytes()
+		rec.Header = make(map[string]string)
+		for _, name := range replayedHeaders {
+			if value := w.Header().Get(name); value != "" {
+				rec.Header[name] = value
+			}
+		}
+
+		if err := app.store.Idempotency.Complete(ctx, rec); err != nil {
+			app.logger.Errorw("failed to save idempotent response", "key", rec.Key, "error", err.Error())
+			return
+		}
+		completed = true
+	})
+}
+
+func (app *application) replayIdempotentResponse(w http.ResponseWriter, r *http.Request, rec, existing *store.IdempotencyRecord) {
+	switch {
+	case existing.Fingerprint != rec.Fingerprint:
+		app.unprocessableEntityResponse(w, r, fmt.Errorf("%s was already used for a different request", idempotencyKeyHeader))
+	case existing.StatusCode == 0:
+		app.conflictResponse(w, r, fmt.Errorf("a request with this %s is still being processed", idempotencyKeyHeader))
+	default:
+		for name, value := range existing.Header {
+			w.Header().Set(name, value)
+		}
+		w.Header().Set("Idempotent-Replayed", "true")
+		w.WriteHeader(existing.StatusCode)
+		w.Write(existing.Body)
+	}
+}
+
+// requestFingerprint identifies a request by its method, path and body.
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", r.Method, r.URL.RequestURI())
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder passes a response through while keeping a copy of its
+// status and body.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rr *responseRecorder) WriteHeader(status int) {
+	if rr.status == 0 {
+		rr.status = status
+	}
+	rr.ResponseWriter.WriteHeader(status)
+}
+
+func (rr *responseRecorder) Write(b []byte) (int, error) {
+	if rr.status == 0 {
+		rr.status = http.StatusOK
+	}
+	rr.body.Write(b)
+	return rr.ResponseWriter.Write(b)
+}
+
+// cleanupIdempotencyKeys deletes the keys that can no longer be replayed.
+func (app *application) cleanupIdempotencyKeys(ctx context.Context) error {
+	_, err := app.store.Idempotency.DeleteExpired(ctx, app.config.idempotency.ttl)
+	return err
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/store"
+)
+
+type memoryIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]store.IdempotencyRecord
+}
+
+func (s *memoryIdempotencyStore) Reserve(_ context.Context, rec *store.IdempotencyRecord, _, _ time.Duration) (*store.IdempotencyRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.records[rec.Key]; ok {
+		return &existing, nil
+	}
+
+	s.records[rec.Key] = *rec
+	return nil, nil
+}
+
+func (s *memoryIdempotencyStore) Complete(_ context.Context, rec *store.IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records[rec.Key] = *rec
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Release(_ context.Context, _ int64, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, key)
+	return nil
+}
+
+func (s *memoryIdempotencyStore) DeleteExpired(context.Context, time.Duration) (int64, error) {
+	return 0, nil
+}
+
+type countingPostStore struct {
+	store.MockPostStore
+	created int
+}
+
+func (s *countingPostStore) Create(_ context.Context, post *store.Post) error {
+	s.created++
+	post.ID = int64(s.created)
+	return nil
+}
+
+func TestIdempotencyKeys(t *testing.T) {
+	app := newTestApplication(t)
+	app.store.Idempotency = &memoryIdempotencyStore{records: map[string]store.IdempotencyRecord{}}
+	posts := &countingPostStore{}
+	app.store.Posts = posts
+	mux := app.mount()
+
+	createPost := func(key, body string) *http.Response {
+		req := newAuthenticatedRequest(t, app, http.MethodPost, "/v1/posts", body)
+		req.Header.Set("Idempotency-Key", key)
+
+		return executeRequest(req, mux).Result()
+	}
+
+	t.Run("should replay the original response on a retry", func(t *testing.T) {
+		first := createPost("retry", `{"title":"t","content":"c"}`)
+		checkResponseCode(t, http.StatusCreated, first.StatusCode)
+
+		retry := createPost("retry", `{"title":"t","content":"c"}`)
+		checkResponseCode(t, http.StatusCreated, retry.StatusCode)
+
+		if posts.created != 1 {
+			t.Errorf("Expected 1 post to be created. Got %d", posts.created)
+		}
+
+		if retry.Header.Get("Idempotent-Replayed") != "true" {
+			t.Error("Expected the retry to be marked as replayed")
+		}
+	})
+
+	t.Run("should reject a reused key with a different body", func(t *testing.T) {
+		res := createPost("retry", `{"title":"t","content":"other"}`)
+
+		checkResponseCode(t, http.StatusUnprocessableEntity, res.StatusCode)
+	})
+
+	t.Run("should replay client errors", func(t *testing.T) {
+		bad := createPost("invalid", `{"title":""}`)
+		checkResponseCode(t, http.StatusBadRequest, bad.StatusCode)
+
+		retry := createPost("invalid", `{"title":""}`)
+		if retry.Header.Get("Idempotent-Replayed") != "true" {
+			t.Error("Expected client errors to be replayed")
+		}
+	})
+	t.Run("should cover moderation reviews", func(t *testing.T) {
+		review := func() *http.Response {
+			req := newAuthenticatedRequest(t, app, http.MethodPut, "/v1/moderation/held/post/1", `{"status":"approved"}`)
+			req.Header.Set("Idempotency-Key", "review")
+
+			return executeRequest(req, mux).Result()
+		}
+
+		review()
+
+		if retry := review(); retry.Header.Get("Idempotent-Replayed") != "true" {
+			t.Error("Expected the retried review to be replayed")
+		}
+	})
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// startBackgroundJobs launches the periodic jobs that run inside the API
+// process. They stop when ctx is cancelled; wg lets run() wait for them
+// during shutdown.
+func (app *application) startBackgroundJobs(ctx context.Context, wg *sync.WaitGroup) {
+	if app.config.scheduler.enabled {
+		app.runPeriodic(ctx, wg, "publish scheduled posts", app.config.scheduler.interval, app.publishScheduledPosts)
+	}
+
+	// Explore is computed before the server starts, rather than by its first
+	// requests.
+	if err := app.refreshExplore(ctx); err != nil {
+		app.logger.Errorw("background job failed", "job", "refresh explore", "error", err.Error())
+	}
+	app.runPeriodic(ctx, wg, "refresh explore", app.config.explore.refreshInterval, app.refreshExplore)
+	app.runPeriodic(ctx, wg, "clean up orphaned media", app.config.media.cleanupInterval, app.cleanupOrphanedMedia)
+	app.runPeriodic(ctx, wg, "purge deleted posts", app.config.deletion.purgeInterval, app.purgeDeletedPosts)
+	app.runPeriodic(ctx, wg, "clean up idempotency keys", app.config.idempotency.cleanupInterval, app.cleanupIdempotencyKeys)
+
+	if app.config.linkPreview.enabled {
+		app.startLinkPreviewWorkers(ctx, wg)
+		app.runPeriodic(ctx, wg, "sweep link previews", app.config.linkPreview.sweepInterval, app.sweepLinkPreviews)
+	}
+
+	if app.config.stats.enabled {
+		app.startStatsWorker(ctx, wg)
+		app.runPeriodic(ctx, wg, "prune view de-duplication", app.config.stats.dedupWindow, app.pruneViewDedup)
+	}
+
+	if app.config.timeline.enabled {
+		app.startTimelineWorkers(ctx, wg)
+		app.runPeriodic(ctx, wg, "trim timelines", app.config.timeline.trimInterval, app.trimTimelines)
+	}
+
+	if app.events != nil {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if err := app.events.Run(ctx); err != nil {
+				app.logger.Errorw("event broker stopped", "error", err.Error())
+			}
+		}()
+	}
+}
+
+func (app *application) runPeriodic(ctx context.Context, wg *sync.WaitGroup, name string, interval time.Duration, job func(context.Context) error) {
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := job(ctx); err != nil && ctx.Err() == nil {
+					app.logger.Errorw("background job failed", "job", name, "error", err.Error())
+				}
+			}
+		}
+	}()
+}
+
+func (app *application) publishScheduledPosts(ctx context.Context) error {
+	for {
+		ids, err := app.store.Posts.PublishDue(ctx, app.config.scheduler.batchSize)
+		if err != nil {
+			return err
+		}
+
+		if len(ids) > 0 {
+			app.logger.Infow("published scheduled posts", "count", len(ids))
+		}
+
+		for _, id := range ids {
+			app.publishToTimelines(id)
+		}
+
+		if len(ids) < app.config.scheduler.batchSize {
+			return nil
+		}
+	}
+}
+
+// purgeDeletedPosts permanently deletes the posts whose restore window has
+// passed.
+func (app *application) purgeDeletedPosts(ctx context.Context) error {
+	for {
+		ids, err := app.store.Posts.PurgeDeleted(ctx, app.config.deletion.retention, app.config.deletion.batchSize)
+		if err != nil {
+			return err
+		}
+
+		if len(ids) > 0 {
+			app.logger.Infow("purged deleted posts", "count", len(ids))
+		}
+
+		if len(ids) < app.config.deletion.batchSize {
+			return nil
+		}
+	}
+}
+
+// cleanupOrphanedMedia deletes uploads that were never attached to a post,
+// or were detached from one, along with their files.
+func (app *application) cleanupOrphanedMedia(ctx context.Context) error {
+	const batchSize = 100
+
+	for {
+		orphans, err := app.store.Media.DeleteOrphans(ctx, app.config.media.orphanTTL, batchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range orphans {
+			for _, key := range []string{m.Key, m.ThumbnailKey} {
+				if err := app.media.Delete(ctx, key); err != nil {
+					app.logger.Warnw("failed to delete media file", "key", key, "error", err.Error())
+				}
+			}
+		}
+
+		if len(orphans) > 0 {
+			app.logger.Infow("deleted orphaned media", "count", len(orphans))
+		}
+
+		if len(orphans) < batchSize {
+			return nil
+		}
+	}
+}
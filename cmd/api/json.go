@@ -2,7 +2,9 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/go-playground/validator/v10"
 )
@@ -49,3 +51,35 @@ func (app *application) jsonResponse(w http.ResponseWriter, status int, data any
 		Data: data,
 	})
 }
+
+// paginatedJSONResponse wraps a page of data together with the encoded
+// cursors that fetch the next and previous pages, if any. The same pages
+// are linked from the Link header.
+func (app *application) paginatedJSONResponse(w http.ResponseWriter, r *http.Request, status int, data any, next, prev string) error {
+	type envelope struct {
+		Data       any    `json:"data"`
+		NextCursor string `json:"next_cursor,omitempty"`
+		PrevCursor string `json:"prev_cursor,omitempty"`
+	}
+
+	var links []string
+	for _, page := range []struct{ rel, cursor string }{{"next", next}, {"prev", prev}} {
+		if page.cursor == "" {
+			continue
+		}
+
+		u := *r.URL
+		q := u.Query()
+		q.Set("cursor", page.cursor)
+		q.Del("offset")
+		u.RawQuery = q.Encode()
+
+		links = append(links, fmt.Sprintf(`<%s>; rel="%s"`, u.RequestURI(), page.rel))
+	}
+
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+
+	return writeJSON(w, status, &envelope{Data: data, NextCursor: next, PrevCursor: prev})
+}
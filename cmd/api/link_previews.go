@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"sync"
+
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/store"
+)
+
+// queueLinkPreview asks the background workers to fetch the preview of the
+// first link of post. URLs dropped because the queue is full are picked up
+// by the next sweep.
+func (app *application) queueLinkPreview(post *store.Post) {
+	url, ok := store.FirstURL(post.Entities)
+	if !ok || app.linkPreviews == nil {
+		return
+	}
+
+	select {
+	case app.linkPreviews <- url:
+	default:
+	}
+}
+
+func (app *application) startLinkPreviewWorkers(ctx context.Context, wg *sync.WaitGroup) {
+	for range app.config.linkPreview.workers {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case url := <-app.linkPreviews:
+					if err := app.fetchLinkPreview(ctx, url); err != nil && ctx.Err() == nil {
+						app.logger.Errorw("failed to save link preview", "url", url, "error", err.Error())
+					}
+				}
+			}
+		}()
+	}
+}
+
+// sweepLinkPreviews fetches the previews of linked URLs that have none yet
+// or whose earlier fetch failed.
+func (app *application) sweepLinkPreviews(ctx context.Context) error {
+	cfg := app.config.linkPreview
+
+	urls, err := app.store.LinkPreviews.GetPending(ctx, cfg.retryAfter, cfg.batchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, url := range urls {
+		if err := app.fetchLinkPreview(ctx, url); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// fetchLinkPreview unfurls url and caches the outcome. Fetch failures are
+// cached as such and only storage errors are returned.
+func (app *application) fetchLinkPreview(ctx context.Context, url string) error {
+	preview := &store.LinkPreview{
+		URL:    url,
+		Status: store.LinkPreviewOK,
+	}
+
+	page, err := app.unfurler.Unfurl(ctx, url)
+	switch {
+	case err != nil:
+		app.logger.Infow("could not unfurl link", "url", url, "error", err.Error())
+		preview.Status = store.LinkPreviewFailed
+	case page.Title == "":
+		preview.Status = store.LinkPreviewFailed
+	default:
+		preview.Title = page.Title
+		preview.Description = page.Description
+		preview.ImageURL = page.ImageURL
+		preview.SiteName = page.SiteName
+	}
+
+	return app.store.LinkPreviews.Save(ctx, preview)
+}
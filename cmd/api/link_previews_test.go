@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/store"
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/unfurl"
+)
+
+type recordingLinkPreviewStore struct {
+	store.MockLinkPreviewStore
+	saved []store.LinkPreview
+}
+
+func (s *recordingLinkPreviewStore) Save(_ context.Context, preview *store.LinkPreview) error {
+	s.saved = append(s.saved, *preview)
+	return nil
+}
+
+func TestFetchLinkPreview(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/article" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><head><meta property="og:title" content="An article"></head></html>`)
+	}))
+	defer srv.Close()
+
+	app := newTestApplication(t)
+	previews := &recordingLinkPreviewStore{}
+	app.store.LinkPreviews = previews
+	app.unfurler = unfurl.New(unfurl.Config{
+		Timeout:              time.Second,
+		MaxBodyBytes:         1 << 10,
+		MaxRedirects:         1,
+		AllowPrivateNetworks: true,
+	})
+
+	for _, path := range []string{"/article", "/missing"} {
+		if err := app.fetchLinkPreview(context.Background(), srv.URL+path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(previews.saved) != 2 {
+		t.Fatalf("Expected 2 saved previews. Got %d", len(previews.saved))
+	}
+
+	if got := previews.saved[0]; got.Status != store.LinkPreviewOK || got.Title != "An article" {
+		t.Errorf("Expected the article preview. Got %+v", got)
+	}
+
+	if got := previews.saved[1]; got.Status != store.LinkPreviewFailed {
+		t.Errorf("Expected the missing page to be cached as failed. Got %+v", got)
+	}
+}
@@ -3,15 +3,19 @@ package main
 import (
 	"expvar"
 	"runtime"
+	"strings"
 	"time"
 
 	"github.com/AlfanDutaPamungkas/Go-Social/internal/auth"
 	"github.com/AlfanDutaPamungkas/Go-Social/internal/db"
 	"github.com/AlfanDutaPamungkas/Go-Social/internal/env"
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/events"
 	"github.com/AlfanDutaPamungkas/Go-Social/internal/mailer"
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/media"
 	ratelimiter "github.com/AlfanDutaPamungkas/Go-Social/internal/rate_limiter"
 	"github.com/AlfanDutaPamungkas/Go-Social/internal/store"
 	"github.com/AlfanDutaPamungkas/Go-Social/internal/store/cache"
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/unfurl"
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 )
@@ -79,6 +83,117 @@ func main() {
 			TimeFrame:           time.Second * 5,
 			Enabled:             env.GetBoolEnv("RATE_LIMITER_ENABLED", true),
 		},
+		scheduler: schedulerConfig{
+			enabled:   env.GetBoolEnv("POST_SCHEDULER_ENABLED", true),
+			interval:  time.Second * 30,
+			batchSize: 100,
+		},
+		explore: exploreConfig{
+			refreshInterval:  time.Minute * 5,
+			trendingRecent:   time.Hour,
+			trendingWindow:   time.Hour * 24,
+			trendingMinCount: 2,
+			popularWindow:    time.Hour * 24 * 7,
+			limit:            20,
+		},
+		media: mediaConfig{
+			dir:             env.GetEnv("MEDIA_DIR", "./uploads"),
+			baseURL:         env.GetEnv("MEDIA_BASE_URL", "/v1/media/files"),
+			maxUploadBytes:  int64(env.GetIntEnv("MEDIA_MAX_UPLOAD_BYTES", 5<<20)),
+			maxPixels:       40_000_000,
+			thumbnailSize:   320,
+			orphanTTL:       time.Hour * 24,
+			cleanupInterval: time.Hour,
+		},
+		linkPreview: linkPreviewConfig{
+			enabled:       env.GetBoolEnv("LINK_PREVIEWS_ENABLED", true),
+			timeout:       time.Second * 5,
+			maxBodyBytes:  512 << 10,
+			maxRedirects:  3,
+			workers:       4,
+			queueSize:     100,
+			sweepInterval: time.Minute,
+			retryAfter:    time.Hour,
+			batchSize:     50,
+		},
+		deletion: deletionConfig{
+			retention:     time.Hour * 24 * time.Duration(env.GetIntEnv("DELETED_POST_RETENTION_DAYS", 30)),
+			purgeInterval: time.Hour,
+			batchSize:     100,
+		},
+		stats: statsConfig{
+			enabled:       env.GetBoolEnv("POST_STATS_ENABLED", true),
+			dedupWindow:   time.Minute * 30,
+			queueSize:     10000,
+			flushInterval: time.Second * 10,
+			batchSize:     1000,
+			topPosts:      10,
+		},
+		idempotency: idempotencyConfig{
+			ttl:             time.Hour * 24,
+			lease:           time.Minute,
+			cleanupInterval: time.Hour,
+		},
+		spam: spamConfig{
+			enabled:         env.GetBoolEnv("SPAM_FILTER_ENABLED", true),
+			holdScore:       1,
+			hideScore:       2,
+			rejectScore:     3,
+			duplicateWindow: time.Minute * 10,
+			duplicateScore:  1,
+			maxLinks:        3,
+			wordsPerLink:    5,
+			linkScore:       1,
+			newAccountAge:   time.Hour * 24,
+			velocityWindow:  time.Minute,
+			velocityLimit:   5,
+			velocityScore:   1,
+			blockedWords:    strings.Split(env.GetEnv("SPAM_BLOCKED_WORDS", ""), ","),
+			blockedScore:    3,
+		},
+		timeline: timelineConfig{
+			enabled:      env.GetBoolEnv("TIMELINES_ENABLED", true),
+			fanoutLimit:  env.GetIntEnv("TIMELINE_FANOUT_LIMIT", 10000),
+			maxLength:    800,
+			backfill:     50,
+			workers:      4,
+			queueSize:    1000,
+			batchSize:    1000,
+			trimInterval: time.Hour,
+		},
+		ranking: store.FeedRanking{
+			RecencyWeight:    env.GetFloatEnv("FEED_RANK_RECENCY_WEIGHT", 1),
+			AffinityWeight:   env.GetFloatEnv("FEED_RANK_AFFINITY_WEIGHT", 0.6),
+			EngagementWeight: env.GetFloatEnv("FEED_RANK_ENGAGEMENT_WEIGHT", 0.4),
+			TagWeight:        env.GetFloatEnv("FEED_RANK_TAG_WEIGHT", 0.3),
+			HalfLife:         time.Hour * time.Duration(env.GetIntEnv("FEED_RANK_HALF_LIFE_HOURS", 6)),
+			AffinityWindow:   time.Hour * 24 * 30,
+			CandidateWindow:  time.Hour * 24 * 3,
+		},
+		events: eventsConfig{
+			enabled:     env.GetBoolEnv("EVENTS_ENABLED", true),
+			heartbeat:   time.Second * 15,
+			retry:       time.Second * 3,
+			bufferSize:  64,
+			historySize: 100,
+			historyTTL:  time.Hour * 24,
+			maxStreams:  env.GetIntEnv("EVENTS_MAX_STREAMS", 5),
+		},
+		gateway: gatewayConfig{
+			enabled:         env.GetBoolEnv("GATEWAY_ENABLED", true),
+			pingInterval:    time.Second * 30,
+			pongWait:        time.Second * 60,
+			writeWait:       time.Second * 10,
+			maxMessageBytes: 4096,
+			messageLimit:    env.GetIntEnv("GATEWAY_MESSAGE_LIMIT", 20),
+			messageWindow:   time.Second * 10,
+			maxTopics:       50,
+			sendBuffer:      64,
+		},
+		maxPinnedPosts:   env.GetIntEnv("MAX_PINNED_POSTS", 3),
+		maxCommentDepth:  env.GetIntEnv("MAX_COMMENT_DEPTH", 5),
+		minCommentLength: env.GetIntEnv("COMMENT_MIN_LENGTH", 1),
+		maxCommentLength: env.GetIntEnv("COMMENT_MAX_LENGTH", 2000),
 	}
 
 	// logger
@@ -115,6 +230,14 @@ func main() {
 
 	store := store.NewStorage(db)
 	cacheStorage := cache.NewRedisStorage(rdb)
+	if cfg.redisCfg.enable {
+		store.Timelines = cache.NewTimelineStore(rdb, cfg.timeline.maxLength)
+	}
+
+	mediaStorage, err := media.NewLocalStorage(cfg.media.dir, cfg.media.baseURL)
+	if err != nil {
+		logger.Fatal(err)
+	}
 
 	mailer := mailer.NewSMTPMailer(
 		cfg.mail.smtp.host,
@@ -137,6 +260,37 @@ func main() {
 		mailer:        mailer,
 		authenticator: jwtAuthenticator,
 		rateLimiter: rateLimiter,
+		media:       mediaStorage,
+		markdown: newMarkdownRenderer(cfg.frontendURL),
+		spam:     newSpamPipeline(cfg.spam, store.Spam),
+		unfurler: unfurl.New(unfurl.Config{
+			Timeout:      cfg.linkPreview.timeout,
+			MaxBodyBytes: cfg.linkPreview.maxBodyBytes,
+			MaxRedirects: cfg.linkPreview.maxRedirects,
+			UserAgent:    "GopherSocialBot/" + version,
+		}),
+	}
+
+	if cfg.linkPreview.enabled {
+		app.linkPreviews = make(chan string, cfg.linkPreview.queueSize)
+	}
+
+	if cfg.stats.enabled {
+		app.views = newViewQueue(cfg.stats.queueSize)
+	}
+
+	if cfg.timeline.enabled {
+		app.timelineEvents = make(chan timelineEvent, cfg.timeline.queueSize)
+	}
+
+	if cfg.events.enabled || cfg.gateway.enabled {
+		if cfg.redisCfg.enable {
+			app.events = events.NewRedisBroker(rdb, cfg.events.bufferSize, cfg.events.historySize, cfg.events.historyTTL)
+			app.presence = events.NewRedisPresence(rdb)
+		} else {
+			app.events = events.NewMemoryBroker(cfg.events.bufferSize, cfg.events.historySize)
+			app.presence = events.NewMemoryPresence()
+		}
 	}
 
 	expvar.NewString("version").Set(version)
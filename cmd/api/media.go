@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/media"
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+type MediaAttachmentPayload struct {
+	ID      int64  `json:"id" validate:"required"`
+	AltText string `json:"alt_text" validate:"max=1000"`
+}
+
+// attachmentsToMedia maps the attachments of a post payload, in order, to
+// the media the store attaches.
+func attachmentsToMedia(attachments []MediaAttachmentPayload) []store.Media {
+	media := make([]store.Media, len(attachments))
+	for i, a := range attachments {
+		media[i] = store.Media{ID: a.ID, AltText: a.AltText}
+	}
+
+	return media
+}
+
+// UploadMedia godoc
+//
+//	@Summary		Upload an image
+//	@Description	Uploads a JPEG, PNG or GIF image and generates its thumbnail. Reference the returned ID in the media of a post within 24 hours, after which unattached uploads are deleted.
+//	@Tags			media
+//	@Accept			mpfd
+//	@Produce		json
+//	@Param			file	formData	file	true	"Image file"
+//	@Success		201		{object}	store.Media
+//	@Failure		400		{object}	error	"Invalid or unsupported image"
+//	@Failure		413		{object}	error	"Image too large"
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/media [post]
+func (app *application) uploadMediaHandler(w http.ResponseWriter, r *http.Request) {
+	cfg := app.config.media
+
+	// Leave room for the multipart framing around the file itself.
+	r.Body = http.MaxBytesReader(w, r.Body, cfg.maxUploadBytes+1<<20)
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxBytesErr):
+			app.payloadTooLargeResponse(w, r, fmt.Errorf("images can be at most %d bytes", cfg.maxUploadBytes))
+		default:
+			app.badRequestResponse(w, r, err)
+		}
+		return
+	}
+	defer file.Close()
+
+	if header.Size > cfg.maxUploadBytes {
+		app.payloadTooLargeResponse(w, r, fmt.Errorf("images can be at most %d bytes", cfg.maxUploadBytes))
+		return
+	}
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	img, err := media.Process(data, cfg.maxPixels, cfg.thumbnailSize)
+	if err != nil {
+		switch {
+		case errors.Is(err, media.ErrUnsupportedType), errors.Is(err, media.ErrImageTooLarge):
+			app.badRequestResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	key, err := media.NewKey()
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	m := &store.Media{
+		Key:          key + img.Ext,
+		ThumbnailKey: key + "_thumb.jpg",
+		ContentType:  img.ContentType,
+		Width:        img.Width,
+		Height:       img.Height,
+		Size:         int64(len(img.Data)),
+	}
+	m.URL = app.media.URL(m.Key)
+	m.ThumbnailURL = app.media.URL(m.ThumbnailKey)
+
+	ctx := r.Context()
+
+	if err := app.media.Put(ctx, m.Key, bytes.NewReader(img.Data)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.media.Put(ctx, m.ThumbnailKey, bytes.NewReader(img.Thumbnail)); err != nil {
+		app.media.Delete(ctx, m.Key)
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.store.Media.Create(ctx, getUserFromCtx(r).ID, m); err != nil {
+		app.media.Delete(ctx, m.Key)
+		app.media.Delete(ctx, m.ThumbnailKey)
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, m); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// GetMediaFile godoc
+//
+//	@Summary		Get a media file
+//	@Description	Serves an uploaded image or thumbnail from local storage to the users who may read the post it is attached to. Files that are not attached yet are only served to their uploader. Files of posts that are not public require the Authorization header.
+//	@Tags			media
+//	@Produce		image/jpeg,image/png,image/gif
+//	@Param			key	path		string	true	"File key"
+//	@Success		200	{file}		file
+//	@Failure		401	{object}	error
+//	@Failure		404	{object}	error	"File not found"
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/media/files/{key} [get]
+func (app *application) getMediaFileHandler(w http.ResponseWriter, r *http.Request) {
+	key := chi.URLParam(r, "key")
+
+	viewer, err := app.mediaViewer(r)
+	if err != nil {
+		app.unauthorizedResponse(w, r, err)
+		return
+	}
+
+	m, err := app.store.Media.GetByKey(r.Context(), key)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	allowed, err := app.canViewMedia(r.Context(), viewer, m)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if !allowed {
+		app.notFoundResponse(w, r, store.ErrNotFound)
+		return
+	}
+
+	f, err := app.media.Open(r.Context(), key)
+	if err != nil {
+		switch {
+		case errors.Is(err, media.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+	defer f.Close()
+
+	// Files never change, but who may see them does, so they are only
+	// cached by the browser that was allowed to fetch them, and not for long.
+	w.Header().Set("Content-Type", mime.TypeByExtension(path.Ext(key)))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, max-age=3600")
+
+	if _, err := io.Copy(w, f); err != nil {
+		app.logger.Warnw("failed to serve media file", "key", key, "error", err.Error())
+	}
+}
+
+// mediaViewer returns the user fetching a media file. Files can be embedded
+// where no token is sent, so anonymous requests get a user without an ID.
+func (app *application) mediaViewer(r *http.Request) (*store.User, error) {
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		return &store.User{}, nil
+	}
+
+	token, ok := strings.CutPrefix(authHeader, "Bearer ")
+	if !ok {
+		return nil, fmt.Errorf("authorization header is malformed")
+	}
+
+	return app.authenticateToken(r.Context(), token)
+}
+
+// canViewMedia reports whether viewer may see the files of an upload: its
+// uploader until it is attached, then whoever may read its post.
+func (app *application) canViewMedia(ctx context.Context, viewer *store.User, m *store.Media) (bool, error) {
+	if m.PostID == nil {
+		return viewer.ID == m.UserID, nil
+	}
+
+	post, err := app.store.Posts.GetByID(ctx, *m.PostID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return app.canViewPost(ctx, viewer, post)
+}
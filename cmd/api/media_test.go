@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/store"
+)
+
+func newUploadRequest(t *testing.T, data []byte) *http.Request {
+	t.Helper()
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+
+	fw, err := mw.CreateFormFile("file", "image.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := fw.Write(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, "/v1/media", &body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+
+	return req
+}
+
+// attachedMediaStore returns uploads attached to post 1.
+type attachedMediaStore struct {
+	store.MockMediaStore
+}
+
+func (s *attachedMediaStore) GetByKey(_ context.Context, key string) (*store.Media, error) {
+	postID := int64(1)
+	return &store.Media{ID: 1, UserID: 42, PostID: &postID, Key: key}, nil
+}
+
+func TestMedia(t *testing.T) {
+	app := newTestApplication(t)
+	mux := app.mount()
+
+	testToken := newTestToken(t, app)
+
+	var img bytes.Buffer
+	if err := png.Encode(&img, image.NewRGBA(image.Rect(0, 0, 200, 100))); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("should store an uploaded image and its thumbnail", func(t *testing.T) {
+		req := newUploadRequest(t, img.Bytes())
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, http.StatusCreated, rr.Code)
+
+		var res struct {
+			Data struct {
+				URL          string `json:"url"`
+				ThumbnailURL string `json:"thumbnail_url"`
+			} `json:"data"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&res); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, url := range []string{res.Data.URL, res.Data.ThumbnailURL} {
+			req := newAuthenticatedRequest(t, app, http.MethodGet, url, "")
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(t, http.StatusOK, rr.Code)
+		}
+	})
+
+	t.Run("should only serve the files of a post to its readers", func(t *testing.T) {
+		app.store.Media = &attachedMediaStore{}
+		defer func() { app.store.Media = &store.MockMediaStore{} }()
+
+		if err := app.media.Put(context.Background(), "attached.png", bytes.NewReader(img.Bytes())); err != nil {
+			t.Fatal(err)
+		}
+
+		get := func() *http.Response {
+			req, err := http.NewRequest(http.MethodGet, "/v1/media/files/attached.png", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			return executeRequest(req, mux).Result()
+		}
+
+		res := get()
+		checkResponseCode(t, http.StatusOK, res.StatusCode)
+
+		if cacheControl := res.Header.Get("Cache-Control"); !strings.HasPrefix(cacheControl, "private") {
+			t.Errorf("Expected the file to be cached privately. Got %q", cacheControl)
+		}
+
+		app.store.Posts = store.NewMockPostStore(store.Post{
+			UserID:     42,
+			Status:     store.PostStatusPublished,
+			Visibility: store.PostVisibilityFollowers,
+		})
+		defer func() { app.store.Posts = &store.MockPostStore{} }()
+
+		checkResponseCode(t, http.StatusNotFound, get().StatusCode)
+	})
+
+	t.Run("should reject uploads that are not images", func(t *testing.T) {
+		req := newUploadRequest(t, []byte("not an image"))
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should reject uploads over the size limit", func(t *testing.T) {
+		req := newUploadRequest(t, make([]byte, app.config.media.maxUploadBytes+1))
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, http.StatusRequestEntityTooLarge, rr.Code)
+	})
+
+	t.Run("should reject posts with more than four attachments", func(t *testing.T) {
+		body := `{"title":"t","content":"c","media":[{"id":1},{"id":2},{"id":3},{"id":4},{"id":5}]}`
+		req := newAuthenticatedRequest(t, app, http.MethodPost, "/v1/posts", body)
+
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should reject the same attachment twice", func(t *testing.T) {
+		body := `{"title":"t","content":"c","media":[{"id":1},{"id":1}]}`
+		req := newAuthenticatedRequest(t, app, http.MethodPatch, "/v1/posts/1", body)
+
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+	})
+}
@@ -26,23 +26,9 @@ func (app *application) AuthTokenMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		token := parts[1]
-		jwtToken, err := app.authenticator.ValidateToken(token)
-		if err != nil {
-			app.unauthorizedResponse(w, r, err)
-			return
-		}
-
-		claims := jwtToken.Claims.(jwt.MapClaims)
-		userID, err := strconv.ParseInt(fmt.Sprintf("%.f", claims["sub"]), 10, 64)
-		if err != nil {
-			app.unauthorizedResponse(w, r, err)
-			return
-		}
-
 		ctx := r.Context()
 
-		user, err := app.getUser(ctx, userID)
+		user, err := app.authenticateToken(ctx, parts[1])
 		if err != nil {
 			app.unauthorizedResponse(w, r, err)
 			return
@@ -53,6 +39,22 @@ func (app *application) AuthTokenMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// authenticateToken returns the user a bearer token was issued to.
+func (app *application) authenticateToken(ctx context.Context, token string) (*store.User, error) {
+	jwtToken, err := app.authenticator.ValidateToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := jwtToken.Claims.(jwt.MapClaims)
+	userID, err := strconv.ParseInt(fmt.Sprintf("%.f", claims["sub"]), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return app.getUser(ctx, userID)
+}
+
 func (app *application) BasicAuthMiddleware() func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -113,6 +115,24 @@ func (app *application) checkPostOwnership(requiredRole string, next http.Handle
 	})
 }
 
+// requireRole only lets users with at least requiredRole through.
+func (app *application) requireRole(requiredRole string, next http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		allowed, err := app.checkRolePrecedence(r.Context(), getUserFromCtx(r), requiredRole)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		if !allowed {
+			app.forbiddenResponse(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
 func (app *application) checkRolePrecedence(ctx context.Context, user *store.User, roleName string) (bool, error) {
 	role, err := app.store.Roles.GetByName(ctx, roleName)
 	if err != nil {
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"net/http"
+)
+
+// GetNotifications godoc
+//
+//	@Summary		List notifications
+//	@Description	Lists the latest notifications of the authenticated user. Listing them does not mark them as read.
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{array}		store.Notification
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/notifications [get]
+func (app *application) getNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromCtx(r)
+
+	notifications, err := app.store.Notifications.GetByUserID(r.Context(), user.ID, 50)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, notifications); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+type MarkNotificationsReadPayload struct {
+	UpTo int64 `json:"up_to" validate:"required,min=1"`
+}
+
+// MarkNotificationsRead godoc
+//
+//	@Summary		Mark notifications as read
+//	@Description	Marks the notifications of the authenticated user up to and including the given ID as read. Clients pass the newest ID they listed, so that notifications that arrived since stay unread.
+//	@Tags			users
+//	@Accept			json
+//	@Param			body	body	MarkNotificationsReadPayload	true	"Newest notification read"
+//	@Success		204
+//	@Failure		400	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/notifications/read [post]
+func (app *application) markNotificationsReadHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromCtx(r)
+
+	var payload MarkNotificationsReadPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.Notifications.MarkRead(r.Context(), user.ID, payload.UpTo); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
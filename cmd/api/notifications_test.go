@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/store"
+)
+
+type readingNotificationStore struct {
+	store.MockNotificationStore
+	upTo int64
+}
+
+func (s *readingNotificationStore) MarkRead(_ context.Context, _, upTo int64) error {
+	s.upTo = upTo
+	return nil
+}
+
+func TestNotifications(t *testing.T) {
+	app := newTestApplication(t)
+	notifications := &readingNotificationStore{}
+	app.store.Notifications = notifications
+	mux := app.mount()
+
+	send := func(method, path, body string) int {
+		req := newAuthenticatedRequest(t, app, method, path, body)
+
+		return executeRequest(req, mux).Code
+	}
+
+	t.Run("should not mark notifications as read when listing them", func(t *testing.T) {
+		checkResponseCode(t, http.StatusOK, send(http.MethodGet, "/v1/users/notifications", ""))
+
+		if notifications.upTo != 0 {
+			t.Errorf("Expected no notification to be marked as read. Got up to %d", notifications.upTo)
+		}
+	})
+
+	t.Run("should mark notifications as read up to an ID", func(t *testing.T) {
+		checkResponseCode(t, http.StatusNoContent, send(http.MethodPost, "/v1/users/notifications/read", `{"up_to":12}`))
+
+		if notifications.upTo != 12 {
+			t.Errorf("Expected notifications up to 12 to be marked as read. Got up to %d", notifications.upTo)
+		}
+	})
+
+	t.Run("should require the newest ID read", func(t *testing.T) {
+		checkResponseCode(t, http.StatusBadRequest, send(http.MethodPost, "/v1/users/notifications/read", `{}`))
+	})
+}
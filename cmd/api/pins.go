@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/store"
+)
+
+type PinPostPayload struct {
+	Position *int `json:"position" validate:"omitempty,gte=0"`
+}
+
+// PinPost godoc
+//
+//	@Summary		Pin a post
+//	@Description	Pins one of the user's own posts to the top of their profile, or moves an already pinned post to the given position (0 is the top)
+//	@Tags			posts
+//	@Accept			json
+//	@Produce		json
+//	@Param			postID	path	int				true	"Post ID"
+//	@Param			body	body	PinPostPayload	false	"Pin position"
+//	@Success		204
+//	@Failure		400	{object}	error
+//	@Failure		403	{object}	error
+//	@Failure		404	{object}	error	"Post not found"
+//	@Failure		409	{object}	error	"Pinned posts limit reached"
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{postID}/pin [put]
+func (app *application) pinPostHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromCtx(r)
+	post := getPostFromCtx(r)
+
+	if post.UserID != user.ID {
+		app.forbiddenResponse(w, r)
+		return
+	}
+
+	if !post.IsPublished() {
+		app.badRequestResponse(w, r, errors.New("only published posts can be pinned"))
+		return
+	}
+
+	var payload PinPostPayload
+	if r.ContentLength != 0 {
+		if err := readJSON(w, r, &payload); err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		if err := Validate.Struct(payload); err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+
+	position := 0
+	if payload.Position != nil {
+		position = *payload.Position
+	}
+
+	if err := app.store.Posts.Pin(r.Context(), user.ID, post.ID, position, app.config.maxPinnedPosts); err != nil {
+		switch {
+		case errors.Is(err, store.ErrPinLimit):
+			app.conflictResponse(w, r, fmt.Errorf("%w: at most %d posts can be pinned", err, app.config.maxPinnedPosts))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UnpinPost godoc
+//
+//	@Summary		Unpin a post
+//	@Description	Removes one of the user's own posts from the pinned posts of their profile
+//	@Tags			posts
+//	@Accept			json
+//	@Produce		json
+//	@Param			postID	path	int	true	"Post ID"
+//	@Success		204
+//	@Failure		403	{object}	error
+//	@Failure		404	{object}	error	"Post not pinned"
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{postID}/pin [delete]
+func (app *application) unpinPostHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromCtx(r)
+	post := getPostFromCtx(r)
+
+	if post.UserID != user.ID {
+		app.forbiddenResponse(w, r)
+		return
+	}
+
+	if err := app.store.Posts.Unpin(r.Context(), user.ID, post.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
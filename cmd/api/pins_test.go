@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/store"
+)
+
+// pinningPostStore keeps posts and their pins in memory. Like the real
+// store, it leaves pinned posts out of GetByUserID.
+type pinningPostStore struct {
+	store.MockPostStore
+	posts  map[int64]store.Post
+	pinned []int64
+}
+
+func (s *pinningPostStore) GetByID(_ context.Context, id int64) (*store.Post, error) {
+	post, ok := s.posts[id]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+
+	return &post, nil
+}
+
+func (s *pinningPostStore) Pin(_ context.Context, _, postID int64, position, limit int) error {
+	pinned := slices.DeleteFunc(slices.Clone(s.pinned), func(id int64) bool { return id == postID })
+	if len(pinned) >= limit {
+		return store.ErrPinLimit
+	}
+
+	s.pinned = slices.Insert(pinned, min(position, len(pinned)), postID)
+
+	return nil
+}
+
+func (s *pinningPostStore) Unpin(_ context.Context, _, postID int64) error {
+	if !slices.Contains(s.pinned, postID) {
+		return store.ErrNotFound
+	}
+
+	s.pinned = slices.DeleteFunc(s.pinned, func(id int64) bool { return id == postID })
+
+	return nil
+}
+
+func (s *pinningPostStore) GetPinnedByUserID(context.Context, int64, int64, *store.PaginatedFeedQuery) ([]store.PostWithMetadata, error) {
+	posts := []store.PostWithMetadata{}
+	for _, id := range s.pinned {
+		posts = append(posts, store.PostWithMetadata{Post: s.posts[id], Pinned: true})
+	}
+
+	return posts, nil
+}
+
+func (s *pinningPostStore) GetByUserID(context.Context, int64, int64, *store.PaginatedFeedQuery) ([]store.PostWithMetadata, error) {
+	posts := []store.PostWithMetadata{}
+	for id := int64(len(s.posts)); id > 0; id-- {
+		post := s.posts[id]
+		if post.UserID == 0 && post.IsPublished() && !slices.Contains(s.pinned, id) {
+			posts = append(posts, store.PostWithMetadata{Post: post})
+		}
+	}
+
+	return posts, nil
+}
+
+func TestPinPost(t *testing.T) {
+	app := newTestApplication(t)
+	app.config.maxPinnedPosts = 2
+	mux := app.mount()
+
+	published := func(id, userID int64) store.Post {
+		return store.Post{
+			ID:         id,
+			UserID:     userID,
+			Status:     store.PostStatusPublished,
+			Visibility: store.PostVisibilityPublic,
+			CreatedAt:  time.Now().Add(time.Duration(id) * time.Minute),
+		}
+	}
+
+	posts := &pinningPostStore{posts: map[int64]store.Post{
+		1: published(1, 0),
+		2: published(2, 0),
+		3: published(3, 0),
+		4: {ID: 4, Status: store.PostStatusDraft, Visibility: store.PostVisibilityPublic},
+		5: published(5, 42),
+	}}
+	app.store.Posts = posts
+
+	send := func(method, target, body string) *httptest.ResponseRecorder {
+		req := newAuthenticatedRequest(t, app, method, target, body)
+
+		return executeRequest(req, mux)
+	}
+
+	checkPinned := func(t *testing.T, expected ...int64) {
+		t.Helper()
+
+		if !slices.Equal(posts.pinned, expected) {
+			t.Fatalf("Expected pinned posts %v. Got %v", expected, posts.pinned)
+		}
+	}
+
+	t.Run("should not pin someone else's post", func(t *testing.T) {
+		rr := send(http.MethodPut, "/v1/posts/5/pin", "")
+
+		checkResponseCode(t, http.StatusForbidden, rr.Code)
+		checkPinned(t)
+	})
+
+	t.Run("should not pin a draft", func(t *testing.T) {
+		rr := send(http.MethodPut, "/v1/posts/4/pin", "")
+
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+		checkPinned(t)
+	})
+
+	t.Run("should pin posts on top", func(t *testing.T) {
+		checkResponseCode(t, http.StatusNoContent, send(http.MethodPut, "/v1/posts/1/pin", "").Code)
+		checkResponseCode(t, http.StatusNoContent, send(http.MethodPut, "/v1/posts/2/pin", "").Code)
+
+		checkPinned(t, 2, 1)
+	})
+
+	t.Run("should not pin more than the limit", func(t *testing.T) {
+		rr := send(http.MethodPut, "/v1/posts/3/pin", "")
+
+		checkResponseCode(t, http.StatusConflict, rr.Code)
+		checkPinned(t, 2, 1)
+	})
+
+	t.Run("should move an already pinned post", func(t *testing.T) {
+		rr := send(http.MethodPut, "/v1/posts/1/pin", `{"position":0}`)
+
+		checkResponseCode(t, http.StatusNoContent, rr.Code)
+		checkPinned(t, 1, 2)
+	})
+
+	t.Run("should not unpin a post that is not pinned", func(t *testing.T) {
+		rr := send(http.MethodDelete, "/v1/posts/3/pin", "")
+
+		checkResponseCode(t, http.StatusNotFound, rr.Code)
+		checkPinned(t, 1, 2)
+	})
+
+	t.Run("should list pinned posts first without repeating them", func(t *testing.T) {
+		rr := send(http.MethodGet, "/v1/users/1/posts", "")
+
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		var response struct {
+			Data []store.PostWithMetadata `json:"data"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+			t.Fatal(err)
+		}
+
+		var ids []int64
+		var pinned []bool
+		for _, post := range response.Data {
+			ids = append(ids, post.ID)
+			pinned = append(pinned, post.Pinned)
+		}
+
+		if !slices.Equal(ids, []int64{1, 2, 3}) || !slices.Equal(pinned, []bool{true, true, false}) {
+			t.Fatalf("Expected posts [1 2 3] with the first two pinned. Got %v pinned %v", ids, pinned)
+		}
+	})
+
+	t.Run("should unpin a pinned post", func(t *testing.T) {
+		rr := send(http.MethodDelete, "/v1/posts/2/pin", "")
+
+		checkResponseCode(t, http.StatusNoContent, rr.Code)
+		checkPinned(t, 1)
+	})
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/store"
+)
+
+type CreatePollPayload struct {
+	Options        []string  `json:"options" validate:"required,min=2,max=10,unique,dive,required,max=100"`
+	MultipleChoice bool      `json:"multiple_choice"`
+	HideResults    bool      `json:"hide_results"`
+	ClosesAt       time.Time `json:"closes_at" validate:"required"`
+}
+
+// toPoll builds the poll of a new post, checking that it closes after the
+// post is published.
+func (payload *CreatePollPayload) toPoll(post *store.Post) (*store.Poll, error) {
+	opensAt := time.Now()
+	if post.PublishAt != nil {
+		opensAt = *post.PublishAt
+	}
+
+	if !payload.ClosesAt.After(opensAt) {
+		return nil, errors.New("poll closes_at must be after the post is published")
+	}
+
+	poll := &store.Poll{
+		MultipleChoice: payload.MultipleChoice,
+		HideResults:    payload.HideResults,
+		ClosesAt:       payload.ClosesAt,
+	}
+
+	for _, text := range payload.Options {
+		poll.Options = append(poll.Options, store.PollOption{Text: text})
+	}
+
+	return poll, nil
+}
+
+type VotePollPayload struct {
+	OptionIDs []int64 `json:"option_ids" validate:"required,min=1,max=10"`
+}
+
+// VotePoll godoc
+//
+//	@Summary		Vote in a poll
+//	@Description	Casts the user's single ballot in the poll attached to a post
+//	@Tags			posts
+//	@Accept			json
+//	@Produce		json
+//	@Param			postID	path		int				true	"Post ID"
+//	@Param			body	body		VotePollPayload	true	"Chosen options"
+//	@Success		200		{object}	store.Poll
+//	@Failure		400		{object}	error	"Invalid options"
+//	@Failure		404		{object}	error	"Post or poll not found"
+//	@Failure		409		{object}	error	"Already voted or poll closed"
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{postID}/poll/votes [post]
+func (app *application) votePollHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromCtx(r)
+	post := getPostFromCtx(r)
+
+	var payload VotePollPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	poll, err := app.store.Polls.GetByPostID(r.Context(), post.ID, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.store.Polls.Vote(r.Context(), poll.ID, user.ID, payload.OptionIDs); err != nil {
+		switch {
+		case errors.Is(err, store.ErrInvalidPollOption):
+			app.badRequestResponse(w, r, err)
+		case errors.Is(err, store.ErrAlreadyVoted), errors.Is(err, store.ErrPollClosed):
+			app.conflictResponse(w, r, err)
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	poll, err = app.store.Polls.GetByPostID(r.Context(), post.ID, user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, poll); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
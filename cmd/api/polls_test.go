@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/store"
+)
+
+type openPollStore struct {
+	store.MockPollStore
+}
+
+func (s *openPollStore) GetByPostID(_ context.Context, postID, _ int64) (*store.Poll, error) {
+	return &store.Poll{
+		ID:       1,
+		PostID:   postID,
+		ClosesAt: time.Now().Add(time.Hour),
+		Options:  []store.PollOption{{ID: 1, Text: "yes"}, {ID: 2, Text: "no"}},
+	}, nil
+}
+
+func (s *openPollStore) Vote(context.Context, int64, int64, []int64) error {
+	return store.ErrAlreadyVoted
+}
+
+func TestPolls(t *testing.T) {
+	app := newTestApplication(t)
+	mux := app.mount()
+
+	t.Run("should reject a poll with a single option", func(t *testing.T) {
+		body := `{"title":"t","content":"c","poll":{"options":["yes"],"closes_at":"2999-01-01T00:00:00Z"}}`
+		req := newAuthenticatedRequest(t, app, http.MethodPost, "/v1/posts", body)
+
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should reject a poll that closes in the past", func(t *testing.T) {
+		body := `{"title":"t","content":"c","poll":{"options":["yes","no"],"closes_at":"2000-01-01T00:00:00Z"}}`
+		req := newAuthenticatedRequest(t, app, http.MethodPost, "/v1/posts", body)
+
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should return not found when voting on a post without a poll", func(t *testing.T) {
+		req := newAuthenticatedRequest(t, app, http.MethodPost, "/v1/posts/1/poll/votes", `{"option_ids":[1]}`)
+
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("should reject a second vote", func(t *testing.T) {
+		app.store.Polls = &openPollStore{}
+
+		req := newAuthenticatedRequest(t, app, http.MethodPost, "/v1/posts/1/poll/votes", `{"option_ids":[1]}`)
+
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, http.StatusConflict, rr.Code)
+	})
+
+	t.Run("should always send a post with an open poll in full", func(t *testing.T) {
+		req := newAuthenticatedRequest(t, app, http.MethodGet, "/v1/posts/1", "")
+		req.Header.Set("If-None-Match", `"1-1"`)
+
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, http.StatusOK, rr.Code)
+	})
+}
@@ -2,11 +2,17 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/spam"
 	"github.com/AlfanDutaPamungkas/Go-Social/internal/store"
 	"github.com/go-chi/chi/v5"
 )
@@ -16,21 +22,30 @@ type postKey string
 const postCtx postKey = "post"
 
 type CreatePostPayload struct {
-	Title   string   `json:"title" validate:"required,max=100"`
-	Content string   `json:"content" validate:"required,max=1000"`
-	Tags    []string `json:"tags"`
+	Title          string                   `json:"title" validate:"required,max=100"`
+	Content        string                   `json:"content" validate:"required,max=1000"`
+	Tags           []string                 `json:"tags" validate:"max=10"`
+	Status         string                   `json:"status" validate:"omitempty,oneof=draft scheduled published"`
+	PublishAt      *time.Time               `json:"publish_at"`
+	Visibility     string                   `json:"visibility" validate:"omitempty,oneof=public followers mentioned private"`
+	Poll           *CreatePollPayload       `json:"poll"`
+	Media          []MediaAttachmentPayload `json:"media" validate:"max=4,unique=ID,dive"`
+	ContentWarning string                   `json:"content_warning" validate:"max=200"`
+	Sensitive      bool                     `json:"sensitive"`
+	CommentPolicy  string                   `json:"comment_policy" validate:"omitempty,oneof=everyone followers mentioned nobody"`
 }
 
 // CreatePost godoc
 //
 //	@Summary		Create a new post
-//	@Description	Creates a new post with title, content, and tags
+//	@Description	Creates a new post with title, content, and tags. Hashtags in the content are added to the tags and mentioned users are notified. Posts can be saved as drafts or scheduled for a later publish_at. Up to 4 uploaded images can be attached, in order, by their media ID. A content warning or the sensitive flag hides the post behind a warning for users who have not chosen to expand such posts. The comment policy restricts who may comment: everyone (the default), the author's followers, the users mentioned in the post, or nobody. Posts go through the spam filter, which may reject them or hold them for review.
 //	@Tags			posts
 //	@Accept			json
 //	@Produce		json
 //	@param			body	body		CreatePostPayload	true	"Post data"
 //	@Success		201		{object}	store.Post
 //	@Failure		400		{object}	error	"Invalid request payload"
+//	@Failure		422		{object}	error	"Rejected as spam"
 //	@Failure		500		{object}	error
 //	@Security		ApiKeyAuth
 //	@Router			/posts [post]
@@ -49,17 +64,78 @@ func (app *application) createPostHandler(w http.ResponseWriter, r *http.Request
 	user := getUserFromCtx(r)
 
 	post := &store.Post{
-		Title:   payload.Title,
-		Content: payload.Content,
-		Tags:    payload.Tags,
-		UserID:  int64(user.ID),
+		Title:      payload.Title,
+		Content:    payload.Content,
+		Tags:       payload.Tags,
+		Status:     payload.Status,
+		PublishAt:  payload.PublishAt,
+		Visibility: payload.Visibility,
+		Media:      attachmentsToMedia(payload.Media),
+		UserID:     int64(user.ID),
+
+		ContentWarning: strings.TrimSpace(payload.ContentWarning),
+		Sensitive:      payload.Sensitive,
+		CommentPolicy:  payload.CommentPolicy,
 	}
 
+	if post.CommentPolicy == "" {
+		post.CommentPolicy = store.CommentPolicyEveryone
+	}
+
+	if post.Visibility == "" {
+		post.Visibility = store.PostVisibilityPublic
+	}
+
+	post.Entities = store.ParseEntities(post.Content)
+
+	tags, err := store.PostTags(payload.Tags, post.Entities)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	post.Tags = tags
+
+	if post.Status == "" {
+		post.Status = store.PostStatusPublished
+	}
+
+	if err := validatePostSchedule(post); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if payload.Poll != nil {
+		poll, err := payload.Poll.toPoll(post)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+		post.Poll = poll
+	}
+
+	reviewStatus, reasons, ok := app.screenContent(w, r, post.UserID, spamContent(post.Title, post.Content, post.Entities))
+	if !ok {
+		return
+	}
+	post.ReviewStatus = reviewStatus
+	post.SpamReasons = reasons
+
 	if err := app.store.Posts.Create(r.Context(), post); err != nil {
-		app.internalServerError(w, r, err)
+		switch {
+		case errors.Is(err, store.ErrInvalidMedia):
+			app.badRequestResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
 		return
 	}
 
+	app.queueLinkPreview(post)
+	if post.IsPublished() {
+		app.publishToTimelines(post.ID)
+	}
+	app.renderPost(post)
+
 	if err := app.jsonResponse(w, http.StatusCreated, post); err != nil {
 		app.internalServerError(w, r, err)
 		return
@@ -69,28 +145,68 @@ func (app *application) createPostHandler(w http.ResponseWriter, r *http.Request
 // GetPost godoc
 //
 //	@Summary		Get a post by ID
-//	@Description	Retrieves a post along with its comments
+//	@Description	Retrieves a post along with its poll tallies and the first page of its newest comments. comments_total counts all comments and comments_next_cursor continues the listing at /posts/{postID}/comments. The ETag covers the whole response. It can also be sent as If-Match when updating or deleting the post, which only compares the post version.
 //	@Tags			posts
 //	@Accept			json
 //	@Produce		json
-//	@Param			postID	path		int	true	"Post ID"
-//	@Success		200		{object}	store.Post
-//	@Failure		404		{object}	error	"Post not found"
-//	@Failure		500		{object}	error
+//	@Param			postID			path		int		true	"Post ID"
+//	@Param			If-None-Match	header		string	false	"ETag of a cached copy of the post"
+//	@Success		200				{object}	store.Post
+//	@Success		304				"Post not modified"
+//	@Failure		404				{object}	error	"Post not found"
+//	@Failure		500				{object}	error
 //	@Security		ApiKeyAuth
 //	@Router			/posts/{postID} [get]
 func (app *application) getPostHandler(w http.ResponseWriter, r *http.Request) {
 	post := getPostFromCtx(r)
+	user := getUserFromCtx(r)
+
+	// Revalidations count as views too.
+	app.recordViews(user, store.ViewDetail, post)
+
+	poll, err := app.store.Polls.GetByPostID(r.Context(), post.ID, user.ID)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		app.internalServerError(w, r, err)
+		return
+	}
+	post.Poll = poll
+
+	q := newCommentQuery()
+	comments, err := app.store.Comments.GetPage(r.Context(), post.ID, user.ID, q)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
 
-	comments, err := app.store.Comments.GetCommentsByPostID(r.Context(), post.ID)
+	total, err := app.store.Comments.CountByPostID(r.Context(), post.ID, user.ID)
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 
 	post.Comments = comments
+	post.CommentsTotal = total
+	if next := q.NextCursor(comments); next != nil {
+		post.CommentsCursor = next.Encode()
+	}
+	app.renderPost(post)
+	post.Collapsed = post.CollapsedFor(user)
 
-	if err := app.jsonResponse(w, http.StatusOK, post); err != nil {
+	body, err := json.Marshal(post)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	etag := representationETag(postETag(post), body)
+	w.Header().Set("ETag", etag)
+
+	if matchETag(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, json.RawMessage(body)); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
@@ -99,25 +215,76 @@ func (app *application) getPostHandler(w http.ResponseWriter, r *http.Request) {
 // DeletePost godoc
 //
 //	@Summary		Delete a post
-//	@Description	Deletes a post by ID
+//	@Description	Soft-deletes a post by ID. It can be restored until it is purged after the retention period.
 //	@Tags			posts
 //	@Accept			json
 //	@Produce		json
-//	@Param			postID	path	int	true	"Post ID"
+//	@Param			postID		path	int		true	"Post ID"
+//	@Param			If-Match	header	string	false	"ETag the client last saw"
 //	@Success		204
 //	@Failure		404	{object}	error	"Post not found"
+//	@Failure		409	{object}	error	"Post was modified concurrently"
+//	@Failure		412	{object}	error	"Post was modified"
 //	@Failure		500	{object}	error
 //	@Security		ApiKeyAuth
 //	@Router			/posts/{postID} [delete]
 func (app *application) deletePostHandler(w http.ResponseWriter, r *http.Request) {
+	post := getPostFromCtx(r)
+
+	if !app.checkPostPrecondition(w, r, post) {
+		return
+	}
+
+	user := getUserFromCtx(r)
+
+	if err := app.store.Posts.Delete(r.Context(), post.ID, post.Version, user.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		case errors.Is(err, store.ErrVersionConflict) && r.Header.Get("If-Match") != "":
+			app.preconditionFailedResponse(w, r, err)
+		case errors.Is(err, store.ErrVersionConflict):
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.removeFromTimelines(post)
+	app.broadcastEvent(r.Context(), postTopic(post.ID), eventPostDeleted, user.ID, postDeletedEvent{PostID: post.ID})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RestorePost godoc
+//
+//	@Summary		Restore a deleted post
+//	@Description	Restores a soft-deleted post within the retention period. Authors can restore posts they deleted themselves; admins can restore any post.
+//	@Tags			posts
+//	@Produce		json
+//	@Param			postID	path		int	true	"Post ID"
+//	@Success		200		{object}	store.Post
+//	@Failure		403		{object}	error	"Post was deleted by someone else"
+//	@Failure		404		{object}	error	"Post not found or past the retention period"
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{postID}/restore [post]
+func (app *application) restorePostHandler(w http.ResponseWriter, r *http.Request) {
 	idParam := chi.URLParam(r, "postID")
 	id, err := strconv.ParseInt(idParam, 10, 64)
 	if err != nil {
-		app.internalServerError(w, r, err)
+		app.badRequestResponse(w, r, err)
 		return
 	}
 
-	if err := app.store.Posts.Delete(r.Context(), id); err != nil {
+	ctx := r.Context()
+
+	post, err := app.store.Posts.GetDeletedByID(ctx, id)
+	if err == nil && post.DeletedAt.Add(app.config.deletion.retention).Before(time.Now()) {
+		err = store.ErrNotFound
+	}
+	if err != nil {
 		switch {
 		case errors.Is(err, store.ErrNotFound):
 			app.notFoundResponse(w, r, err)
@@ -127,33 +294,85 @@ func (app *application) deletePostHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	w.WriteHeader(http.StatusNoContent)
+	user := getUserFromCtx(r)
+	if post.UserID != user.ID || post.DeletedBy == nil || *post.DeletedBy != user.ID {
+		allowed, err := app.checkRolePrecedence(ctx, user, "admin")
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		if !allowed {
+			app.forbiddenResponse(w, r)
+			return
+		}
+	}
+
+	if err := app.store.Posts.Restore(ctx, post.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	post, err = app.store.Posts.GetByID(ctx, post.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.publishToTimelines(post.ID)
+	app.renderPost(post)
+	post.Collapsed = post.CollapsedFor(user)
+
+	if err := app.jsonResponse(w, http.StatusOK, post); err != nil {
+		app.internalServerError(w, r, err)
+	}
 }
 
 type UpdatePostPayload struct {
-	Title   *string   `json:"title" validate:"omitempty,max=100"`
-	Content *string   `json:"content" validate:"omitempty,max=1000"`
-	Tags    *[]string `json:"tags" validate:"omitempty"`
+	Title          *string                   `json:"title" validate:"omitempty,max=100"`
+	Content        *string                   `json:"content" validate:"omitempty,max=1000"`
+	Tags           *[]string                 `json:"tags" validate:"omitempty,max=10"`
+	Status         *string                   `json:"status" validate:"omitempty,oneof=draft scheduled published"`
+	PublishAt      *time.Time                `json:"publish_at"`
+	Visibility     *string                   `json:"visibility" validate:"omitempty,oneof=public followers mentioned private"`
+	Media          *[]MediaAttachmentPayload `json:"media" validate:"omitempty,max=4,unique=ID,dive"`
+	ContentWarning *string                   `json:"content_warning" validate:"omitempty,max=200"`
+	Sensitive      *bool                     `json:"sensitive"`
+	CommentPolicy  *string                   `json:"comment_policy" validate:"omitempty,oneof=everyone followers mentioned nobody"`
 }
 
 // UpdatePost godoc
 //
 //	@Summary		Update a post
-//	@Description	Updates a post's title, content, or tags
+//	@Description	Updates a post's title, content, or tags. Sending media replaces the attached images. Only the author can change the content warning and comment policy here; moderators use the content-warning and comment-lock endpoints. An edited title or content is screened by the spam filter again, which can hold or hide the post.
 //	@Tags			posts
 //	@Accept			json
 //	@Produce		json
-//	@Param			postID	path		int					true	"Post ID"
-//	@Param			body	body		UpdatePostPayload	true	"Updated post data"
-//	@Success		200		{object}	store.Post
-//	@Failure		400		{object}	error	"Invalid request payload"
-//	@Failure		404		{object}	error	"Post not found"
-//	@Failure		500		{object}	error
+//	@Param			postID		path		int					true	"Post ID"
+//	@Param			If-Match	header		string				false	"ETag the client last saw"
+//	@Param			body		body		UpdatePostPayload	true	"Updated post data"
+//	@Success		200			{object}	store.Post
+//	@Failure		400			{object}	error	"Invalid request payload"
+//	@Failure		403			{object}	error	"Only the author can change the content warning or comment policy"
+//	@Failure		404			{object}	error	"Post not found"
+//	@Failure		409			{object}	error	"Post was modified concurrently"
+//	@Failure		412			{object}	error	"Post was modified"
+//	@Failure		422			{object}	error	"Rejected as spam"
+//	@Failure		500			{object}	error
 //	@Security		ApiKeyAuth
 //	@Router			/posts/{postID} [patch]
 func (app *application) updatePostHandler(w http.ResponseWriter, r *http.Request) {
 	post := getPostFromCtx(r)
 
+	if !app.checkPostPrecondition(w, r, post) {
+		return
+	}
+
 	var payload UpdatePostPayload
 	if err := readJSON(w, r, &payload); err != nil {
 		app.badRequestResponse(w, r, err)
@@ -165,6 +384,9 @@ func (app *application) updatePostHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	textChanged := payload.Content != nil && *payload.Content != post.Content
+	edited := textChanged || (payload.Title != nil && *payload.Title != post.Title)
+
 	if payload.Title != nil {
 		post.Title = *payload.Title
 	}
@@ -173,26 +395,152 @@ func (app *application) updatePostHandler(w http.ResponseWriter, r *http.Request
 		post.Content = *payload.Content
 	}
 
+	explicitTags := store.ExplicitTags(post.Tags, post.Entities)
 	if payload.Tags != nil {
-		post.Tags = *payload.Tags
+		explicitTags = *payload.Tags
+	}
+
+	post.Entities = store.ParseEntities(post.Content)
+
+	tags, err := store.PostTags(explicitTags, post.Entities)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	post.Tags = tags
+
+	if payload.Visibility != nil {
+		post.Visibility = *payload.Visibility
+	}
+
+	user := getUserFromCtx(r)
+
+	if payload.ContentWarning != nil || payload.Sensitive != nil || payload.CommentPolicy != nil {
+		if post.UserID != user.ID {
+			app.forbiddenResponse(w, r)
+			return
+		}
+
+		if payload.ContentWarning != nil {
+			post.ContentWarning = strings.TrimSpace(*payload.ContentWarning)
+		}
+
+		if payload.Sensitive != nil {
+			post.Sensitive = *payload.Sensitive
+		}
+
+		if payload.CommentPolicy != nil {
+			post.CommentPolicy = *payload.CommentPolicy
+		}
+	}
+
+	wasPublished := post.IsPublished()
+	if payload.Status != nil || payload.PublishAt != nil {
+		if wasPublished {
+			app.badRequestResponse(w, r, errors.New("a published post cannot be unpublished or rescheduled"))
+			return
+		}
+
+		if payload.Status != nil {
+			post.Status = *payload.Status
+		}
+
+		if payload.PublishAt != nil {
+			post.PublishAt = payload.PublishAt
+		}
+
+		if err := validatePostSchedule(post); err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+
+	// Edited posts go through the spam filter again, which can hold or hide
+	// them but leaves approving them to the moderators.
+	reviewStatus := post.ReviewStatus
+	if edited {
+		content := spamContent(post.Title, post.Content, post.Entities)
+		content.Edit = spam.Edit{PostID: post.ID, TextChanged: textChanged}
+
+		screened, reasons, ok := app.screenContent(w, r, post.UserID, content)
+		if !ok {
+			return
+		}
+		post.ReviewStatus = screened
+		post.SpamReasons = reasons
+	}
+
+	// The store only touches the attachments when post.Media is set.
+	attached := post.Media
+	post.Media = nil
+	if payload.Media != nil {
+		post.Media = attachmentsToMedia(*payload.Media)
 	}
 
 	post.UpdatedAt = time.Now()
 
 	if err := app.store.Posts.Update(r.Context(), post); err != nil {
 		switch {
+		case errors.Is(err, store.ErrInvalidMedia):
+			app.badRequestResponse(w, r, err)
 		case errors.Is(err, store.ErrNotFound):
 			app.notFoundResponse(w, r, err)
+		case errors.Is(err, store.ErrVersionConflict) && r.Header.Get("If-Match") != "":
+			app.preconditionFailedResponse(w, r, err)
+		case errors.Is(err, store.ErrVersionConflict):
+			app.conflictResponse(w, r, err)
 		default:
 			app.internalServerError(w, r, err)
 		}
 		return
 	}
 
-	if err := app.jsonResponse(w, http.StatusOK, post); err != nil {
+	if post.Media == nil {
+		post.Media = attached
+	}
+
+	app.queueLinkPreview(post)
+	// The fan-out adds the post to timelines or, once it is no longer
+	// approved, removes it from them.
+	if post.IsPublished() && (!wasPublished || post.ReviewStatus != reviewStatus) {
+		app.publishToTimelines(post.ID)
+	}
+	// Subscribers that may no longer see the post are dropped as the event
+	// is forwarded to them.
+	app.broadcastEvent(r.Context(), postTopic(post.ID), eventPostUpdated, user.ID, postUpdatedEvent{PostID: post.ID, Version: post.Version})
+	app.renderPost(post)
+	post.Collapsed = post.CollapsedFor(user)
+
+	app.postResponse(w, r, post)
+}
+
+// GetUserDrafts godoc
+//
+//	@Summary		List the user's unpublished posts
+//	@Description	Lists the authenticated user's drafts and scheduled posts
+//	@Tags			posts
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{array}		store.Post
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/drafts [get]
+func (app *application) getUserDraftsHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromCtx(r)
+
+	drafts, err := app.store.Posts.GetDraftsByUserID(r.Context(), user.ID)
+	if err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
+
+	for i := range drafts {
+		app.renderPost(&drafts[i])
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, drafts); err != nil {
+		app.internalServerError(w, r, err)
+	}
 }
 
 func (app *application) postsContextMiddleware(next http.Handler) http.Handler {
@@ -216,6 +564,18 @@ func (app *application) postsContextMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
+		visible, err := app.canViewPost(ctx, getUserFromCtx(r), post)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		// Hidden posts are reported as missing so their existence is not leaked.
+		if !visible {
+			app.notFoundResponse(w, r, fmt.Errorf("post %d is not visible to the user", post.ID))
+			return
+		}
+
 		ctx = context.WithValue(ctx, postCtx, post)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
@@ -225,3 +585,115 @@ func getPostFromCtx(r *http.Request) *store.Post {
 	post, _ := r.Context().Value(postCtx).(*store.Post)
 	return post
 }
+
+// canViewPost applies the post's status and visibility for the given user.
+// Moderators may read any published post so they can act on it.
+func (app *application) canViewPost(ctx context.Context, user *store.User, post *store.Post) (bool, error) {
+	var follows bool
+	if post.Visibility == store.PostVisibilityFollowers && post.UserID != user.ID {
+		var err error
+		follows, err = app.store.Followers.IsFollowing(ctx, user.ID, post.UserID)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	if post.VisibleTo(user, follows) {
+		return true, nil
+	}
+
+	if !post.IsPublished() {
+		return false, nil
+	}
+
+	return app.checkRolePrecedence(ctx, user, "moderator")
+}
+
+// validatePostSchedule checks that scheduled posts carry a future publish_at
+// and clears publish_at for any other status.
+func validatePostSchedule(post *store.Post) error {
+	if post.Status != store.PostStatusScheduled {
+		post.PublishAt = nil
+		return nil
+	}
+
+	if post.PublishAt == nil {
+		return errors.New("publish_at is required for scheduled posts")
+	}
+
+	if !post.PublishAt.After(time.Now()) {
+		return errors.New("publish_at must be in the future")
+	}
+
+	return nil
+}
+
+// checkPostPrecondition enforces an optional If-Match header against the
+// current version of the post. It writes a 412 and returns false when the
+// client edited a stale copy.
+func (app *application) checkPostPrecondition(w http.ResponseWriter, r *http.Request, post *store.Post) bool {
+	return app.checkPrecondition(w, r, postETag(post), fmt.Errorf("post %d is at version %d", post.ID, post.Version))
+}
+
+// checkPrecondition compares the If-Match header with the current etag of a
+// resource and responds with 412 when it does not match.
+func (app *application) checkPrecondition(w http.ResponseWriter, r *http.Request, etag string, err error) bool {
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" || matchETag(ifMatch, etag) {
+		return true
+	}
+
+	w.Header().Set("ETag", etag)
+	app.preconditionFailedResponse(w, r, err)
+	return false
+}
+
+// postResponse writes post with the representation ETag of the body sent,
+// like getPostHandler does, so that every ETag of a post has the same form.
+// The bodies of writes leave out the comments, so their ETag does not match
+// a read with If-None-Match, but it matches an If-Match on the same version.
+func (app *application) postResponse(w http.ResponseWriter, r *http.Request, post *store.Post) {
+	body, err := json.Marshal(post)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", representationETag(postETag(post), body))
+
+	if err := app.jsonResponse(w, http.StatusOK, json.RawMessage(body)); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func postETag(post *store.Post) string {
+	return fmt.Sprintf(`"%d-%d"`, post.ID, post.Version)
+}
+
+// representationETag extends the version ETag of a resource with a hash of
+// the body sent for it. The body can carry data that does not bump the
+// version, such as comments, link previews, poll tallies and fields that
+// depend on the viewer.
+func representationETag(etag string, body []byte) string {
+	sum := sha256.Sum256(body)
+	return strings.TrimSuffix(etag, `"`) + "." + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// matchETag reports whether an If-Match / If-None-Match header value lists
+// etag. Weak validators are compared by their opaque tag only. A
+// representation ETag also matches the version ETag it extends, so that
+// preconditions only compare versions.
+func matchETag(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+
+		if version, _, ok := strings.Cut(candidate, "."); ok && version+`"` == etag {
+			return true
+		}
+	}
+
+	return false
+}
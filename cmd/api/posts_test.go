@@ -0,0 +1,342 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/store"
+)
+
+type countingCommentStore struct {
+	store.MockCommentStore
+	total int
+}
+
+func (s *countingCommentStore) CountByPostID(context.Context, int64, int64) (int, error) {
+	return s.total, nil
+}
+
+func TestPostPreconditions(t *testing.T) {
+	app := newTestApplication(t)
+	mux := app.mount()
+
+	get := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		req := newAuthenticatedRequest(t, app, http.MethodGet, "/v1/posts/1", "")
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+
+		return executeRequest(req, mux)
+	}
+
+	var etag string
+
+	t.Run("should return the post version and a hash of the body as ETag", func(t *testing.T) {
+		rr := get("")
+
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		etag = rr.Header().Get("ETag")
+		if !strings.HasPrefix(etag, `"1-1.`) {
+			t.Errorf("Expected an ETag of version 1. Got %q", etag)
+		}
+	})
+
+	t.Run("should return not modified for a matching If-None-Match", func(t *testing.T) {
+		checkResponseCode(t, http.StatusNotModified, get(etag).Code)
+	})
+
+	t.Run("should not match the version alone", func(t *testing.T) {
+		checkResponseCode(t, http.StatusOK, get(`"1-1"`).Code)
+	})
+
+	t.Run("should send the post again when its comments change", func(t *testing.T) {
+		app.store.Comments = &countingCommentStore{total: 3}
+		defer func() { app.store.Comments = &store.MockCommentStore{} }()
+
+		checkResponseCode(t, http.StatusOK, get(etag).Code)
+	})
+
+	t.Run("should accept the representation ETag as If-Match", func(t *testing.T) {
+		if !matchETag(etag, `"1-1"`) {
+			t.Errorf("Expected %q to match version 1", etag)
+		}
+	})
+
+	t.Run("should reject updates of a stale version", func(t *testing.T) {
+		req := newAuthenticatedRequest(t, app, http.MethodPatch, "/v1/posts/1", `{"title":"new"}`)
+		req.Header.Set("If-Match", `"1-0"`)
+
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, http.StatusPreconditionFailed, rr.Code)
+	})
+
+	t.Run("should allow updates of the current version", func(t *testing.T) {
+		req := newAuthenticatedRequest(t, app, http.MethodPatch, "/v1/posts/1", `{"title":"new"}`)
+		req.Header.Set("If-Match", `"1-1"`)
+
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, http.StatusOK, rr.Code)
+		if etag := rr.Header().Get("ETag"); !strings.HasPrefix(etag, `"1-2.`) {
+			t.Errorf("Expected a representation ETag of version 2. Got %q", etag)
+		}
+	})
+
+	t.Run("should reject deletes of a stale version", func(t *testing.T) {
+		req := newAuthenticatedRequest(t, app, http.MethodDelete, "/v1/posts/1", "")
+		req.Header.Set("If-Match", `"1-0"`)
+
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, http.StatusPreconditionFailed, rr.Code)
+	})
+
+	t.Run("should reject deletes that race an update", func(t *testing.T) {
+		app.store.Posts = &racingPostStore{}
+		defer func() { app.store.Posts = &store.MockPostStore{} }()
+
+		req := newAuthenticatedRequest(t, app, http.MethodDelete, "/v1/posts/1", "")
+		req.Header.Set("If-Match", `"1-1"`)
+
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, http.StatusPreconditionFailed, rr.Code)
+	})
+}
+
+// racingPostStore behaves as if the post was updated between loading and
+// deleting it.
+type racingPostStore struct {
+	store.MockPostStore
+}
+
+func (s *racingPostStore) Delete(context.Context, int64, int, int64) error {
+	return store.ErrVersionConflict
+}
+
+type followingStore struct {
+	store.MockFollowerStore
+}
+
+func (s *followingStore) IsFollowing(context.Context, int64, int64) (bool, error) {
+	return true, nil
+}
+
+func TestPostVisibility(t *testing.T) {
+	app := newTestApplication(t)
+	app.store.Posts = store.NewMockPostStore(store.Post{
+		UserID:     42,
+		Status:     store.PostStatusPublished,
+		Visibility: store.PostVisibilityFollowers,
+	})
+	mux := app.mount()
+
+	t.Run("should not allow a non-follower to read a followers-only post", func(t *testing.T) {
+		rr := executeRequest(newAuthenticatedRequest(t, app, http.MethodGet, "/v1/posts/1", ""), mux)
+
+		checkResponseCode(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("should not allow a non-follower to comment on a followers-only post", func(t *testing.T) {
+		rr := executeRequest(newAuthenticatedRequest(t, app, http.MethodPost, "/v1/posts/1/comment", `{"content":"hi"}`), mux)
+
+		checkResponseCode(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("should allow a follower to read a followers-only post", func(t *testing.T) {
+		app.store.Followers = &followingStore{}
+
+		rr := executeRequest(newAuthenticatedRequest(t, app, http.MethodGet, "/v1/posts/1", ""), mux)
+
+		checkResponseCode(t, http.StatusOK, rr.Code)
+	})
+}
+
+func TestPostContentHTML(t *testing.T) {
+	app := newTestApplication(t)
+	mux := app.mount()
+
+	body := `{"title":"t","content":"**hi** <b>there</b> #go"}`
+	rr := executeRequest(newAuthenticatedRequest(t, app, http.MethodPost, "/v1/posts", body), mux)
+
+	checkResponseCode(t, http.StatusCreated, rr.Code)
+
+	var res struct {
+		Data store.Post `json:"data"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `<p><strong>hi</strong> &lt;b&gt;there&lt;/b&gt; ` +
+		`<a href="http://localhost:5173/tags/go" class="hashtag" rel="nofollow noopener" target="_blank">#go</a></p>` + "\n"
+	if res.Data.ContentHTML != want {
+		t.Errorf("Expected %q. Got %q", want, res.Data.ContentHTML)
+	}
+}
+
+type deletedByOtherPostStore struct {
+	store.MockPostStore
+}
+
+func (s *deletedByOtherPostStore) GetDeletedByID(_ context.Context, id int64) (*store.Post, error) {
+	deletedAt := time.Now()
+	deletedBy := int64(42)
+	return &store.Post{ID: id, DeletedAt: &deletedAt, DeletedBy: &deletedBy}, nil
+}
+
+func TestRestorePost(t *testing.T) {
+	app := newTestApplication(t)
+	app.config.deletion.retention = time.Hour
+	mux := app.mount()
+
+	restore := func() *httptest.ResponseRecorder {
+		return executeRequest(newAuthenticatedRequest(t, app, http.MethodPost, "/v1/posts/1/restore", ""), mux)
+	}
+
+	t.Run("should allow the author to restore their own deletion", func(t *testing.T) {
+		rr := restore()
+
+		checkResponseCode(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("should not restore a post past the retention period", func(t *testing.T) {
+		app.config.deletion.retention = 0
+		defer func() { app.config.deletion.retention = time.Hour }()
+
+		rr := restore()
+
+		checkResponseCode(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("should not allow the author to restore a post deleted by someone else", func(t *testing.T) {
+		app.store.Posts = &deletedByOtherPostStore{}
+		defer func() { app.store.Posts = &store.MockPostStore{} }()
+
+		rr := restore()
+
+		checkResponseCode(t, http.StatusForbidden, rr.Code)
+	})
+}
+
+type draftsPostStore struct {
+	store.MockPostStore
+	userIDs []int64
+}
+
+func (s *draftsPostStore) GetDraftsByUserID(_ context.Context, userID int64) ([]store.Post, error) {
+	s.userIDs = append(s.userIDs, userID)
+
+	return []store.Post{{ID: 1, UserID: userID, Status: store.PostStatusDraft}}, nil
+}
+
+func TestPostDrafts(t *testing.T) {
+	app := newTestApplication(t)
+	mux := app.mount()
+
+	send := func(method, target, body string) *httptest.ResponseRecorder {
+		return executeRequest(newAuthenticatedRequest(t, app, method, target, body), mux)
+	}
+
+	for _, status := range []string{store.PostStatusDraft, store.PostStatusScheduled} {
+		t.Run("should hide a "+status+" post from other users", func(t *testing.T) {
+			app.store.Posts = store.NewMockPostStore(store.Post{UserID: 42, Status: status, Visibility: store.PostVisibilityPublic, Version: 1})
+
+			rr := send(http.MethodGet, "/v1/posts/1", "")
+
+			checkResponseCode(t, http.StatusNotFound, rr.Code)
+		})
+
+		t.Run("should show a "+status+" post to its author", func(t *testing.T) {
+			app.store.Posts = store.NewMockPostStore(store.Post{Status: status, Visibility: store.PostVisibilityPublic, Version: 1})
+
+			rr := send(http.MethodGet, "/v1/posts/1", "")
+
+			checkResponseCode(t, http.StatusOK, rr.Code)
+		})
+	}
+
+	t.Run("should list only the drafts of the authenticated user", func(t *testing.T) {
+		posts := &draftsPostStore{}
+		app.store.Posts = posts
+
+		rr := send(http.MethodGet, "/v1/users/drafts", "")
+
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		if len(posts.userIDs) != 1 || posts.userIDs[0] != 0 {
+			t.Fatalf("expected the drafts of user 0 to be listed, got %v", posts.userIDs)
+		}
+
+		var response struct {
+			Data []store.Post `json:"data"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(response.Data) != 1 || response.Data[0].UserID != 0 {
+			t.Fatalf("expected the draft of user 0, got %+v", response.Data)
+		}
+	})
+
+	t.Run("should not unpublish or reschedule a published post", func(t *testing.T) {
+		app.store.Posts = &store.MockPostStore{}
+
+		publishAt := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+
+		for _, body := range []string{
+			`{"status":"draft"}`,
+			`{"status":"scheduled","publish_at":"` + publishAt + `"}`,
+			`{"publish_at":"` + publishAt + `"}`,
+		} {
+			rr := send(http.MethodPatch, "/v1/posts/1", body)
+
+			checkResponseCode(t, http.StatusBadRequest, rr.Code)
+
+			if !strings.Contains(rr.Body.String(), "cannot be unpublished or rescheduled") {
+				t.Fatalf("expected %s to be rejected as a published post, got %s", body, rr.Body)
+			}
+		}
+	})
+}
+
+func TestValidatePostSchedule(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name      string
+		status    string
+		publishAt *time.Time
+		wantErr   bool
+	}{
+		{"scheduled without publish_at", store.PostStatusScheduled, nil, true},
+		{"scheduled in the past", store.PostStatusScheduled, &past, true},
+		{"scheduled in the future", store.PostStatusScheduled, &future, false},
+		{"draft with publish_at", store.PostStatusDraft, &future, false},
+		{"published with publish_at", store.PostStatusPublished, &past, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			post := &store.Post{Status: tt.status, PublishAt: tt.publishAt}
+
+			err := validatePostSchedule(post)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+
+			if tt.status != store.PostStatusScheduled && post.PublishAt != nil {
+				t.Fatalf("expected publish_at to be cleared for a %s post", tt.status)
+			}
+		})
+	}
+}
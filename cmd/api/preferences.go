@@ -0,0 +1,76 @@
+package main
+
+import (
+	"net/http"
+)
+
+type UpdatePreferencesPayload struct {
+	SensitiveContent *string `json:"sensitive_content" validate:"omitempty,oneof=hide expand"`
+}
+
+// GetPreferences godoc
+//
+//	@Summary		Get the user's preferences
+//	@Description	Returns the authenticated user's preferences
+//	@Tags			users
+//	@Produce		json
+//	@Success		200	{object}	store.UserPreferences
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/preferences [get]
+func (app *application) getPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromCtx(r)
+
+	if err := app.jsonResponse(w, http.StatusOK, user.Preferences); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// UpdatePreferences godoc
+//
+//	@Summary		Update the user's preferences
+//	@Description	Updates the authenticated user's preferences. sensitive_content is "hide" to show posts with a content warning or sensitive flag collapsed behind it, or "expand" to show them expanded.
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		UpdatePreferencesPayload	true	"Preferences"
+//	@Success		200		{object}	store.UserPreferences
+//	@Failure		400		{object}	error	"Invalid request payload"
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/preferences [patch]
+func (app *application) updatePreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromCtx(r)
+
+	var payload UpdatePreferencesPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	prefs := user.Preferences
+	if payload.SensitiveContent != nil {
+		prefs.SensitiveContent = *payload.SensitiveContent
+	}
+
+	if err := app.store.Users.UpdatePreferences(r.Context(), user.ID, &prefs); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	// The user is cached by the auth middleware.
+	if app.config.redisCfg.enable {
+		if err := app.cacheStorage.Users.Delete(r.Context(), user.ID); err != nil {
+			app.logger.Warnw("failed to invalidate cached user", "user_id", user.ID, "error", err.Error())
+		}
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, prefs); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/spam"
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+var errRejectedAsSpam = errors.New("the content was rejected by the spam filter")
+
+// newSpamPipeline builds the spam checks from cfg. A disabled filter allows
+// everything.
+func newSpamPipeline(cfg spamConfig, history spam.History) *spam.Pipeline {
+	if !cfg.enabled {
+		return spam.New(spam.Thresholds{})
+	}
+
+	return spam.New(
+		spam.Thresholds{Hold: cfg.holdScore, Hide: cfg.hideScore, Reject: cfg.rejectScore},
+		&spam.Duplicates{History: history, Window: cfg.duplicateWindow, Score: cfg.duplicateScore},
+		&spam.LinkDensity{MaxLinks: cfg.maxLinks, WordsPerLink: cfg.wordsPerLink, Score: cfg.linkScore},
+		&spam.Velocity{
+			History:       history,
+			NewAccountAge: cfg.newAccountAge,
+			Window:        cfg.velocityWindow,
+			Limit:         cfg.velocityLimit,
+			Score:         cfg.velocityScore,
+		},
+		spam.NewBlockedWords(cfg.blockedWords, cfg.blockedScore),
+	)
+}
+
+// spamContent describes a post or comment for the spam filter.
+func spamContent(title, text string, entities []store.Entity) *spam.Content {
+	content := &spam.Content{Title: title, Text: text}
+	for _, entity := range entities {
+		if entity.Type == store.EntityURL {
+			content.Links++
+		}
+	}
+	return content
+}
+
+// screenContent runs the spam filter on a post or comment by authorID that
+// is about to be created or edited. The content is scored against its
+// author even when a moderator edits it. Rejected content gets a 422
+// response and false; otherwise the review status and spam reasons to store
+// it with are returned.
+func (app *application) screenContent(w http.ResponseWriter, r *http.Request, authorID int64, content *spam.Content) (string, []string, bool) {
+	author := getUserFromCtx(r)
+	if author.ID != authorID {
+		var err error
+		author, err = app.getUser(r.Context(), authorID)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return "", nil, false
+		}
+	}
+
+	content.AuthorID = author.ID
+	content.AuthorSince = author.CreatedAt
+
+	verdict, err := app.spam.Evaluate(r.Context(), content)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return "", nil, false
+	}
+
+	if verdict.Action != spam.Allow {
+		app.logger.Infow("spam filter", "action", verdict.Action.String(), "user_id", author.ID, "score", verdict.Score, "reasons", verdict.Reasons())
+	}
+
+	switch verdict.Action {
+	case spam.Reject:
+		app.unprocessableEntityResponse(w, r, errRejectedAsSpam)
+		return "", nil, false
+	case spam.Hide:
+		return store.ReviewHidden, verdict.Reasons(), true
+	case spam.Hold:
+		return store.ReviewHeld, verdict.Reasons(), true
+	default:
+		return store.ReviewApproved, verdict.Reasons(), true
+	}
+}
+
+// GetHeldContent godoc
+//
+//	@Summary		List content flagged as spam
+//	@Description	Lists the posts and comments the spam filter held for review, or hid from everyone but their author, oldest first. Requires the moderator role.
+//	@Tags			moderation
+//	@Produce		json
+//	@Param			status	query		string	false	"Review status"						default(held)	Enums(held, hidden)
+//	@Param			limit	query		int		false	"Number of items to retrieve (1-100)"	default(50)
+//	@Success		200		{array}		store.HeldContent
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/moderation/held [get]
+func (app *application) getHeldContentHandler(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = store.ReviewHeld
+	}
+
+	if status != store.ReviewHeld && status != store.ReviewHidden {
+		app.badRequestResponse(w, r, errors.New("status must be held or hidden"))
+		return
+	}
+
+	limit := 50
+	if l := r.URL.Query().Get("limit"); l != "" {
+		var err error
+		limit, err = strconv.Atoi(l)
+		if err != nil || limit < 1 || limit > 100 {
+			app.badRequestResponse(w, r, errors.New("limit must be between 1 and 100"))
+			return
+		}
+	}
+
+	held, err := app.store.Spam.GetHeld(r.Context(), status, limit)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, held); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+type ReviewPayload struct {
+	Status string `json:"status" validate:"required,oneof=approved hidden"`
+	Reason string `json:"reason" validate:"max=500"`
+}
+
+// ReviewHeldContent godoc
+//
+//	@Summary		Review content flagged as spam
+//	@Description	Approves a held or hidden post or comment, which publishes it and notifies the users it mentions, or hides it from everyone but its author. The decision is recorded in the moderation log. Requires the moderator role.
+//	@Tags			moderation
+//	@Accept			json
+//	@Param			kind	path	string			true	"Content kind"	Enums(post, comment)
+//	@Param			id		path	int				true	"Post or comment ID"
+//	@Param			body	body	ReviewPayload	true	"Decision"
+//	@Success		204
+//	@Failure		400	{object}	error
+//	@Failure		403	{object}	error
+//	@Failure		404	{object}	error	"No held or hidden content with that ID"
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/moderation/held/{kind}/{id} [put]
+func (app *application) reviewHeldContentHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromCtx(r)
+
+	kind := chi.URLParam(r, "kind")
+	if kind != store.ContentKindPost && kind != store.ContentKindComment {
+		app.notFoundResponse(w, r, errors.New("kind must be post or comment"))
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var payload ReviewPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	entry := &store.ModerationEntry{
+		ModeratorID: user.ID,
+		Action:      store.ModerationSpamHide,
+		Reason:      strings.TrimSpace(payload.Reason),
+	}
+	if payload.Status == store.ReviewApproved {
+		entry.Action = store.ModerationSpamApprove
+	}
+
+	if err := app.store.Spam.Review(r.Context(), kind, id, payload.Status, entry); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if kind == store.ContentKindPost {
+		app.publishToTimelines(id)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/spam"
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/store"
+)
+
+func TestSpamFilter(t *testing.T) {
+	app := newTestApplication(t)
+	app.spam = spam.New(spam.Thresholds{Hold: 1, Reject: 3}, spam.NewBlockedWords([]string{"casino"}, 3))
+	mux := app.mount()
+
+	send := func(method, path, body string) int {
+		req := newAuthenticatedRequest(t, app, method, path, body)
+
+		return executeRequest(req, mux).Code
+	}
+
+	t.Run("should accept an ordinary comment", func(t *testing.T) {
+		checkResponseCode(t, http.StatusCreated, send(http.MethodPost, "/v1/posts/1/comment", `{"content":"Nice post"}`))
+	})
+
+	t.Run("should reject a comment with a blocked word", func(t *testing.T) {
+		checkResponseCode(t, http.StatusUnprocessableEntity, send(http.MethodPost, "/v1/posts/1/comment", `{"content":"Best CASINO in town"}`))
+	})
+
+	t.Run("should reject a post with a blocked word in its title", func(t *testing.T) {
+		checkResponseCode(t, http.StatusUnprocessableEntity, send(http.MethodPost, "/v1/posts", `{"title":"casino night","content":"come by"}`))
+	})
+
+	t.Run("should reject a comment longer than the limit", func(t *testing.T) {
+		body := `{"content":"` + strings.Repeat("a", 101) + `"}`
+
+		checkResponseCode(t, http.StatusBadRequest, send(http.MethodPost, "/v1/posts/1/comment", body))
+	})
+
+	t.Run("should reject a blank comment", func(t *testing.T) {
+		checkResponseCode(t, http.StatusBadRequest, send(http.MethodPost, "/v1/posts/1/comment", `{"content":"   "}`))
+	})
+
+	t.Run("should not allow a regular user to review held content", func(t *testing.T) {
+		checkResponseCode(t, http.StatusForbidden, send(http.MethodGet, "/v1/moderation/held", ""))
+		checkResponseCode(t, http.StatusForbidden, send(http.MethodPut, "/v1/moderation/held/comment/1", `{"status":"approved"}`))
+	})
+}
+
+type approvedPostStore struct {
+	*store.MockPostStore
+	updated *store.Post
+}
+
+func newApprovedPostStore(author int64) *approvedPostStore {
+	return &approvedPostStore{MockPostStore: store.NewMockPostStore(store.Post{
+		UserID:       author,
+		Title:        "Hello",
+		Content:      "Nice to meet you",
+		Status:       store.PostStatusPublished,
+		Visibility:   store.PostVisibilityPublic,
+		ReviewStatus: store.ReviewApproved,
+		Version:      1,
+	})}
+}
+
+func (s *approvedPostStore) Update(_ context.Context, post *store.Post) error {
+	updated := *post
+	s.updated = &updated
+	return nil
+}
+
+type approvedCommentStore struct {
+	store.MockCommentStore
+	updated *store.Comment
+}
+
+func (s *approvedCommentStore) GetByID(_ context.Context, postID, commentID int64) (*store.Comment, error) {
+	return &store.Comment{
+		ID:           commentID,
+		PostID:       postID,
+		Content:      "Nice post",
+		ReviewStatus: store.ReviewApproved,
+		Replies:      []store.Comment{},
+	}, nil
+}
+
+func (s *approvedCommentStore) Update(_ context.Context, comment *store.Comment, _ *store.ModerationEntry) error {
+	updated := *comment
+	s.updated = &updated
+	return nil
+}
+
+func TestSpamFilterEdits(t *testing.T) {
+	app := newTestApplication(t)
+	app.spam = spam.New(
+		spam.Thresholds{Hold: 1, Hide: 2, Reject: 3},
+		spam.NewBlockedWords([]string{"prize"}, 1),
+		spam.NewBlockedWords([]string{"casino"}, 3),
+	)
+	posts := newApprovedPostStore(0)
+	app.store.Posts = posts
+	comments := &approvedCommentStore{}
+	app.store.Comments = comments
+	app.timelineEvents = make(chan timelineEvent, 1)
+	mux := app.mount()
+
+	send := func(method, path, body string) int {
+		req := newAuthenticatedRequest(t, app, method, path, body)
+
+		return executeRequest(req, mux).Code
+	}
+
+	t.Run("should hold an approved post edited into spam", func(t *testing.T) {
+		checkResponseCode(t, http.StatusOK, send(http.MethodPatch, "/v1/posts/1", `{"content":"Win a prize"}`))
+
+		if posts.updated == nil || posts.updated.ReviewStatus != store.ReviewHeld {
+			t.Fatalf("Expected the post to be held. Got %+v", posts.updated)
+		}
+
+		select {
+		case event := <-app.timelineEvents:
+			if event.kind != timelinePublish || event.postID != 1 {
+				t.Errorf("Expected the post to be fanned out again. Got %+v", event)
+			}
+		default:
+			t.Error("Expected the timelines to be updated")
+		}
+	})
+
+	t.Run("should reject a post edited into blocked content", func(t *testing.T) {
+		posts.updated = nil
+
+		checkResponseCode(t, http.StatusUnprocessableEntity, send(http.MethodPatch, "/v1/posts/1", `{"title":"Casino night"}`))
+
+		if posts.updated != nil {
+			t.Error("Expected the post not to be saved")
+		}
+	})
+
+	t.Run("should not screen a post whose text did not change", func(t *testing.T) {
+		checkResponseCode(t, http.StatusOK, send(http.MethodPatch, "/v1/posts/1", `{"sensitive":true}`))
+
+		if posts.updated.ReviewStatus != store.ReviewApproved {
+			t.Errorf("Expected the post to stay approved. Got %s", posts.updated.ReviewStatus)
+		}
+
+		select {
+		case event := <-app.timelineEvents:
+			t.Errorf("Expected the timelines to be left alone. Got %+v", event)
+		default:
+		}
+	})
+
+	t.Run("should hold an approved comment edited into spam", func(t *testing.T) {
+		checkResponseCode(t, http.StatusOK, send(http.MethodPatch, "/v1/posts/1/comments/2", `{"content":"Win a prize"}`))
+
+		if comments.updated == nil || comments.updated.ReviewStatus != store.ReviewHeld {
+			t.Fatalf("Expected the comment to be held. Got %+v", comments.updated)
+		}
+	})
+
+	t.Run("should reject a comment edited into blocked content", func(t *testing.T) {
+		comments.updated = nil
+
+		checkResponseCode(t, http.StatusUnprocessableEntity, send(http.MethodPatch, "/v1/posts/1/comments/2", `{"content":"Casino tonight"}`))
+
+		if comments.updated != nil {
+			t.Error("Expected the comment not to be saved")
+		}
+	})
+}
+
+// recordingCheck remembers the content the spam filter was asked about.
+type recordingCheck struct {
+	content *spam.Content
+}
+
+func (c *recordingCheck) Check(_ context.Context, content *spam.Content) (*spam.Signal, error) {
+	c.content = content
+	return nil, nil
+}
+
+func TestSpamFilterEditHistory(t *testing.T) {
+	app := newTestApplication(t)
+	check := &recordingCheck{}
+	app.spam = spam.New(spam.Thresholds{Hold: 1}, check)
+	app.store.Users = &moderatorUserStore{}
+	app.store.Posts = newApprovedPostStore(7)
+	mux := app.mount()
+
+	req := newAuthenticatedRequest(t, app, http.MethodPatch, "/v1/posts/1", `{"title":"Hello again"}`)
+	checkResponseCode(t, http.StatusOK, executeRequest(req, mux).Code)
+
+	if check.content == nil {
+		t.Fatal("Expected the edited post to be screened")
+	}
+
+	if check.content.AuthorID != 7 {
+		t.Errorf("Expected a moderator's edit to be scored against the author. Got user %d", check.content.AuthorID)
+	}
+
+	want := spam.Edit{PostID: 1}
+	if check.content.Edit != want {
+		t.Errorf("Expected the edit to leave out post 1 and its unchanged text. Got %+v", check.content.Edit)
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/store"
+)
+
+const maxStatsHours = 24 * 90
+
+func newViewQueue(size int) chan store.PostView {
+	return make(chan store.PostView, size)
+}
+
+// recordViews queues a view of kind of each post for the stats worker.
+// Authors viewing their own posts are not counted, and views are dropped
+// when the queue is full rather than slowing the request down.
+func (app *application) recordViews(viewer *store.User, kind string, posts ...*store.Post) {
+	if app.views == nil {
+		return
+	}
+
+	now := time.Now()
+	for _, post := range posts {
+		if post.UserID == viewer.ID {
+			continue
+		}
+
+		select {
+		case app.views <- store.PostView{PostID: post.ID, UserID: viewer.ID, Kind: kind, SeenAt: now}:
+		default:
+		}
+	}
+}
+
+func (app *application) recordImpressions(viewer *store.User, posts []store.PostWithMetadata) {
+	for i := range posts {
+		app.recordViews(viewer, store.ViewImpression, &posts[i].Post)
+	}
+}
+
+// startStatsWorker aggregates the queued views into the hourly buckets in
+// batches. Views still queued at shutdown are flushed before it returns.
+func (app *application) startStatsWorker(ctx context.Context, wg *sync.WaitGroup) {
+	cfg := app.config.stats
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(cfg.flushInterval)
+		defer ticker.Stop()
+
+		batch := make([]store.PostView, 0, cfg.batchSize)
+		flush := func(ctx context.Context) {
+			if len(batch) == 0 {
+				return
+			}
+
+			if err := app.store.Stats.RecordViews(ctx, batch, cfg.dedupWindow); err != nil {
+				app.logger.Errorw("failed to record post views", "count", len(batch), "error", err.Error())
+			}
+			batch = batch[:0]
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				for len(app.views) > 0 {
+					batch = append(batch, <-app.views)
+				}
+
+				// ctx is done, but the store applies its own timeout.
+				flush(context.Background())
+				return
+			case view := <-app.views:
+				batch = append(batch, view)
+				if len(batch) >= cfg.batchSize {
+					flush(ctx)
+				}
+			case <-ticker.C:
+				flush(ctx)
+			}
+		}
+	}()
+}
+
+func (app *application) pruneViewDedup(ctx context.Context) error {
+	_, err := app.store.Stats.PruneViewDedup(ctx, app.config.stats.dedupWindow)
+	return err
+}
+
+// statsSince reads the hours query parameter, the number of past hours to
+// return hourly buckets for.
+func statsSince(r *http.Request) (time.Time, error) {
+	hours := 24 * 7
+
+	if param := r.URL.Query().Get("hours"); param != "" {
+		n, err := strconv.Atoi(param)
+		if err != nil || n < 1 || n > maxStatsHours {
+			return time.Time{}, errors.New("hours must be between 1 and 2160")
+		}
+		hours = n
+	}
+
+	return time.Now().Add(-time.Duration(hours) * time.Hour).Truncate(time.Hour), nil
+}
+
+// GetPostStats godoc
+//
+//	@Summary		Get a post's stats
+//	@Description	Returns the impressions and views of one of the user's own posts, in total and per hour. Repeated impressions or views by the same user within the de-duplication window count once.
+//	@Tags			posts
+//	@Produce		json
+//	@Param			postID	path		int	true	"Post ID"
+//	@Param			hours	query		int	false	"Past hours to return hourly buckets for (default 168, max 2160)"
+//	@Success		200		{object}	store.PostStats
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error	"Post not found"
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{postID}/stats [get]
+func (app *application) getPostStatsHandler(w http.ResponseWriter, r *http.Request) {
+	post := getPostFromCtx(r)
+	user := getUserFromCtx(r)
+
+	if post.UserID != user.ID {
+		app.forbiddenResponse(w, r)
+		return
+	}
+
+	since, err := statsSince(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	stats, err := app.store.Stats.GetPostStats(r.Context(), post.ID, since)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, stats); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// GetUserStats godoc
+//
+//	@Summary		Get the user's stats
+//	@Description	Returns the impressions and views of all of the user's posts, in total and per hour, along with their most viewed posts
+//	@Tags			users
+//	@Produce		json
+//	@Param			hours	query		int	false	"Past hours to return hourly buckets for (default 168, max 2160)"
+//	@Success		200		{object}	store.UserStats
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/stats [get]
+func (app *application) getUserStatsHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromCtx(r)
+
+	since, err := statsSince(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	stats, err := app.store.Stats.GetUserStats(r.Context(), user.ID, since, app.config.stats.topPosts)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, stats); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/store"
+)
+
+func TestPostStats(t *testing.T) {
+	app := newTestApplication(t)
+	app.views = newViewQueue(10)
+	mux := app.mount()
+
+	get := func(path string) int {
+		return executeRequest(newAuthenticatedRequest(t, app, http.MethodGet, path, ""), mux).Code
+	}
+
+	t.Run("should return the stats of the user's own post", func(t *testing.T) {
+		checkResponseCode(t, http.StatusOK, get("/v1/posts/1/stats"))
+	})
+
+	t.Run("should reject an out of range hours parameter", func(t *testing.T) {
+		checkResponseCode(t, http.StatusBadRequest, get("/v1/users/me/stats?hours=0"))
+	})
+
+	t.Run("should not count authors viewing their own post", func(t *testing.T) {
+		checkResponseCode(t, http.StatusOK, get("/v1/posts/1"))
+
+		if len(app.views) != 0 {
+			t.Errorf("Expected no queued views. Got %d", len(app.views))
+		}
+	})
+
+	app.store.Posts = store.NewMockPostStore(store.Post{UserID: 42, Status: store.PostStatusPublished, Visibility: store.PostVisibilityPublic})
+
+	t.Run("should count a view of someone else's post", func(t *testing.T) {
+		checkResponseCode(t, http.StatusOK, get("/v1/posts/1"))
+
+		if len(app.views) != 1 {
+			t.Fatalf("Expected 1 queued view. Got %d", len(app.views))
+		}
+
+		if view := <-app.views; view.Kind != store.ViewDetail || view.PostID != 1 {
+			t.Errorf("Unexpected view %+v", view)
+		}
+	})
+
+	t.Run("should not show the stats of someone else's post", func(t *testing.T) {
+		checkResponseCode(t, http.StatusForbidden, get("/v1/posts/1/stats"))
+	})
+}
@@ -1,11 +1,15 @@
 package main
 
 import (
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/AlfanDutaPamungkas/Go-Social/internal/auth"
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/media"
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/spam"
 	"github.com/AlfanDutaPamungkas/Go-Social/internal/store"
 	"github.com/AlfanDutaPamungkas/Go-Social/internal/store/cache"
 	"go.uber.org/zap"
@@ -19,14 +23,64 @@ func newTestApplication(t *testing.T) *application {
 	mockCacheStore := cache.NewMockStore()
 	testAuth := &auth.TestAuthenticator{}
 
+	mediaStorage, err := media.NewLocalStorage(t.TempDir(), "/v1/media/files")
+	if err != nil {
+		t.Fatal(err)
+	}
+
 	return &application{
-		logger:       logger,
-		store:        mockStore,
-		cacheStorage: mockCacheStore,
+		config: config{
+			media: mediaConfig{
+				maxUploadBytes: 1 << 20,
+				maxPixels:      1_000_000,
+				thumbnailSize:  64,
+			},
+			maxCommentDepth:  2,
+			minCommentLength: 1,
+			maxCommentLength: 100,
+		},
+		logger:        logger,
+		store:         mockStore,
+		cacheStorage:  mockCacheStore,
 		authenticator: testAuth,
+		media:         mediaStorage,
+		markdown:      newMarkdownRenderer("http://localhost:5173"),
+		spam:          spam.New(spam.Thresholds{}),
 	}
 }
 
+// newTestToken returns a token of the test user.
+func newTestToken(t *testing.T, app *application) string {
+	t.Helper()
+
+	token, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return token
+}
+
+// newAuthenticatedRequest builds a request made by the test user. An empty
+// body sends none.
+func newAuthenticatedRequest(t *testing.T, app *application, method, path, body string) *http.Request {
+	t.Helper()
+
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+
+	req, err := http.NewRequest(method, path, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+newTestToken(t, app))
+
+	return req
+}
+
 func executeRequest(req *http.Request, mux http.Handler) *httptest.ResponseRecorder {
 	rr := httptest.NewRecorder()
 	mux.ServeHTTP(rr, req)
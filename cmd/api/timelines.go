@@ -0,0 +1,318 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/store"
+)
+
+// Home timelines are written as posts are published (fan-out on write) by
+// the timeline workers, which take their work from app.timelineEvents.
+const (
+	timelinePublish  = "publish"
+	timelineRemove   = "remove"
+	timelineFollow   = "follow"
+	timelineUnfollow = "unfollow"
+	timelineRebuild  = "rebuild"
+)
+
+type timelineEvent struct {
+	kind string
+	// postID is the post to publish.
+	postID int64
+	// entry and authorID are the post to remove.
+	entry    store.TimelineEntry
+	authorID int64
+	// userID is the timeline to rebuild, or the user that followed or
+	// unfollowed authorID.
+	userID int64
+}
+
+// queueTimeline hands event to the timeline workers. Events dropped
+// because the queue is full leave timelines stale until they are rebuilt,
+// so they are logged.
+func (app *application) queueTimeline(event timelineEvent) {
+	if app.timelineEvents == nil {
+		return
+	}
+
+	select {
+	case app.timelineEvents <- event:
+	default:
+		app.logger.Warnw("timeline queue is full", "event", event.kind)
+	}
+}
+
+func (app *application) publishToTimelines(postID int64) {
+	app.queueTimeline(timelineEvent{kind: timelinePublish, postID: postID})
+}
+
+func (app *application) removeFromTimelines(post *store.Post) {
+	app.queueTimeline(timelineEvent{
+		kind:     timelineRemove,
+		entry:    store.TimelineEntry{PostID: post.ID, CreatedAt: post.CreatedAt},
+		authorID: post.UserID,
+	})
+}
+
+func (app *application) startTimelineWorkers(ctx context.Context, wg *sync.WaitGroup) {
+	for range app.config.timeline.workers {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case event := <-app.timelineEvents:
+					if err := app.handleTimelineEvent(ctx, event); err != nil && ctx.Err() == nil {
+						app.logger.Errorw("failed to update timelines", "event", event.kind, "error", err.Error())
+					}
+				}
+			}
+		}()
+	}
+}
+
+func (app *application) handleTimelineEvent(ctx context.Context, event timelineEvent) error {
+	cfg := app.config.timeline
+
+	switch event.kind {
+	case timelinePublish:
+		return app.fanOut(ctx, event.postID)
+	case timelineRemove:
+		return app.fanOutRemoval(ctx, event.entry, event.authorID)
+	case timelineFollow:
+		large, err := app.isLargeAccount(ctx, event.authorID)
+		if err != nil || large {
+			return err
+		}
+
+		entries, err := app.store.Posts.GetTimelineEntries(ctx, []int64{event.authorID}, cfg.backfill)
+		if err != nil {
+			return err
+		}
+
+		return app.store.Timelines.Backfill(ctx, event.userID, entries)
+	case timelineUnfollow:
+		// Older posts of the author have been trimmed from the timeline.
+		entries, err := app.store.Posts.GetTimelineEntries(ctx, []int64{event.authorID}, cfg.maxLength)
+		if err != nil {
+			return err
+		}
+
+		return app.store.Timelines.Remove(ctx, entries, []int64{event.userID})
+	case timelineRebuild:
+		followees, err := app.store.Followers.GetFolloweeIDs(ctx, event.userID, 0)
+		if err != nil {
+			return err
+		}
+
+		entries, err := app.store.Posts.GetTimelineEntries(ctx, append(followees, event.userID), cfg.maxLength)
+		if err != nil {
+			return err
+		}
+
+		return app.store.Timelines.Backfill(ctx, event.userID, entries)
+	}
+
+	return nil
+}
+
+// fanOut writes a published post to the timeline of its author and to the
+// timelines of their followers, unless the author is a large account.
+// Posts held or hidden by the spam filter are kept out of, or taken back
+// out of, the timelines of the followers; the others are announced to the
+// followers' event streams.
+func (app *application) fanOut(ctx context.Context, postID int64) error {
+	post, err := app.store.Posts.GetByID(ctx, postID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			// Deleted before it was fanned out.
+			return nil
+		}
+		return err
+	}
+
+	if !post.IsPublished() {
+		return nil
+	}
+
+	entry := store.TimelineEntry{PostID: post.ID, CreatedAt: post.CreatedAt}
+	if err := app.store.Timelines.Add(ctx, entry, []int64{post.UserID}); err != nil {
+		return err
+	}
+
+	return app.forEachFollowerBatch(ctx, post.UserID, func(userIDs []int64) error {
+		if post.ReviewStatus != store.ReviewApproved {
+			return app.store.Timelines.Remove(ctx, []store.TimelineEntry{entry}, userIDs)
+		}
+
+		if err := app.store.Timelines.Add(ctx, entry, userIDs); err != nil {
+			return err
+		}
+
+		// Followers only hear of posts they may read, so a post visible to
+		// the users it mentions reaches just the followers it mentions.
+		recipients := slices.DeleteFunc(slices.Clone(userIDs), func(id int64) bool {
+			return !post.VisibleTo(&store.User{ID: id}, true)
+		})
+
+		app.publishEvent(ctx, eventPost, postEvent{PostID: post.ID, AuthorID: post.UserID}, recipients...)
+		return nil
+	})
+}
+
+// fanOutRemoval takes a deleted or hidden post out of the timelines it was
+// fanned out to.
+func (app *application) fanOutRemoval(ctx context.Context, entry store.TimelineEntry, authorID int64) error {
+	entries := []store.TimelineEntry{entry}
+	if err := app.store.Timelines.Remove(ctx, entries, []int64{authorID}); err != nil {
+		return err
+	}
+
+	return app.forEachFollowerBatch(ctx, authorID, func(userIDs []int64) error {
+		return app.store.Timelines.Remove(ctx, entries, userIDs)
+	})
+}
+
+// forEachFollowerBatch calls fn with the followers of authorID, a batch at
+// a time. Large accounts are skipped: their posts are merged into
+// timelines as they are read instead.
+func (app *application) forEachFollowerBatch(ctx context.Context, authorID int64, fn func([]int64) error) error {
+	large, err := app.isLargeAccount(ctx, authorID)
+	if err != nil || large {
+		return err
+	}
+
+	batchSize := app.config.timeline.batchSize
+
+	var afterID int64
+	for {
+		userIDs, err := app.store.Followers.GetFollowerIDs(ctx, authorID, afterID, batchSize)
+		if err != nil {
+			return err
+		}
+
+		if len(userIDs) > 0 {
+			if err := fn(userIDs); err != nil {
+				return err
+			}
+		}
+
+		if len(userIDs) < batchSize {
+			return nil
+		}
+		afterID = userIDs[len(userIDs)-1]
+	}
+}
+
+func (app *application) isLargeAccount(ctx context.Context, userID int64) (bool, error) {
+	count, err := app.store.Followers.CountFollowers(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	return count >= app.config.timeline.fanoutLimit, nil
+}
+
+// trimTimelines drops the entries past the length timelines are kept to.
+func (app *application) trimTimelines(ctx context.Context) error {
+	count, err := app.store.Timelines.Trim(ctx, app.config.timeline.maxLength)
+	if err != nil {
+		return err
+	}
+
+	if count > 0 {
+		app.logger.Infow("trimmed timelines", "count", count)
+	}
+
+	return nil
+}
+
+// timelineFeed reads a page of the feed of user from their timeline,
+// merging in the posts of the large accounts they follow, and returns it
+// with the cursors around it. ok is false when the feed has to be queried
+// instead, because it is filtered, sorted oldest first or paged by offset,
+// or because the timeline ran out.
+func (app *application) timelineFeed(ctx context.Context, user *store.User, p *store.PaginatedFeedQuery) (feed []store.PostWithMetadata, next, prev string, ok bool, err error) {
+	if !app.config.timeline.enabled || p.Sort != "desc" || p.Offset > 0 || len(p.Tags) > 0 ||
+		p.Search != "" || !p.Since.IsZero() || !p.Until.IsZero() {
+		return nil, "", "", false, nil
+	}
+
+	entries, err := app.store.Timelines.Page(ctx, user.ID, p.Cursor, p.Limit)
+	if err != nil {
+		return nil, "", "", false, err
+	}
+
+	if len(entries) < p.Limit {
+		if len(entries) == 0 && p.Cursor == nil {
+			// The timeline was never built or has expired.
+			app.queueTimeline(timelineEvent{kind: timelineRebuild, userID: user.ID})
+		}
+		return nil, "", "", false, nil
+	}
+
+	var large []store.PostWithMetadata
+	largeIDs, err := app.store.Followers.GetFolloweeIDs(ctx, user.ID, app.config.timeline.fanoutLimit)
+	if err != nil {
+		return nil, "", "", false, err
+	}
+
+	if len(largeIDs) > 0 {
+		large, err = app.store.Posts.GetByAuthorIDs(ctx, user.ID, largeIDs, p)
+		if err != nil {
+			return nil, "", "", false, err
+		}
+	}
+
+	// The page is cut from the positions of the posts, before the posts
+	// that were deleted or are not visible to user are dropped, so that
+	// cursors keep moving past them.
+	positions := make([]store.PostWithMetadata, len(entries))
+	for i, entry := range entries {
+		positions[i].ID = entry.PostID
+		positions[i].CreatedAt = entry.CreatedAt
+	}
+
+	page := p.MergePages(large, positions)
+	next, prev = pageCursors(p, page)
+
+	loaded := make(map[int64]store.PostWithMetadata, len(page))
+	for _, post := range large {
+		loaded[post.ID] = post
+	}
+
+	var ids []int64
+	for _, post := range page {
+		if _, ok := loaded[post.ID]; !ok {
+			ids = append(ids, post.ID)
+		}
+	}
+
+	if len(ids) > 0 {
+		posts, err := app.store.Posts.GetFeedByIDs(ctx, user.ID, ids)
+		if err != nil {
+			return nil, "", "", false, err
+		}
+
+		for _, post := range posts {
+			loaded[post.ID] = post
+		}
+	}
+
+	feed = make([]store.PostWithMetadata, 0, len(page))
+	for _, position := range page {
+		if post, ok := loaded[position.ID]; ok {
+			feed = append(feed, post)
+		}
+	}
+
+	return feed, next, prev, true, nil
+}
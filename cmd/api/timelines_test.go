@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/events"
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/store"
+)
+
+type recordingTimelineStore struct {
+	store.MockTimelineStore
+	entries []store.TimelineEntry
+	added   map[int64][]int64
+}
+
+func (s *recordingTimelineStore) Add(_ context.Context, entry store.TimelineEntry, userIDs []int64) error {
+	for _, userID := range userIDs {
+		s.added[userID] = append(s.added[userID], entry.PostID)
+	}
+	return nil
+}
+
+func (s *recordingTimelineStore) Page(_ context.Context, _ int64, _ *store.Cursor, limit int) ([]store.TimelineEntry, error) {
+	return s.entries[:min(limit, len(s.entries))], nil
+}
+
+type timelinePostStore struct {
+	store.MockPostStore
+	authorID int64
+	large    []store.PostWithMetadata
+	hidden   int64
+	queried  bool
+}
+
+func (s *timelinePostStore) GetByID(_ context.Context, id int64) (*store.Post, error) {
+	return &store.Post{ID: id, UserID: s.authorID, Status: store.PostStatusPublished, ReviewStatus: store.ReviewApproved}, nil
+}
+
+func (s *timelinePostStore) GetFeedByIDs(_ context.Context, _ int64, ids []int64) ([]store.PostWithMetadata, error) {
+	feed := []store.PostWithMetadata{}
+	for _, id := range ids {
+		if id != s.hidden {
+			feed = append(feed, store.PostWithMetadata{Post: store.Post{ID: id}})
+		}
+	}
+	return feed, nil
+}
+
+func (s *timelinePostStore) GetByAuthorIDs(context.Context, int64, []int64, *store.PaginatedFeedQuery) ([]store.PostWithMetadata, error) {
+	return s.large, nil
+}
+
+func (s *timelinePostStore) GetUserFeed(context.Context, int64, *store.PaginatedFeedQuery) ([]store.PostWithMetadata, error) {
+	s.queried = true
+	return []store.PostWithMetadata{}, nil
+}
+
+type timelineFollowerStore struct {
+	store.MockFollowerStore
+	followers int
+	large     []int64
+}
+
+func (s *timelineFollowerStore) CountFollowers(context.Context, int64) (int, error) {
+	return s.followers, nil
+}
+
+func (s *timelineFollowerStore) GetFollowerIDs(_ context.Context, _ int64, afterID int64, limit int) ([]int64, error) {
+	var ids []int64
+	for id := afterID + 1; id <= int64(s.followers) && len(ids) < limit; id++ {
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (s *timelineFollowerStore) GetFolloweeIDs(context.Context, int64, int) ([]int64, error) {
+	return s.large, nil
+}
+
+func TestTimelineFeed(t *testing.T) {
+	now := time.Now()
+
+	app := newTestApplication(t)
+	app.config.timeline = timelineConfig{enabled: true, fanoutLimit: 100, maxLength: 800}
+	app.timelineEvents = make(chan timelineEvent, 1)
+
+	timelines := &recordingTimelineStore{entries: []store.TimelineEntry{
+		{PostID: 5, CreatedAt: now},
+		{PostID: 3, CreatedAt: now.Add(-2 * time.Minute)},
+		{PostID: 1, CreatedAt: now.Add(-4 * time.Minute)},
+	}}
+	posts := &timelinePostStore{
+		large:  []store.PostWithMetadata{{Post: store.Post{ID: 4, CreatedAt: now.Add(-time.Minute)}}},
+		hidden: 3,
+	}
+	app.store.Timelines = timelines
+	app.store.Posts = posts
+	app.store.Followers = &timelineFollowerStore{large: []int64{9}}
+	mux := app.mount()
+
+	get := func(query string) *http.Response {
+		req := newAuthenticatedRequest(t, app, http.MethodGet, "/v1/users/feed"+query, "")
+
+		return executeRequest(req, mux).Result()
+	}
+
+	t.Run("should merge large accounts into the timeline", func(t *testing.T) {
+		res := get("?limit=3")
+
+		checkResponseCode(t, http.StatusOK, res.StatusCode)
+
+		if link := res.Header.Get("Link"); !strings.Contains(link, `rel="next"`) {
+			t.Errorf("Expected a next link. Got %q", link)
+		}
+
+		var body struct {
+			Data []store.PostWithMetadata `json:"data"`
+		}
+		if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+
+		var ids []int64
+		for _, post := range body.Data {
+			ids = append(ids, post.ID)
+		}
+
+		// Post 3 is no longer visible, but the page still ends past it.
+		if want := []int64{5, 4}; !slices.Equal(ids, want) {
+			t.Errorf("Expected posts %v. Got %v", want, ids)
+		}
+
+		if posts.queried {
+			t.Error("Expected the feed not to be queried")
+		}
+	})
+
+	t.Run("should query the feed when the timeline runs out", func(t *testing.T) {
+		timelines.entries = nil
+		posts.queried = false
+
+		checkResponseCode(t, http.StatusOK, get("?limit=3").StatusCode)
+
+		if !posts.queried {
+			t.Error("Expected the feed to be queried")
+		}
+
+		select {
+		case event := <-app.timelineEvents:
+			if event.kind != timelineRebuild {
+				t.Errorf("Expected a rebuild. Got %q", event.kind)
+			}
+		default:
+			t.Error("Expected the empty timeline to be rebuilt")
+		}
+	})
+
+	t.Run("should query filtered feeds", func(t *testing.T) {
+		posts.queried = false
+
+		checkResponseCode(t, http.StatusOK, get("?tags=go").StatusCode)
+
+		if !posts.queried {
+			t.Error("Expected the feed to be queried")
+		}
+	})
+}
+
+func TestFanOut(t *testing.T) {
+	tests := []struct {
+		name      string
+		followers int
+		want      int
+	}{
+		{"should write to every follower in batches", 5, 6},
+		{"should skip the followers of large accounts", 100, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t)
+			app.config.timeline = timelineConfig{enabled: true, fanoutLimit: 100, batchSize: 2}
+
+			timelines := &recordingTimelineStore{added: map[int64][]int64{}}
+			app.store.Timelines = timelines
+			app.store.Posts = &timelinePostStore{authorID: 42}
+			app.store.Followers = &timelineFollowerStore{followers: tt.followers}
+
+			if err := app.fanOut(context.Background(), 7); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(timelines.added) != tt.want {
+				t.Errorf("Expected %d timelines. Got %d", tt.want, len(timelines.added))
+			}
+
+			if !slices.Equal(timelines.added[42], []int64{7}) {
+				t.Errorf("Expected the post in the timeline of its author. Got %v", timelines.added[42])
+			}
+		})
+	}
+}
+
+type recordingBroker struct {
+	*events.MemoryBroker
+	userIDs []int64
+}
+
+func (b *recordingBroker) Publish(_ context.Context, batch ...events.Event) error {
+	for _, event := range batch {
+		b.userIDs = append(b.userIDs, event.UserID)
+	}
+	return nil
+}
+
+// visiblePost returns a reviewed post of user 42 that mentions user 3.
+func visiblePost(visibility string) store.Post {
+	return store.Post{
+		UserID:       42,
+		Status:       store.PostStatusPublished,
+		Visibility:   visibility,
+		ReviewStatus: store.ReviewApproved,
+		Entities:     []store.Entity{{Type: store.EntityMention, UserID: 3}},
+	}
+}
+
+func TestFanOutEvents(t *testing.T) {
+	tests := []struct {
+		visibility string
+		want       []int64
+	}{
+		{store.PostVisibilityPublic, []int64{1, 2, 3, 4, 5}},
+		{store.PostVisibilityFollowers, []int64{1, 2, 3, 4, 5}},
+		{store.PostVisibilityMentioned, []int64{3}},
+		{store.PostVisibilityPrivate, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run("should tell followers about "+tt.visibility+" posts they may read", func(t *testing.T) {
+			app := newTestApplication(t)
+			app.config.timeline = timelineConfig{enabled: true, fanoutLimit: 100, batchSize: 2}
+			app.config.events.enabled = true
+
+			broker := &recordingBroker{MemoryBroker: events.NewMemoryBroker(4, 10)}
+			app.events = broker
+			app.store.Timelines = &recordingTimelineStore{added: map[int64][]int64{}}
+			app.store.Posts = store.NewMockPostStore(visiblePost(tt.visibility))
+			app.store.Followers = &timelineFollowerStore{followers: 5}
+
+			if err := app.fanOut(context.Background(), 7); err != nil {
+				t.Fatal(err)
+			}
+
+			if !slices.Equal(broker.userIDs, tt.want) {
+				t.Errorf("Expected events for %v. Got %v", tt.want, broker.userIDs)
+			}
+		})
+	}
+}
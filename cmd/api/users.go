@@ -51,6 +51,97 @@ func (app *application) getUserHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// GetUserPosts godoc
+//
+//	@Summary		Lists a user's posts
+//	@Description	Lists the published posts of a user that the caller may see, with cursor pagination. The next and previous pages are also linked from the Link header. The first page starts with the user's pinned posts. The offset and mode parameters of the feed are rejected.
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@param			userID	path		int			true	"User ID"
+//	@Param			limit	query		int			false	"Number of posts to retrieve (1-20)"	default(20)
+//	@Param			cursor	query		string		false	"Cursor returned as next_cursor or prev_cursor by another page"
+//	@Param			sort	query		string		false	"Sort order (asc or desc)"	default(desc)	Enums(asc, desc)
+//	@Param			tags	query		[]string	false	"Filter by up to 5 tags"
+//	@Param			search	query		string		false	"Search query (max 100 chars)"
+//	@Param			since	query		string		false	"Start date (YYYY-MM-DD)"
+//	@Param			until	query		string		false	"End date (YYYY-MM-DD)"
+//	@Success		200		{array}		store.PostWithMetadata
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/{userID}/posts [get]
+func (app *application) getUserPostsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil || userID < 1 {
+		app.badRequestResponse(w, r, errors.New("invalid user id"))
+		return
+	}
+
+	// The feed query also parses an offset and a ranking mode, which this
+	// listing does not support.
+	if query := r.URL.Query(); query.Has("offset") || query.Has("mode") {
+		app.badRequestResponse(w, r, errors.New("offset and mode are not supported, use cursor"))
+		return
+	}
+
+	p := &store.PaginatedFeedQuery{
+		Limit: 20,
+		Sort:  "desc",
+	}
+
+	p, err = p.Parse(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(p); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	author, err := app.getUser(r.Context(), userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	viewer := getUserFromCtx(r)
+
+	posts, err := app.store.Posts.GetByUserID(r.Context(), author.ID, viewer.ID, p)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	next, prev := pageCursors(p, posts)
+
+	if p.Cursor == nil {
+		pinned, err := app.store.Posts.GetPinnedByUserID(r.Context(), author.ID, viewer.ID, p)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		posts = append(pinned, posts...)
+	}
+
+	app.renderPosts(posts)
+	collapsePosts(posts, viewer)
+	app.recordImpressions(viewer, posts)
+
+	if err := app.paginatedJSONResponse(w, r, http.StatusOK, posts, next, prev); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
 // FollowUser godoc
 //
 //	@Summary		Follows a user
@@ -83,6 +174,9 @@ func (app *application) followUserHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	app.queueTimeline(timelineEvent{kind: timelineFollow, userID: user.ID, authorID: followedID})
+	app.publishEvent(r.Context(), eventFollow, followEvent{FollowerID: user.ID}, followedID)
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -113,6 +207,8 @@ func (app *application) unfollowUserHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	app.queueTimeline(timelineEvent{kind: timelineUnfollow, userID: user.ID, authorID: unfollowedID})
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
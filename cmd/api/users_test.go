@@ -2,6 +2,7 @@ package main
 
 import (
 	"net/http"
+	"strings"
 	"testing"
 )
 
@@ -9,11 +10,6 @@ func TestGetUser(t *testing.T) {
 	app := newTestApplication(t)
 	mux := app.mount()
 
-	testToken, err := app.authenticator.GenerateToken(nil)
-	if err != nil {
-		t.Fatal(err)
-	}
-
 	t.Run("should not allow unauthenticated requests", func(t *testing.T) {
 		req, err := http.NewRequest(http.MethodGet, "/v1/users/1", nil)
 		if err != nil {
@@ -26,15 +22,57 @@ func TestGetUser(t *testing.T) {
 	})
 
 	t.Run("should allow uauthenticated requests", func(t *testing.T) {
-		req, err := http.NewRequest(http.MethodGet, "/v1/users/1", nil)
-		if err != nil {
-			t.Fatal(err)
+		req := newAuthenticatedRequest(t, app, http.MethodGet, "/v1/users/1", "")
+
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		if strings.Contains(rr.Body.String(), "preferences") {
+			t.Errorf("Expected the preferences to be left out. Got %s", rr.Body)
+		}
+	})
+
+	t.Run("should return the preferences to the user", func(t *testing.T) {
+		req := newAuthenticatedRequest(t, app, http.MethodGet, "/v1/users/me/preferences", "")
+
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		if !strings.Contains(rr.Body.String(), "sensitive_content") {
+			t.Errorf("Expected the preferences. Got %s", rr.Body)
 		}
+	})
+}
 
-		req.Header.Set("Authorization", "Bearer " + testToken)
+func TestGetUserPosts(t *testing.T) {
+	app := newTestApplication(t)
+	mux := app.mount()
+
+	t.Run("should list a user's posts", func(t *testing.T) {
+		req := newAuthenticatedRequest(t, app, http.MethodGet, "/v1/users/1/posts?tags=go&sort=asc", "")
 
 		rr := executeRequest(req, mux)
 
 		checkResponseCode(t, http.StatusOK, rr.Code)
 	})
+
+	t.Run("should reject a malformed cursor", func(t *testing.T) {
+		req := newAuthenticatedRequest(t, app, http.MethodGet, "/v1/users/1/posts?cursor=not-a-cursor", "")
+
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+	})
+
+	for _, query := range []string{"offset=20", "mode=ranked"} {
+		t.Run("should reject "+query, func(t *testing.T) {
+			req := newAuthenticatedRequest(t, app, http.MethodGet, "/v1/users/1/posts?"+query, "")
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(t, http.StatusBadRequest, rr.Code)
+		})
+	}
 }
@@ -0,0 +1,16 @@
+DROP TABLE IF EXISTS mentions;
+
+DROP TABLE IF EXISTS post_tags;
+
+UPDATE posts
+SET tags = l.tags
+FROM legacy_post_tags l
+WHERE l.post_id = posts.id;
+
+DROP TABLE IF EXISTS legacy_post_tags;
+
+ALTER TABLE comments
+DROP COLUMN entities;
+
+ALTER TABLE posts
+DROP COLUMN entities;
@@ -0,0 +1,62 @@
+ALTER TABLE posts
+ADD COLUMN entities jsonb NOT NULL DEFAULT '[]';
+
+ALTER TABLE comments
+ADD COLUMN entities jsonb NOT NULL DEFAULT '[]';
+
+CREATE TABLE IF NOT EXISTS post_tags (
+    post_id bigint NOT NULL,
+    tag VARCHAR(100) NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (post_id, tag),
+    FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_tags_tag ON post_tags (tag, created_at);
+
+-- The tags are kept as they were so that the down migration can put them
+-- back.
+CREATE TABLE IF NOT EXISTS legacy_post_tags (
+    post_id bigint PRIMARY KEY,
+    tags VARCHAR(100) [] NOT NULL,
+
+    FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE
+);
+
+INSERT INTO legacy_post_tags (post_id, tags)
+SELECT id, tags FROM posts
+WHERE tags IS NOT NULL;
+
+-- Tags are normalized like store.NormalizeTag does: trimmed, without a
+-- leading '#' and lower-cased. Tags that are still not 1-50 letters, digits
+-- and underscores are dropped.
+UPDATE posts
+SET tags = ARRAY(
+    SELECT DISTINCT t.tag
+    FROM (SELECT lower(regexp_replace(btrim(raw), '^#', '')) AS tag FROM unnest(tags) raw) t
+    WHERE t.tag ~ '^[[:alnum:]_]{1,50}$'
+)
+WHERE tags IS NOT NULL;
+
+INSERT INTO post_tags (post_id, tag, created_at)
+SELECT id, unnest(tags), created_at FROM posts
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS mentions (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL,
+    author_id bigint NOT NULL,
+    post_id bigint NOT NULL,
+    comment_id bigint,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (author_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE,
+    FOREIGN KEY (comment_id) REFERENCES comments (id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_mentions_edge ON mentions (post_id, COALESCE(comment_id, 0), user_id);
+
+CREATE INDEX IF NOT EXISTS idx_mentions_user_id ON mentions (user_id);
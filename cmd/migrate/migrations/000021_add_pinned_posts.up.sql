@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS pinned_posts (
+    user_id bigint NOT NULL,
+    post_id bigint NOT NULL,
+    position int NOT NULL,
+
+    PRIMARY KEY (user_id, post_id),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_pinned_posts_post_id ON pinned_posts (post_id);
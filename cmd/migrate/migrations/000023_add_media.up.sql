@@ -0,0 +1,26 @@
+-- Uploads start out unattached (post_id NULL) and are attached when a post
+-- referencing them is saved. Uploads that stay unattached are cleaned up by
+-- a background job.
+CREATE TABLE IF NOT EXISTS media (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL,
+    post_id bigint,
+    position int,
+    key text NOT NULL UNIQUE,
+    thumbnail_key text NOT NULL,
+    url text NOT NULL,
+    thumbnail_url text NOT NULL,
+    content_type VARCHAR(50) NOT NULL,
+    width int NOT NULL,
+    height int NOT NULL,
+    size bigint NOT NULL,
+    alt_text VARCHAR(1000) NOT NULL DEFAULT '',
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_media_post_id ON media (post_id, position);
+
+CREATE INDEX IF NOT EXISTS idx_media_orphans ON media (created_at) WHERE post_id IS NULL;
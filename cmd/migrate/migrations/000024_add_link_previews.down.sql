@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS link_previews;
+
+DROP INDEX IF EXISTS idx_posts_link_url;
+
+ALTER TABLE posts DROP COLUMN IF EXISTS link_url;
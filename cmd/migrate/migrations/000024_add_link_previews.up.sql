@@ -0,0 +1,16 @@
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS link_url text;
+
+CREATE INDEX IF NOT EXISTS idx_posts_link_url ON posts (link_url) WHERE link_url IS NOT NULL;
+
+-- Previews are cached by URL and shared by every post linking to it. Failed
+-- fetches are kept too so a broken link is not fetched again on every sweep.
+CREATE TABLE IF NOT EXISTS link_previews (
+    url text PRIMARY KEY,
+    status VARCHAR(10) NOT NULL CHECK (status IN ('ok', 'failed')),
+    title text NOT NULL DEFAULT '',
+    description text NOT NULL DEFAULT '',
+    image_url text NOT NULL DEFAULT '',
+    site_name text NOT NULL DEFAULT '',
+    attempts int NOT NULL DEFAULT 1,
+    fetched_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
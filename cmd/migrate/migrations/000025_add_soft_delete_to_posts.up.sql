@@ -0,0 +1,5 @@
+ALTER TABLE posts
+    ADD COLUMN IF NOT EXISTS deleted_at timestamp(0) with time zone,
+    ADD COLUMN IF NOT EXISTS deleted_by bigint REFERENCES users (id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_posts_deleted_at ON posts (deleted_at) WHERE deleted_at IS NOT NULL;
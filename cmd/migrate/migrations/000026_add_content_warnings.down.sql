@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS moderation_log;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS sensitive_content;
+
+ALTER TABLE posts
+    DROP COLUMN IF EXISTS sensitive,
+    DROP COLUMN IF EXISTS content_warning;
@@ -0,0 +1,28 @@
+ALTER TABLE posts
+    ADD COLUMN IF NOT EXISTS content_warning VARCHAR(200) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS sensitive BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Whether posts with a content warning or sensitive flag are shown expanded
+-- or hidden behind their warning.
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS sensitive_content VARCHAR(10) NOT NULL DEFAULT 'hide'
+        CHECK (sensitive_content IN ('hide', 'expand'));
+
+-- Actions moderators take on other users' content. Entries outlive the
+-- moderator, the author and the post they refer to.
+CREATE TABLE IF NOT EXISTS moderation_log (
+    id bigserial PRIMARY KEY,
+    moderator_id bigint,
+    action VARCHAR(50) NOT NULL,
+    target_user_id bigint,
+    post_id bigint,
+    reason VARCHAR(500) NOT NULL DEFAULT '',
+    details jsonb NOT NULL DEFAULT '{}',
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+    FOREIGN KEY (moderator_id) REFERENCES users (id) ON DELETE SET NULL,
+    FOREIGN KEY (target_user_id) REFERENCES users (id) ON DELETE SET NULL,
+    FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_moderation_log_post_id ON moderation_log (post_id);
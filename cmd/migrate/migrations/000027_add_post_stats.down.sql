@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS post_stats_hourly;
+
+DROP TABLE IF EXISTS post_view_dedup;
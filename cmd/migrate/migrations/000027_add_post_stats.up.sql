@@ -0,0 +1,24 @@
+-- When each user was last counted as having seen a post, so repeated
+-- impressions and views within the de-duplication window count once.
+CREATE TABLE IF NOT EXISTS post_view_dedup (
+    post_id bigint NOT NULL,
+    user_id bigint NOT NULL,
+    kind VARCHAR(20) NOT NULL,
+    seen_at timestamp(0) with time zone NOT NULL,
+
+    PRIMARY KEY (post_id, user_id, kind),
+    FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_view_dedup_seen_at ON post_view_dedup (seen_at);
+
+CREATE TABLE IF NOT EXISTS post_stats_hourly (
+    post_id bigint NOT NULL,
+    hour timestamp(0) with time zone NOT NULL,
+    impressions bigint NOT NULL DEFAULT 0,
+    views bigint NOT NULL DEFAULT 0,
+
+    PRIMARY KEY (post_id, hour),
+    FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE
+);
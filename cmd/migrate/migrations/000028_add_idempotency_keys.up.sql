@@ -0,0 +1,17 @@
+-- Responses to mutating requests sent with an Idempotency-Key header, so
+-- retries get the original response instead of repeating the request. A
+-- NULL status_code marks a request that is still being processed.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    user_id bigint NOT NULL,
+    key VARCHAR(255) NOT NULL,
+    fingerprint VARCHAR(64) NOT NULL,
+    status_code int,
+    header jsonb NOT NULL DEFAULT '{}',
+    body bytea,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (user_id, key),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_created_at ON idempotency_keys (created_at);
@@ -0,0 +1,8 @@
+-- Top-level comments have no parent and a depth of 0. reply_count counts
+-- direct replies only.
+ALTER TABLE comments
+    ADD COLUMN IF NOT EXISTS parent_id bigint REFERENCES comments (id) ON DELETE CASCADE,
+    ADD COLUMN IF NOT EXISTS depth int NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS reply_count int NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_comments_parent_id ON comments (parent_id);
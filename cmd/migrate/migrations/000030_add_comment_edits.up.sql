@@ -0,0 +1,3 @@
+ALTER TABLE comments
+    ADD COLUMN IF NOT EXISTS edited_at timestamp(0) with time zone,
+    ADD COLUMN IF NOT EXISTS version int NOT NULL DEFAULT 0;
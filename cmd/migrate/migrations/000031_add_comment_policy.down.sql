@@ -0,0 +1,3 @@
+ALTER TABLE posts
+    DROP COLUMN IF EXISTS comments_locked,
+    DROP COLUMN IF EXISTS comment_policy;
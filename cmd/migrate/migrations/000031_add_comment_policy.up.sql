@@ -0,0 +1,4 @@
+ALTER TABLE posts
+    ADD COLUMN IF NOT EXISTS comment_policy VARCHAR(20) NOT NULL DEFAULT 'everyone'
+        CHECK (comment_policy IN ('everyone', 'followers', 'mentioned', 'nobody')),
+    ADD COLUMN IF NOT EXISTS comments_locked BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,12 @@
+DROP INDEX IF EXISTS idx_posts_user_id_created_at;
+DROP INDEX IF EXISTS idx_comments_user_id_created_at;
+DROP INDEX IF EXISTS idx_comments_review_status;
+DROP INDEX IF EXISTS idx_posts_review_status;
+
+ALTER TABLE comments
+    DROP COLUMN IF EXISTS spam_reasons,
+    DROP COLUMN IF EXISTS review_status;
+
+ALTER TABLE posts
+    DROP COLUMN IF EXISTS spam_reasons,
+    DROP COLUMN IF EXISTS review_status;
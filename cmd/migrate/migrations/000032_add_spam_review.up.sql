@@ -0,0 +1,17 @@
+-- Content flagged by the spam filter is either held until a moderator
+-- reviews it or hidden from everyone but its author.
+ALTER TABLE posts
+    ADD COLUMN IF NOT EXISTS review_status VARCHAR(10) NOT NULL DEFAULT 'approved'
+        CHECK (review_status IN ('approved', 'held', 'hidden')),
+    ADD COLUMN IF NOT EXISTS spam_reasons text[] NOT NULL DEFAULT '{}';
+
+ALTER TABLE comments
+    ADD COLUMN IF NOT EXISTS review_status VARCHAR(10) NOT NULL DEFAULT 'approved'
+        CHECK (review_status IN ('approved', 'held', 'hidden')),
+    ADD COLUMN IF NOT EXISTS spam_reasons text[] NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS idx_posts_review_status ON posts (created_at) WHERE review_status <> 'approved';
+CREATE INDEX IF NOT EXISTS idx_comments_review_status ON comments (created_at) WHERE review_status <> 'approved';
+
+CREATE INDEX IF NOT EXISTS idx_comments_user_id_created_at ON comments (user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_posts_user_id_created_at ON posts (user_id, created_at);
@@ -0,0 +1,5 @@
+ALTER TABLE users DROP COLUMN IF EXISTS followers_count;
+
+DROP INDEX IF EXISTS idx_followers_follower_id;
+
+DROP TABLE IF EXISTS timeline_entries;
@@ -0,0 +1,21 @@
+-- Home timelines hold the IDs of the latest posts of the accounts a user
+-- follows, written as the posts are published.
+CREATE TABLE IF NOT EXISTS timeline_entries (
+    user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    post_id bigint NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+    created_at timestamp(0) with time zone NOT NULL,
+
+    PRIMARY KEY (user_id, post_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_timeline_entries_user_id_created_at ON timeline_entries (user_id, created_at DESC, post_id DESC);
+CREATE INDEX IF NOT EXISTS idx_timeline_entries_post_id ON timeline_entries (post_id);
+
+-- Posts are fanned out to the followers of an account, and accounts with
+-- too many of them are merged into timelines as they are read.
+CREATE INDEX IF NOT EXISTS idx_followers_follower_id ON followers (follower_id, user_id);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS followers_count bigint NOT NULL DEFAULT 0;
+
+UPDATE users u
+SET followers_count = (SELECT count(*) FROM followers f WHERE f.follower_id = u.id);
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_poll_voters_user_id_created_at;
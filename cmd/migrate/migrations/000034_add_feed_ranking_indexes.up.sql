@@ -0,0 +1,2 @@
+-- The ranked feed counts the poll votes of the viewer.
+CREATE INDEX IF NOT EXISTS idx_poll_voters_user_id_created_at ON poll_voters (user_id, created_at);
@@ -0,0 +1,3 @@
+-- Files are looked up by their key or their thumbnail's key to check who
+-- may see them.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_media_thumbnail_key ON media (thumbnail_key);
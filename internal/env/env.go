@@ -47,3 +47,17 @@ func GetBoolEnv(key string, fallback bool) bool {
 
 	return boolVal
 }
+
+func GetFloatEnv(key string, fallback float64) float64 {
+	val, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+
+	floatVal, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		return fallback
+	}
+
+	return floatVal
+}
@@ -0,0 +1,235 @@
+// Package events delivers real-time events to the users they concern. A
+// Broker carries published events to the subscriptions of their user,
+// possibly on another API instance, and keeps a short history of them so
+// that a client that reconnects can resume where it left off. Events can
+// also be broadcast to everyone that joined a topic, without history.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync"
+)
+
+var (
+	// ErrInvalidEventID is returned when a subscription resumes after an ID
+	// the broker did not issue.
+	ErrInvalidEventID = errors.New("invalid event ID")
+	// ErrLagged is returned by Subscription.Err when the subscription was
+	// closed because it fell too far behind.
+	ErrLagged = errors.New("subscription fell behind")
+)
+
+// Event is something that happened to UserID. ID is assigned by the broker
+// when the event is published and orders the events of a user. Events
+// broadcast to a Topic are something UserID did instead, and have no ID.
+type Event struct {
+	ID     string          `json:"id"`
+	UserID int64           `json:"user_id"`
+	Topic  string          `json:"topic,omitempty"`
+	Type   string          `json:"type"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// New returns an event of type kind for userID carrying data as JSON.
+func New(userID int64, kind string, data any) (Event, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, err
+	}
+
+	return Event{UserID: userID, Type: kind, Data: raw}, nil
+}
+
+type Broker interface {
+	// Publish delivers events to the subscriptions of their users.
+	Publish(ctx context.Context, events ...Event) error
+	// Subscribe returns a subscription to the events of userID. When
+	// lastEventID is set, the events after it that are still in the
+	// history are delivered first.
+	Subscribe(ctx context.Context, userID int64, lastEventID string) (*Subscription, error)
+	// Broadcast delivers events to the current subscribers of their
+	// topics.
+	Broadcast(ctx context.Context, events ...Event) error
+	// Join returns a subscription to the events broadcast to topic.
+	Join(ctx context.Context, topic string) *Subscription
+	// Run carries the events published elsewhere to the subscriptions of
+	// this broker until ctx is done.
+	Run(ctx context.Context) error
+}
+
+// Subscription receives the events of a user. Events are buffered up to
+// the size the broker was created with; a subscription whose buffer is
+// full is closed with ErrLagged rather than slowing down the publisher,
+// and can be resumed from the last event it received.
+type Subscription struct {
+	events chan Event
+
+	in  chan Event
+	hub *hub
+	key string
+
+	mu     sync.Mutex
+	err    error
+	closed bool
+}
+
+// Events returns the channel the events are received from. It is closed
+// when the subscription ends.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Err returns ErrLagged once the subscription was closed for falling
+// behind, and nil otherwise.
+func (s *Subscription) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.err
+}
+
+// Close ends the subscription.
+func (s *Subscription) Close() {
+	s.hub.remove(s, nil)
+}
+
+// replay delivers missed and then the events from the hub, leaving out the
+// ones that were already among missed, until the subscription is closed
+// or ctx is done.
+func (s *Subscription) replay(ctx context.Context, missed []Event) {
+	defer close(s.events)
+	defer s.Close()
+
+	seen := make(map[string]bool, len(missed))
+	for _, event := range missed {
+		seen[event.ID] = true
+
+		select {
+		case s.events <- event:
+		case <-ctx.Done():
+			return
+		}
+	}
+
+	for {
+		select {
+		case event, ok := <-s.in:
+			if !ok {
+				return
+			}
+
+			if seen[event.ID] {
+				continue
+			}
+
+			select {
+			case s.events <- event:
+			case <-ctx.Done():
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// hub holds the subscriptions of one broker by user or topic.
+type hub struct {
+	mu            sync.Mutex
+	subscriptions map[string]map[*Subscription]bool
+	bufferSize    int
+}
+
+func newHub(bufferSize int) *hub {
+	return &hub{subscriptions: make(map[string]map[*Subscription]bool), bufferSize: bufferSize}
+}
+
+func userKey(userID int64) string {
+	return "user/" + strconv.FormatInt(userID, 10)
+}
+
+func topicKey(topic string) string {
+	return "topic/" + topic
+}
+
+// eventKey returns the key of the subscriptions event is delivered to.
+func eventKey(event Event) string {
+	if event.Topic != "" {
+		return topicKey(event.Topic)
+	}
+	return userKey(event.UserID)
+}
+
+// subscribe registers a subscription under key. The events delivered from
+// now on are buffered until the subscription is replayed.
+func (h *hub) subscribe(key string) *Subscription {
+	s := &Subscription{
+		events: make(chan Event),
+		in:     make(chan Event, h.bufferSize),
+		hub:    h,
+		key:    key,
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.subscriptions[key] == nil {
+		h.subscriptions[key] = make(map[*Subscription]bool)
+	}
+	h.subscriptions[key][s] = true
+
+	return s
+}
+
+// join subscribes to topic and starts delivering its events.
+func (h *hub) join(ctx context.Context, topic string) *Subscription {
+	s := h.subscribe(topicKey(topic))
+	go s.replay(ctx, nil)
+
+	return s
+}
+
+// deliver hands event to the subscriptions of its user or topic without
+// blocking.
+func (h *hub) deliver(event Event) {
+	h.mu.Lock()
+	var lagging []*Subscription
+	for s := range h.subscriptions[eventKey(event)] {
+		select {
+		case s.in <- event:
+		default:
+			lagging = append(lagging, s)
+		}
+	}
+	h.mu.Unlock()
+
+	for _, s := range lagging {
+		h.remove(s, ErrLagged)
+	}
+}
+
+// remove unregisters s and closes it with err. Removing it again is a
+// no-op.
+func (h *hub) remove(s *Subscription, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+	s.closed = true
+	s.err = err
+
+	delete(h.subscriptions[s.key], s)
+	if len(h.subscriptions[s.key]) == 0 {
+		delete(h.subscriptions, s.key)
+	}
+
+	close(s.in)
+}
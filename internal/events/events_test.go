@@ -0,0 +1,177 @@
+package events
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func receive(t *testing.T, s *Subscription) Event {
+	t.Helper()
+
+	select {
+	case event, ok := <-s.Events():
+		if !ok {
+			t.Fatal("Expected an event. The subscription was closed")
+		}
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("Expected an event. Got none")
+	}
+
+	return Event{}
+}
+
+func publish(t *testing.T, b Broker, userID int64, kind string) {
+	t.Helper()
+
+	event, err := New(userID, kind, map[string]int64{"user_id": userID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.Publish(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMemoryBroker(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	t.Run("should deliver events to the subscriptions of their user", func(t *testing.T) {
+		b := NewMemoryBroker(4, 10)
+
+		s, err := b.Subscribe(ctx, 1, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+
+		publish(t, b, 2, "follow")
+		publish(t, b, 1, "comment")
+
+		if event := receive(t, s); event.Type != "comment" || event.UserID != 1 {
+			t.Errorf("Expected the comment of user 1. Got %+v", event)
+		}
+	})
+
+	t.Run("should resume after the last event", func(t *testing.T) {
+		b := NewMemoryBroker(4, 10)
+
+		publish(t, b, 1, "post")
+		publish(t, b, 1, "comment")
+		publish(t, b, 1, "follow")
+
+		s, err := b.Subscribe(ctx, 1, "1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+
+		publish(t, b, 1, "post")
+
+		for _, want := range []string{"2", "3", "4"} {
+			if event := receive(t, s); event.ID != want {
+				t.Errorf("Expected event %s. Got %s", want, event.ID)
+			}
+		}
+	})
+
+	t.Run("should broadcast events to the subscriptions of their topic", func(t *testing.T) {
+		b := NewMemoryBroker(4, 10)
+
+		s := b.Join(ctx, "post:1")
+		defer s.Close()
+
+		user, err := b.Subscribe(ctx, 1, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer user.Close()
+
+		for _, topic := range []string{"post:2", "post:1"} {
+			event, err := New(1, "typing", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			event.Topic = topic
+
+			if err := b.Broadcast(ctx, event); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if event := receive(t, s); event.Topic != "post:1" || event.ID != "" {
+			t.Errorf("Expected the event of post:1 without an ID. Got %+v", event)
+		}
+
+		select {
+		case event := <-user.Events():
+			t.Errorf("Expected the events of topics not to reach users. Got %+v", event)
+		default:
+		}
+	})
+
+	t.Run("should reject an unknown event ID", func(t *testing.T) {
+		if _, err := NewMemoryBroker(4, 10).Subscribe(ctx, 1, "1700000000000-0"); err != ErrInvalidEventID {
+			t.Errorf("Expected ErrInvalidEventID. Got %v", err)
+		}
+	})
+
+	t.Run("should close subscriptions that fall behind", func(t *testing.T) {
+		b := NewMemoryBroker(1, 10)
+
+		s, err := b.Subscribe(ctx, 1, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Nothing reads the subscription, so its buffer fills up.
+		for range 3 {
+			publish(t, b, 1, "post")
+		}
+
+		deadline := time.After(time.Second)
+		for {
+			select {
+			case _, ok := <-s.Events():
+				if !ok {
+					if s.Err() != ErrLagged {
+						t.Errorf("Expected ErrLagged. Got %v", s.Err())
+					}
+					return
+				}
+			case <-deadline:
+				t.Fatal("Expected the subscription to be closed")
+			}
+		}
+	})
+}
+
+func TestMemoryPresence(t *testing.T) {
+	ctx := context.Background()
+	p := NewMemoryPresence()
+
+	if err := p.Touch(ctx, 1, "a", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Touch(ctx, 1, "b", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Touch(ctx, 2, "c", -time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	if online, _ := p.Leave(ctx, 1, "a"); !online {
+		t.Error("Expected user 1 to be online through another connection")
+	}
+
+	if online, _ := p.Leave(ctx, 1, "b"); online {
+		t.Error("Expected user 1 to be offline")
+	}
+
+	if online, _ := p.Online(ctx, 2); online {
+		t.Error("Expected the expired connection of user 2 not to count")
+	}
+}
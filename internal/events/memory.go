@@ -0,0 +1,93 @@
+package events
+
+import (
+	"context"
+	"strconv"
+	"sync"
+)
+
+// MemoryBroker delivers events to the subscriptions of a single process.
+// Event IDs count up from 1 across all users.
+type MemoryBroker struct {
+	hub         *hub
+	historySize int
+
+	mu      sync.Mutex
+	lastID  int64
+	history map[int64][]Event
+}
+
+// NewMemoryBroker returns a broker that buffers bufferSize events per
+// subscription and keeps the last historySize events of every user.
+func NewMemoryBroker(bufferSize, historySize int) *MemoryBroker {
+	return &MemoryBroker{
+		hub:         newHub(bufferSize),
+		historySize: historySize,
+		history:     make(map[int64][]Event),
+	}
+}
+
+func (b *MemoryBroker) Publish(_ context.Context, events ...Event) error {
+	for _, event := range events {
+		b.mu.Lock()
+		b.lastID++
+		event.ID = strconv.FormatInt(b.lastID, 10)
+
+		history := append(b.history[event.UserID], event)
+		if len(history) > b.historySize {
+			history = history[len(history)-b.historySize:]
+		}
+		b.history[event.UserID] = history
+		b.mu.Unlock()
+
+		b.hub.deliver(event)
+	}
+
+	return nil
+}
+
+func (b *MemoryBroker) Subscribe(ctx context.Context, userID int64, lastEventID string) (*Subscription, error) {
+	var after int64
+	if lastEventID != "" {
+		id, err := strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil {
+			return nil, ErrInvalidEventID
+		}
+		after = id
+	}
+
+	s := b.hub.subscribe(userKey(userID))
+
+	var missed []Event
+	if lastEventID != "" {
+		b.mu.Lock()
+		for _, event := range b.history[userID] {
+			if id, _ := strconv.ParseInt(event.ID, 10, 64); id > after {
+				missed = append(missed, event)
+			}
+		}
+		b.mu.Unlock()
+	}
+
+	go s.replay(ctx, missed)
+
+	return s, nil
+}
+
+func (b *MemoryBroker) Broadcast(_ context.Context, events ...Event) error {
+	for _, event := range events {
+		b.hub.deliver(event)
+	}
+
+	return nil
+}
+
+func (b *MemoryBroker) Join(ctx context.Context, topic string) *Subscription {
+	return b.hub.join(ctx, topic)
+}
+
+// Run waits for ctx: every event is published in this process.
+func (b *MemoryBroker) Run(ctx context.Context) error {
+	<-ctx.Done()
+	return nil
+}
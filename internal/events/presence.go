@@ -0,0 +1,119 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Presence tracks which users are connected. A user is online while any of
+// their connections was touched within the TTL it was touched with, so
+// connections of instances that died expire on their own.
+type Presence interface {
+	// Touch marks connID of userID as connected for ttl.
+	Touch(ctx context.Context, userID int64, connID string, ttl time.Duration) error
+	// Leave marks connID of userID as disconnected and reports whether
+	// userID is still online through another connection.
+	Leave(ctx context.Context, userID int64, connID string) (bool, error)
+	Online(ctx context.Context, userID int64) (bool, error)
+}
+
+// MemoryPresence tracks the connections of a single process.
+type MemoryPresence struct {
+	mu          sync.Mutex
+	connections map[int64]map[string]time.Time
+}
+
+func NewMemoryPresence() *MemoryPresence {
+	return &MemoryPresence{connections: make(map[int64]map[string]time.Time)}
+}
+
+func (p *MemoryPresence) Touch(_ context.Context, userID int64, connID string, ttl time.Duration) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.connections[userID] == nil {
+		p.connections[userID] = make(map[string]time.Time)
+	}
+	p.connections[userID][connID] = time.Now().Add(ttl)
+
+	return nil
+}
+
+func (p *MemoryPresence) Leave(ctx context.Context, userID int64, connID string) (bool, error) {
+	p.mu.Lock()
+	delete(p.connections[userID], connID)
+	p.mu.Unlock()
+
+	return p.Online(ctx, userID)
+}
+
+func (p *MemoryPresence) Online(_ context.Context, userID int64) (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	for connID, expiresAt := range p.connections[userID] {
+		if expiresAt.After(now) {
+			return true, nil
+		}
+		delete(p.connections[userID], connID)
+	}
+	delete(p.connections, userID)
+
+	return false, nil
+}
+
+// RedisPresence tracks the connections of every API instance in a Redis
+// hash per user, from connection ID to expiry.
+type RedisPresence struct {
+	rdb *redis.Client
+}
+
+func NewRedisPresence(rdb *redis.Client) *RedisPresence {
+	return &RedisPresence{rdb: rdb}
+}
+
+func presenceKey(userID int64) string {
+	return fmt.Sprintf("presence-%v", userID)
+}
+
+func (p *RedisPresence) Touch(ctx context.Context, userID int64, connID string, ttl time.Duration) error {
+	key := presenceKey(userID)
+	expiresAt := time.Now().Add(ttl).UnixMilli()
+
+	_, err := p.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, connID, expiresAt)
+		pipe.PExpire(ctx, key, ttl)
+		return nil
+	})
+	return err
+}
+
+func (p *RedisPresence) Leave(ctx context.Context, userID int64, connID string) (bool, error) {
+	if err := p.rdb.HDel(ctx, presenceKey(userID), connID).Err(); err != nil {
+		return false, err
+	}
+
+	return p.Online(ctx, userID)
+}
+
+func (p *RedisPresence) Online(ctx context.Context, userID int64) (bool, error) {
+	connections, err := p.rdb.HGetAll(ctx, presenceKey(userID)).Result()
+	if err != nil {
+		return false, err
+	}
+
+	now := time.Now().UnixMilli()
+	for _, value := range connections {
+		if expiresAt, err := strconv.ParseInt(value, 10, 64); err == nil && expiresAt > now {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,169 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisChannel is the pub/sub channel every API instance listens to for
+// the events to hand to its subscriptions.
+const redisChannel = "events"
+
+// RedisBroker delivers events across API instances over Redis pub/sub. The
+// history of every user is kept in a Redis stream, whose entry IDs are the
+// event IDs.
+type RedisBroker struct {
+	rdb         *redis.Client
+	hub         *hub
+	historySize int64
+	historyTTL  time.Duration
+}
+
+// NewRedisBroker returns a broker that buffers bufferSize events per
+// subscription and keeps about the last historySize events of every user
+// for historyTTL after the latest one.
+func NewRedisBroker(rdb *redis.Client, bufferSize, historySize int, historyTTL time.Duration) *RedisBroker {
+	return &RedisBroker{
+		rdb:         rdb,
+		hub:         newHub(bufferSize),
+		historySize: int64(historySize),
+		historyTTL:  historyTTL,
+	}
+}
+
+func streamKey(userID int64) string {
+	return fmt.Sprintf("events-%v", userID)
+}
+
+func (b *RedisBroker) Publish(ctx context.Context, events ...Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	adds := make([]*redis.StringCmd, len(events))
+	_, err := b.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, event := range events {
+			key := streamKey(event.UserID)
+
+			adds[i] = pipe.XAdd(ctx, &redis.XAddArgs{
+				Stream: key,
+				MaxLen: b.historySize,
+				Approx: true,
+				Values: map[string]any{"type": event.Type, "data": string(event.Data)},
+			})
+			pipe.Expire(ctx, key, b.historyTTL)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	events = slices.Clone(events)
+	for i := range events {
+		events[i].ID = adds[i].Val()
+	}
+
+	return b.Broadcast(ctx, events...)
+}
+
+// Broadcast sends events to every instance, which hands them to its
+// subscriptions. Publish sends the events of users the same way once they
+// are kept.
+func (b *RedisBroker) Broadcast(ctx context.Context, events ...Event) error {
+	_, err := b.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, event := range events {
+			message, err := json.Marshal(event)
+			if err != nil {
+				return err
+			}
+
+			pipe.Publish(ctx, redisChannel, message)
+		}
+		return nil
+	})
+	return err
+}
+
+func (b *RedisBroker) Join(ctx context.Context, topic string) *Subscription {
+	return b.hub.join(ctx, topic)
+}
+
+func (b *RedisBroker) Subscribe(ctx context.Context, userID int64, lastEventID string) (*Subscription, error) {
+	if lastEventID != "" && !validStreamID(lastEventID) {
+		return nil, ErrInvalidEventID
+	}
+
+	s := b.hub.subscribe(userKey(userID))
+
+	var missed []Event
+	if lastEventID != "" {
+		messages, err := b.rdb.XRange(ctx, streamKey(userID), "("+lastEventID, "+").Result()
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+
+		for _, message := range messages {
+			kind, _ := message.Values["type"].(string)
+			data, _ := message.Values["data"].(string)
+
+			missed = append(missed, Event{ID: message.ID, UserID: userID, Type: kind, Data: json.RawMessage(data)})
+		}
+	}
+
+	go s.replay(ctx, missed)
+
+	return s, nil
+}
+
+// Run hands the events published by every instance to the subscriptions
+// of this one. go-redis reconnects the subscription when it drops.
+func (b *RedisBroker) Run(ctx context.Context) error {
+	pubsub := b.rdb.Subscribe(ctx, redisChannel)
+	defer pubsub.Close()
+
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case message, ok := <-messages:
+			if !ok {
+				return nil
+			}
+
+			// Messages that are not events are ignored.
+			var event Event
+			if err := json.Unmarshal([]byte(message.Payload), &event); err != nil {
+				continue
+			}
+
+			b.hub.deliver(event)
+		}
+	}
+}
+
+// validStreamID reports whether id has the <milliseconds>-<sequence> form
+// of Redis stream IDs.
+func validStreamID(id string) bool {
+	ms, seq, ok := strings.Cut(id, "-")
+	if !ok {
+		return false
+	}
+
+	_, err := strconv.ParseUint(ms, 10, 64)
+	if err != nil {
+		return false
+	}
+
+	_, err = strconv.ParseUint(seq, 10, 64)
+	return err == nil
+}
@@ -0,0 +1,220 @@
+// Package markdown renders the restricted Markdown dialect of posts and
+// comments to sanitized HTML.
+//
+// The dialect covers paragraphs with hard line breaks, emphasis, strong,
+// strikethrough, code spans and blocks, block quotes, lists and links.
+// Headings, thematic breaks, images and raw HTML are not part of it: heading
+// and HTML syntax is shown as typed, and images are dropped by the
+// sanitizer. Mention, hashtag and URL entities become links.
+package markdown
+
+import (
+	"bytes"
+	"html"
+	"regexp"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/store"
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	gmhtml "github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// Links builds the targets of mention and hashtag links.
+type Links struct {
+	Mention func(store.Entity) string
+	Hashtag func(tag string) string
+}
+
+type Renderer struct {
+	md     goldmark.Markdown
+	policy *bluemonday.Policy
+}
+
+func New(links Links) *Renderer {
+	p := parser.NewParser(
+		parser.WithBlockParsers(
+			util.Prioritized(parser.NewListParser(), 300),
+			util.Prioritized(parser.NewListItemParser(), 400),
+			util.Prioritized(parser.NewCodeBlockParser(), 500),
+			util.Prioritized(parser.NewFencedCodeBlockParser(), 700),
+			util.Prioritized(parser.NewBlockquoteParser(), 800),
+			util.Prioritized(parser.NewParagraphParser(), 1000),
+		),
+		parser.WithInlineParsers(
+			util.Prioritized(parser.NewCodeSpanParser(), 100),
+			util.Prioritized(parser.NewLinkParser(), 200),
+			util.Prioritized(parser.NewAutoLinkParser(), 300),
+			util.Prioritized(parser.NewEmphasisParser(), 500),
+		),
+		parser.WithParagraphTransformers(parser.DefaultParagraphTransformers()...),
+		parser.WithASTTransformers(util.Prioritized(&entityLinker{links: links}, 100)),
+	)
+
+	md := goldmark.New(
+		goldmark.WithParser(p),
+		goldmark.WithExtensions(extension.Strikethrough),
+		goldmark.WithRendererOptions(gmhtml.WithHardWraps()),
+	)
+
+	policy := bluemonday.NewPolicy()
+	policy.AllowElements("p", "br", "em", "strong", "del", "code", "pre", "blockquote", "ul", "ol", "li")
+	policy.AllowAttrs("start").Matching(bluemonday.Integer).OnElements("ol")
+	policy.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w-]+$`)).OnElements("code")
+	policy.AllowAttrs("href").OnElements("a")
+	policy.AllowAttrs("class").Matching(regexp.MustCompile(`^(mention|hashtag|url)$`)).OnElements("a")
+	policy.AllowStandardURLs()
+	policy.AllowRelativeURLs(true)
+	policy.RequireNoFollowOnLinks(true)
+	policy.AddTargetBlankToFullyQualifiedLinks(true)
+
+	return &Renderer{md: md, policy: policy}
+}
+
+// Render returns the sanitized HTML of content. entities are the parsed
+// entities of content; mentions without a user ID are not linked.
+func (r *Renderer) Render(content string, entities []store.Entity) string {
+	ctx := parser.NewContext()
+	ctx.Set(entitiesKey, entities)
+
+	var buf bytes.Buffer
+	if err := r.md.Convert([]byte(content), &buf, parser.WithContext(ctx)); err != nil {
+		return "<p>" + html.EscapeString(content) + "</p>"
+	}
+
+	return r.policy.Sanitize(buf.String())
+}
+
+var entitiesKey = parser.NewContextKey()
+
+// entityLinker wraps the text of entities in links. Entities inside code,
+// links and images are left alone.
+type entityLinker struct {
+	links Links
+}
+
+func (l *entityLinker) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	entities, _ := pc.Get(entitiesKey).([]store.Entity)
+	if len(entities) == 0 {
+		return
+	}
+
+	source := reader.Source()
+	offsets := byteOffsets(source)
+
+	for _, entity := range entities {
+		if entity.Offset+entity.Length >= len(offsets) {
+			continue
+		}
+
+		href, class := l.target(entity)
+		if href == "" {
+			continue
+		}
+
+		start, stop := offsets[entity.Offset], offsets[entity.Offset+entity.Length]
+		linkText(doc, start, stop, href, class)
+	}
+}
+
+func (l *entityLinker) target(entity store.Entity) (string, string) {
+	switch entity.Type {
+	case store.EntityMention:
+		if entity.UserID == 0 || l.links.Mention == nil {
+			return "", ""
+		}
+		return l.links.Mention(entity), "mention"
+	case store.EntityHashtag:
+		if l.links.Hashtag == nil {
+			return "", ""
+		}
+		return l.links.Hashtag(entity.Text), "hashtag"
+	case store.EntityURL:
+		return entity.Text, "url"
+	default:
+		return "", ""
+	}
+}
+
+// linkText replaces the source range [start, stop) with a link when it is
+// covered by adjacent text nodes, which the parser produces when it splits
+// text at characters such as underscores.
+func linkText(doc *ast.Document, start, stop int, href, class string) {
+	var first *ast.Text
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+
+		switch n := n.(type) {
+		case *ast.CodeSpan, *ast.Link, *ast.AutoLink, *ast.Image:
+			return ast.WalkSkipChildren, nil
+		case *ast.Text:
+			if n.Segment.Start <= start && start < n.Segment.Stop {
+				first = n
+				return ast.WalkStop, nil
+			}
+		}
+
+		return ast.WalkContinue, nil
+	})
+
+	if first == nil {
+		return
+	}
+
+	run := []*ast.Text{first}
+	last := first
+	for last.Segment.Stop < stop {
+		next, ok := last.NextSibling().(*ast.Text)
+		if !ok || next.Segment.Start != last.Segment.Stop || last.SoftLineBreak() || last.HardLineBreak() {
+			return
+		}
+		run = append(run, next)
+		last = next
+	}
+
+	parent := first.Parent()
+
+	if first.Segment.Start < start {
+		parent.InsertBefore(parent, first, ast.NewTextSegment(text.NewSegment(first.Segment.Start, start)))
+	}
+
+	link := ast.NewLink()
+	link.Destination = []byte(href)
+	link.SetAttributeString("class", []byte(class))
+	link.AppendChild(link, ast.NewTextSegment(text.NewSegment(start, stop)))
+	parent.InsertBefore(parent, first, link)
+
+	if stop < last.Segment.Stop || last.SoftLineBreak() || last.HardLineBreak() {
+		after := ast.NewTextSegment(text.NewSegment(stop, last.Segment.Stop))
+		after.SetSoftLineBreak(last.SoftLineBreak())
+		after.SetHardLineBreak(last.HardLineBreak())
+		parent.InsertBefore(parent, first, after)
+	}
+
+	for _, n := range run {
+		parent.RemoveChild(parent, n)
+	}
+}
+
+// byteOffsets maps every UTF-16 offset into source, the unit of entity
+// offsets, to the matching byte offset.
+func byteOffsets(source []byte) []int {
+	offsets := make([]int, 0, len(source)+1)
+	for i := 0; i < len(source); {
+		r, size := utf8.DecodeRune(source[i:])
+		for range utf16.RuneLen(r) {
+			offsets = append(offsets, i)
+		}
+		i += size
+	}
+
+	return append(offsets, len(source))
+}
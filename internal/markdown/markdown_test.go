@@ -0,0 +1,82 @@
+package markdown
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/store"
+)
+
+func resolve(entities []store.Entity) []store.Entity {
+	for i := range entities {
+		if entities[i].Type == store.EntityMention && entities[i].Text != "nobody" {
+			entities[i].UserID = 7
+		}
+	}
+	return entities
+}
+
+func TestRender(t *testing.T) {
+	r := New(Links{
+		Mention: func(e store.Entity) string { return fmt.Sprintf("/users/%d", e.UserID) },
+		Hashtag: func(tag string) string { return "/tags/" + tag },
+	})
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "inline formatting",
+			content: "**bold** _em_ ~~gone~~ `code`",
+			want:    "<p><strong>bold</strong> <em>em</em> <del>gone</del> <code>code</code></p>\n",
+		},
+		{
+			name:    "hard line breaks",
+			content: "one\ntwo",
+			want:    "<p>one<br>\ntwo</p>\n",
+		},
+		{
+			name:    "headings and raw html are shown as typed",
+			content: "# title\n<script>alert(1)</script>",
+			want:    "<p># title<br>\n&lt;script&gt;alert(1)&lt;/script&gt;</p>\n",
+		},
+		{
+			name:    "dangerous links and images are dropped",
+			content: "[x](javascript:alert(1)) ![img](https://example.com/a.png)",
+			want:    "<p>x </p>\n",
+		},
+		{
+			name:    "links get nofollow",
+			content: "[go](https://go.dev)",
+			want:    `<p><a href="https://go.dev" rel="nofollow noopener" target="_blank">go</a></p>` + "\n",
+		},
+		{
+			name:    "entities are autolinked",
+			content: "hi @go_pher, see https://go.dev #golang",
+			want: `<p>hi <a href="/users/7" class="mention" rel="nofollow">@go_pher</a>, ` +
+				`see <a href="https://go.dev" class="url" rel="nofollow noopener" target="_blank">https://go.dev</a> ` +
+				`<a href="/tags/golang" class="hashtag" rel="nofollow">#golang</a></p>` + "\n",
+		},
+		{
+			name:    "unresolved mentions and entities in code are not linked",
+			content: "@nobody `#code`",
+			want:    "<p>@nobody <code>#code</code></p>\n",
+		},
+		{
+			name:    "entities in emphasis keep their formatting",
+			content: "*🎉 @gopher*",
+			want:    `<p><em>🎉 <a href="/users/7" class="mention" rel="nofollow">@gopher</a></em></p>` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := r.Render(tt.content, resolve(store.ParseEntities(tt.content)))
+			if got != tt.want {
+				t.Errorf("Expected %q. Got %q", tt.want, got)
+			}
+		})
+	}
+}
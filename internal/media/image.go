@@ -0,0 +1,128 @@
+package media
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	"golang.org/x/image/draw"
+)
+
+var (
+	ErrUnsupportedType = errors.New("unsupported image type, upload a JPEG, PNG or GIF")
+	ErrImageTooLarge   = errors.New("image dimensions are too large")
+)
+
+var extensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// Image is a validated upload ready to be stored.
+type Image struct {
+	ContentType string
+	// Ext is the file extension matching ContentType, including the dot.
+	Ext       string
+	Width     int
+	Height    int
+	Data      []byte
+	Thumbnail []byte
+}
+
+// Process sniffs the type of an uploaded image, rejects anything that is not
+// a JPEG, PNG or GIF of at most maxPixels pixels, and renders a JPEG
+// thumbnail whose longest side is thumbnailSize. JPEG and PNG images are
+// re-encoded, which drops any embedded metadata such as GPS coordinates;
+// GIFs are kept as uploaded so animations survive.
+func Process(data []byte, maxPixels, thumbnailSize int) (*Image, error) {
+	contentType := http.DetectContentType(data)
+	ext, ok := extensions[contentType]
+	if !ok {
+		return nil, ErrUnsupportedType
+	}
+
+	// Check the dimensions from the header before decoding so a small file
+	// cannot make us allocate a huge bitmap.
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedType
+	}
+
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxPixels {
+		return nil, ErrImageTooLarge
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedType
+	}
+
+	img := &Image{
+		ContentType: contentType,
+		Ext:         ext,
+		Width:       cfg.Width,
+		Height:      cfg.Height,
+	}
+
+	var buf bytes.Buffer
+	switch contentType {
+	case "image/jpeg":
+		err = jpeg.Encode(&buf, src, &jpeg.Options{Quality: 90})
+	case "image/png":
+		err = png.Encode(&buf, src)
+	default:
+		_, err = buf.Write(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	img.Data = buf.Bytes()
+
+	var thumb bytes.Buffer
+	if err := jpeg.Encode(&thumb, thumbnail(src, thumbnailSize), &jpeg.Options{Quality: 80}); err != nil {
+		return nil, err
+	}
+	img.Thumbnail = thumb.Bytes()
+
+	return img, nil
+}
+
+// thumbnail scales src down to fit in a size×size box, flattening any
+// transparency onto white since JPEG has no alpha channel.
+func thumbnail(src image.Image, size int) image.Image {
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+
+	if w > size || h > size {
+		if w >= h {
+			h = max(1, h*size/w)
+			w = size
+		} else {
+			w = max(1, w*size/h)
+			h = size
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Over, nil)
+
+	return dst
+}
+
+// NewKey returns a random, unguessable base name for the files of an upload.
+func NewKey() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
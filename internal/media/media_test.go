@@ -0,0 +1,115 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"testing"
+)
+
+func encodePNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for x := 0; x < w; x++ {
+		img.Set(x, 0, color.NRGBA{R: 255, A: 128})
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestProcess(t *testing.T) {
+	t.Run("should generate a thumbnail that keeps the aspect ratio", func(t *testing.T) {
+		img, err := Process(encodePNG(t, 400, 100), 1_000_000, 80)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if img.ContentType != "image/png" || img.Ext != ".png" {
+			t.Errorf("Expected a PNG. Got %q %q", img.ContentType, img.Ext)
+		}
+
+		if img.Width != 400 || img.Height != 100 {
+			t.Errorf("Expected 400x100. Got %dx%d", img.Width, img.Height)
+		}
+
+		thumb, err := jpeg.Decode(bytes.NewReader(img.Thumbnail))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if size := thumb.Bounds().Size(); size.X != 80 || size.Y != 20 {
+			t.Errorf("Expected an 80x20 thumbnail. Got %dx%d", size.X, size.Y)
+		}
+	})
+
+	t.Run("should reject files that are not images", func(t *testing.T) {
+		_, err := Process([]byte("<html><body>hi</body></html>"), 1_000_000, 80)
+		if !errors.Is(err, ErrUnsupportedType) {
+			t.Errorf("Expected %v. Got %v", ErrUnsupportedType, err)
+		}
+	})
+
+	t.Run("should reject images with too many pixels", func(t *testing.T) {
+		_, err := Process(encodePNG(t, 400, 100), 100, 80)
+		if !errors.Is(err, ErrImageTooLarge) {
+			t.Errorf("Expected %v. Got %v", ErrImageTooLarge, err)
+		}
+	})
+}
+
+func TestLocalStorage(t *testing.T) {
+	ctx := context.Background()
+
+	s, err := NewLocalStorage(t.TempDir(), "/files/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Put(ctx, "a.png", bytes.NewReader([]byte("data"))); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := s.Open(ctx, "a.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := io.ReadAll(f)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "data" {
+		t.Errorf("Expected %q. Got %q", "data", data)
+	}
+
+	if url := s.URL("a.png"); url != "/files/a.png" {
+		t.Errorf("Expected %q. Got %q", "/files/a.png", url)
+	}
+
+	if err := s.Delete(ctx, "a.png"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Open(ctx, "a.png"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected %v. Got %v", ErrNotFound, err)
+	}
+
+	for _, key := range []string{"", "..", "../a.png", "dir/a.png", ".upload-1"} {
+		if err := s.Put(ctx, key, bytes.NewReader(nil)); err == nil {
+			t.Errorf("Expected key %q to be rejected", key)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+var ErrNotFound = errors.New("media file not found")
+
+// Storage keeps uploaded media files addressed by flat keys.
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	// URL is the address clients fetch the file from.
+	URL(key string) string
+}
@@ -0,0 +1,153 @@
+package spam
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// History looks up what an author recently published, posts and comments
+// alike.
+type History interface {
+	// CountDuplicates counts the content authorID created since since whose
+	// text matches text, ignoring case and surrounding whitespace. The
+	// content being edited, if any, is not counted.
+	CountDuplicates(ctx context.Context, authorID int64, text string, since time.Time, edit Edit) (int, error)
+	CountRecent(ctx context.Context, authorID int64, since time.Time, edit Edit) (int, error)
+}
+
+// Duplicates flags content the author already published within Window.
+// Every earlier copy adds Score.
+type Duplicates struct {
+	History History
+	Window  time.Duration
+	Score   float64
+}
+
+func (d *Duplicates) Check(ctx context.Context, c *Content) (*Signal, error) {
+	// The text was checked when it was published.
+	if c.Edit.isEdit() && !c.Edit.TextChanged {
+		return nil, nil
+	}
+
+	count, err := d.History.CountDuplicates(ctx, c.AuthorID, c.Text, time.Now().Add(-d.Window), c.Edit)
+	if err != nil {
+		return nil, err
+	}
+
+	if count == 0 {
+		return nil, nil
+	}
+
+	return &Signal{
+		Check:  "duplicates",
+		Score:  float64(count) * d.Score,
+		Reason: fmt.Sprintf("same content published %d times in the last %s", count, d.Window),
+	}, nil
+}
+
+// LinkDensity flags content with more than MaxLinks links, or with more
+// than one link every WordsPerLink words.
+type LinkDensity struct {
+	MaxLinks     int
+	WordsPerLink int
+	Score        float64
+}
+
+func (l *LinkDensity) Check(_ context.Context, c *Content) (*Signal, error) {
+	if c.Links == 0 {
+		return nil, nil
+	}
+
+	words := len(strings.Fields(c.Text))
+
+	switch {
+	case c.Links > l.MaxLinks:
+		return &Signal{
+			Check:  "link_density",
+			Score:  l.Score,
+			Reason: fmt.Sprintf("%d links, more than the %d allowed", c.Links, l.MaxLinks),
+		}, nil
+	case l.WordsPerLink > 0 && words < c.Links*l.WordsPerLink:
+		return &Signal{
+			Check:  "link_density",
+			Score:  l.Score,
+			Reason: fmt.Sprintf("%d links in %d words", c.Links, words),
+		}, nil
+	default:
+		return nil, nil
+	}
+}
+
+// Velocity flags accounts younger than NewAccountAge that publish Limit or
+// more times within Window.
+type Velocity struct {
+	History       History
+	NewAccountAge time.Duration
+	Window        time.Duration
+	Limit         int
+	Score         float64
+}
+
+func (v *Velocity) Check(ctx context.Context, c *Content) (*Signal, error) {
+	if time.Since(c.AuthorSince) >= v.NewAccountAge {
+		return nil, nil
+	}
+
+	count, err := v.History.CountRecent(ctx, c.AuthorID, time.Now().Add(-v.Window), c.Edit)
+	if err != nil {
+		return nil, err
+	}
+
+	if count < v.Limit {
+		return nil, nil
+	}
+
+	return &Signal{
+		Check:  "velocity",
+		Score:  v.Score,
+		Reason: fmt.Sprintf("new account published %d times in the last %s", count, v.Window),
+	}, nil
+}
+
+// BlockedWords flags content containing any of a list of words or phrases,
+// matched as whole words regardless of case.
+type BlockedWords struct {
+	pattern *regexp.Regexp
+	score   float64
+}
+
+func NewBlockedWords(words []string, score float64) *BlockedWords {
+	quoted := []string{}
+	for _, word := range words {
+		if word = strings.TrimSpace(word); word != "" {
+			quoted = append(quoted, regexp.QuoteMeta(word))
+		}
+	}
+
+	b := &BlockedWords{score: score}
+	if len(quoted) > 0 {
+		b.pattern = regexp.MustCompile(`(?i)\b(?:` + strings.Join(quoted, "|") + `)\b`)
+	}
+
+	return b
+}
+
+func (b *BlockedWords) Check(_ context.Context, c *Content) (*Signal, error) {
+	if b.pattern == nil {
+		return nil, nil
+	}
+
+	match := b.pattern.FindString(c.Title + "\n" + c.Text)
+	if match == "" {
+		return nil, nil
+	}
+
+	return &Signal{
+		Check:  "blocked_words",
+		Score:  b.score,
+		Reason: fmt.Sprintf("contains the blocked word %q", strings.ToLower(match)),
+	}, nil
+}
@@ -0,0 +1,143 @@
+// Package spam scores user-submitted posts and comments with a pipeline of
+// checks and decides whether they are published, held for review, hidden
+// from everyone but their author, or rejected.
+package spam
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Action is what happens to content, from the least to the most severe.
+type Action int
+
+const (
+	Allow Action = iota
+	Hold
+	Hide
+	Reject
+)
+
+func (a Action) String() string {
+	switch a {
+	case Allow:
+		return "allow"
+	case Hold:
+		return "hold"
+	case Hide:
+		return "hide"
+	case Reject:
+		return "reject"
+	default:
+		return fmt.Sprintf("Action(%d)", int(a))
+	}
+}
+
+// Content is a post or comment about to be created or edited.
+type Content struct {
+	AuthorID int64
+	// AuthorSince is when the author's account was created.
+	AuthorSince time.Time
+	// Title is only set for posts.
+	Title string
+	Text  string
+	Links int
+	// Edit is set when the content replaces a stored post or comment.
+	Edit Edit
+}
+
+// Edit identifies the stored post or comment that edited content replaces.
+// The history checks leave it out so that an edit is not counted against
+// itself. The zero Edit stands for new content.
+type Edit struct {
+	PostID    int64
+	CommentID int64
+	// TextChanged is false when only the title of a post was edited.
+	TextChanged bool
+}
+
+func (e Edit) isEdit() bool {
+	return e.PostID != 0 || e.CommentID != 0
+}
+
+// Signal is a check's finding about content. Scores of all signals add up.
+type Signal struct {
+	Check  string
+	Score  float64
+	Reason string
+}
+
+// Checker is one step of the pipeline. It returns nil when the content
+// looks fine to it.
+type Checker interface {
+	Check(ctx context.Context, c *Content) (*Signal, error)
+}
+
+// Thresholds are the total scores at which content is held, hidden or
+// rejected. A zero threshold never triggers.
+type Thresholds struct {
+	Hold   float64
+	Hide   float64
+	Reject float64
+}
+
+type Verdict struct {
+	Action  Action
+	Score   float64
+	Signals []Signal
+}
+
+// Reasons lists the reasons of the signals, in pipeline order.
+func (v *Verdict) Reasons() []string {
+	reasons := make([]string, len(v.Signals))
+	for i, s := range v.Signals {
+		reasons[i] = s.Reason
+	}
+	return reasons
+}
+
+type Pipeline struct {
+	thresholds Thresholds
+	checkers   []Checker
+}
+
+func New(thresholds Thresholds, checkers ...Checker) *Pipeline {
+	return &Pipeline{thresholds: thresholds, checkers: checkers}
+}
+
+// Evaluate runs every check on c and turns the total score into an action.
+func (p *Pipeline) Evaluate(ctx context.Context, c *Content) (*Verdict, error) {
+	verdict := &Verdict{Signals: []Signal{}}
+
+	for _, checker := range p.checkers {
+		signal, err := checker.Check(ctx, c)
+		if err != nil {
+			return nil, err
+		}
+
+		if signal == nil || signal.Score <= 0 {
+			continue
+		}
+
+		verdict.Score += signal.Score
+		verdict.Signals = append(verdict.Signals, *signal)
+	}
+
+	switch {
+	case reached(verdict.Score, p.thresholds.Reject):
+		verdict.Action = Reject
+	case reached(verdict.Score, p.thresholds.Hide):
+		verdict.Action = Hide
+	case reached(verdict.Score, p.thresholds.Hold):
+		verdict.Action = Hold
+	default:
+		verdict.Action = Allow
+	}
+
+	return verdict, nil
+}
+
+func reached(score, threshold float64) bool {
+	return threshold > 0 && score >= threshold
+}
@@ -0,0 +1,114 @@
+package spam
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type fakeHistory struct {
+	duplicates int
+	recent     int
+}
+
+func (h *fakeHistory) CountDuplicates(context.Context, int64, string, time.Time, Edit) (int, error) {
+	return h.duplicates, nil
+}
+
+func (h *fakeHistory) CountRecent(context.Context, int64, time.Time, Edit) (int, error) {
+	return h.recent, nil
+}
+
+func TestPipeline(t *testing.T) {
+	history := &fakeHistory{}
+	pipeline := New(
+		Thresholds{Hold: 1, Hide: 2, Reject: 3},
+		&Duplicates{History: history, Window: time.Minute * 10, Score: 1},
+		&LinkDensity{MaxLinks: 3, WordsPerLink: 5, Score: 1},
+		&Velocity{History: history, NewAccountAge: time.Hour * 24, Window: time.Minute, Limit: 5, Score: 1},
+		NewBlockedWords([]string{"cheap pills", " "}, 3),
+	)
+
+	tests := []struct {
+		name       string
+		duplicates int
+		recent     int
+		content    Content
+		want       Action
+	}{
+		{
+			name:    "allows ordinary content",
+			content: Content{Text: "A perfectly normal comment about gophers", Links: 1},
+			want:    Allow,
+		},
+		{
+			name:       "holds a duplicate",
+			duplicates: 1,
+			content:    Content{Text: "first!"},
+			want:       Hold,
+		},
+		{
+			name:       "hides a duplicate from a flooding new account",
+			duplicates: 1,
+			recent:     5,
+			content:    Content{Text: "first!", AuthorSince: time.Now()},
+			want:       Hide,
+		},
+		{
+			name:       "does not check the duplicates of an edited title",
+			duplicates: 1,
+			content:    Content{Title: "Fixed a typo", Text: "first!", Edit: Edit{PostID: 1}},
+			want:       Allow,
+		},
+		{
+			name:       "checks the duplicates of edited text",
+			duplicates: 1,
+			content:    Content{Text: "first!", Edit: Edit{CommentID: 1, TextChanged: true}},
+			want:       Hold,
+		},
+		{
+			name:    "ignores the velocity of established accounts",
+			recent:  50,
+			content: Content{Text: "busy day", AuthorSince: time.Now().Add(-time.Hour * 48)},
+			want:    Allow,
+		},
+		{
+			name:    "holds content made of links",
+			content: Content{Text: "see a.com b.com", Links: 2},
+			want:    Hold,
+		},
+		{
+			name:    "rejects blocked words regardless of case",
+			content: Content{Text: "Buy CHEAP pills here"},
+			want:    Reject,
+		},
+		{
+			name:    "rejects blocked words in titles",
+			content: Content{Title: "cheap pills", Text: "click through"},
+			want:    Reject,
+		},
+		{
+			name:    "matches blocked words as whole words only",
+			content: Content{Text: "cheap pillsbury dough"},
+			want:    Allow,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			history.duplicates, history.recent = tt.duplicates, tt.recent
+			if tt.content.AuthorSince.IsZero() {
+				tt.content.AuthorSince = time.Now().Add(-time.Hour * 24 * 365)
+			}
+
+			verdict, err := pipeline.Evaluate(context.Background(), &tt.content)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if verdict.Action != tt.want {
+				t.Errorf("Expected %s. Got %s with %v", tt.want, verdict.Action, verdict.Reasons())
+			}
+		})
+	}
+}
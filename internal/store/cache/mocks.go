@@ -21,3 +21,7 @@ func (m *MockUsersStore) Get(context.Context, int64) (*store.User, error){
 func (m *MockUsersStore) Set(context.Context, *store.User) error{
 	return nil
 }
+
+func (m *MockUsersStore) Delete(context.Context, int64) error {
+	return nil
+}
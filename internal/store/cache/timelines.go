@@ -0,0 +1,164 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/store"
+	"github.com/redis/go-redis/v9"
+)
+
+// TimelineExpTime is how long a timeline nobody writes to is kept. An
+// expired timeline is rebuilt the next time it is read.
+const TimelineExpTime = 30 * 24 * time.Hour
+
+// TimelineStore keeps home timelines in Redis sorted sets. Every member
+// scores 0 and encodes the (created_at, id) position of its post as fixed
+// width text, so members sort in feed order and pages are read with
+// lexicographic ranges.
+type TimelineStore struct {
+	rdb       *redis.Client
+	maxLength int
+}
+
+// NewTimelineStore returns a store that keeps the newest maxLength entries
+// of every timeline.
+func NewTimelineStore(rdb *redis.Client, maxLength int) *TimelineStore {
+	return &TimelineStore{rdb: rdb, maxLength: maxLength}
+}
+
+func timelineKey(userID int64) string {
+	return fmt.Sprintf("timeline-%v", userID)
+}
+
+func timelineMember(createdAt time.Time, postID int64) string {
+	return fmt.Sprintf("%020d:%020d", createdAt.UnixNano(), postID)
+}
+
+func parseTimelineMember(member string) (store.TimelineEntry, error) {
+	nanos, id, ok := strings.Cut(member, ":")
+	if !ok {
+		return store.TimelineEntry{}, fmt.Errorf("invalid timeline member %q", member)
+	}
+
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return store.TimelineEntry{}, err
+	}
+
+	postID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return store.TimelineEntry{}, err
+	}
+
+	return store.TimelineEntry{PostID: postID, CreatedAt: time.Unix(0, n)}, nil
+}
+
+func (s *TimelineStore) Add(ctx context.Context, entry store.TimelineEntry, userIDs []int64) error {
+	member := redis.Z{Member: timelineMember(entry.CreatedAt, entry.PostID)}
+
+	_, err := s.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, userID := range userIDs {
+			s.write(ctx, pipe, userID, member)
+		}
+		return nil
+	})
+	return err
+}
+
+func (s *TimelineStore) Backfill(ctx context.Context, userID int64, entries []store.TimelineEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	members := make([]redis.Z, len(entries))
+	for i, entry := range entries {
+		members[i] = redis.Z{Member: timelineMember(entry.CreatedAt, entry.PostID)}
+	}
+
+	_, err := s.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		s.write(ctx, pipe, userID, members...)
+		return nil
+	})
+	return err
+}
+
+// write adds members to the timeline of userID, trims it to maxLength and
+// keeps it for another TimelineExpTime.
+func (s *TimelineStore) write(ctx context.Context, pipe redis.Pipeliner, userID int64, members ...redis.Z) {
+	key := timelineKey(userID)
+
+	pipe.ZAdd(ctx, key, members...)
+	pipe.ZRemRangeByRank(ctx, key, 0, int64(-s.maxLength-1))
+	pipe.Expire(ctx, key, TimelineExpTime)
+}
+
+func (s *TimelineStore) Remove(ctx context.Context, entries []store.TimelineEntry, userIDs []int64) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	members := make([]any, len(entries))
+	for i, entry := range entries {
+		members[i] = timelineMember(entry.CreatedAt, entry.PostID)
+	}
+
+	_, err := s.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, userID := range userIDs {
+			pipe.ZRem(ctx, timelineKey(userID), members...)
+		}
+		return nil
+	})
+	return err
+}
+
+func (s *TimelineStore) Page(ctx context.Context, userID int64, cursor *store.Cursor, limit int) ([]store.TimelineEntry, error) {
+	// go-redis swaps Start and Stop for reverse ranges, so Start is always
+	// the lower bound.
+	args := redis.ZRangeArgs{
+		Key:   timelineKey(userID),
+		Start: "-",
+		Stop:  "+",
+		ByLex: true,
+		Rev:   true,
+		Count: int64(limit),
+	}
+
+	if cursor != nil {
+		position := "(" + timelineMember(cursor.CreatedAt, cursor.ID)
+		if cursor.Prev {
+			args.Start, args.Rev = position, false
+		} else {
+			args.Stop = position
+		}
+	}
+
+	members, err := s.rdb.ZRangeArgs(ctx, args).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]store.TimelineEntry, 0, len(members))
+	for _, member := range members {
+		entry, err := parseTimelineMember(member)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	if !args.Rev {
+		slices.Reverse(entries)
+	}
+
+	return entries, nil
+}
+
+// Trim is a no-op: timelines are trimmed as they are written.
+func (s *TimelineStore) Trim(context.Context, int) (int64, error) {
+	return 0, nil
+}
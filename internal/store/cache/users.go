@@ -17,6 +17,13 @@ type UserStore struct {
 
 const UserExpTime = time.Minute
 
+// cachedUser keeps the preferences of the user, which are left out of its
+// JSON so that they are only shown to the user themselves.
+type cachedUser struct {
+	*store.User
+	Preferences store.UserPreferences `json:"preferences"`
+}
+
 func (s *UserStore) Get(ctx context.Context, userID int64) (*store.User, error) {
 	cacheKey := fmt.Sprintf("user-%v", userID)
 
@@ -29,10 +36,12 @@ func (s *UserStore) Get(ctx context.Context, userID int64) (*store.User, error)
 
 	var user store.User
 	if data != "" {
-		err := json.Unmarshal([]byte(data), &user)
+		cached := cachedUser{User: &user}
+		err := json.Unmarshal([]byte(data), &cached)
 		if err != nil {
 			return nil, err
 		}
+		user.Preferences = cached.Preferences
 	}
 
 	return &user, nil
@@ -45,10 +54,16 @@ func (s *UserStore) Set(ctx context.Context, user *store.User) error {
 
 	cacheKey := fmt.Sprintf("user-%v", user.ID)
 	
-	json, err := json.Marshal(user)
+	json, err := json.Marshal(cachedUser{User: user, Preferences: user.Preferences})
 	if err != nil {
 		return err
 	}
 
 	return s.rdb.SetEx(ctx, cacheKey, json, UserExpTime).Err()
 }
+
+func (s *UserStore) Delete(ctx context.Context, userID int64) error {
+	cacheKey := fmt.Sprintf("user-%v", userID)
+
+	return s.rdb.Del(ctx, cacheKey).Err()
+}
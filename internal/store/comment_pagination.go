@@ -0,0 +1,155 @@
+package store
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	CommentSortNewest = "newest"
+	CommentSortOldest = "oldest"
+	CommentSortTop    = "top"
+)
+
+// PaginatedCommentQuery pages through the top-level comments of a post,
+// each with up to Replies of its direct replies. Top comments are the ones
+// with the most replies.
+type PaginatedCommentQuery struct {
+	Limit   int            `json:"limit" validate:"gte=1,lte=50"`
+	Sort    string         `json:"sort" validate:"oneof=newest oldest top"`
+	Cursor  *CommentCursor `json:"cursor"`
+	Replies int            `json:"replies" validate:"gte=0,lte=10"`
+}
+
+// CommentCursor is a keyset position in a comment listing: the sort key of
+// the last comment of a page (its creation time in nanoseconds, or its
+// reply count for top comments) and its ID. The sort order is part of the
+// cursor so a cursor cannot be used with another order.
+type CommentCursor struct {
+	Sort string
+	Key  int64
+	ID   int64
+}
+
+func (c CommentCursor) Encode() string {
+	raw := fmt.Sprintf("%s:%d:%d", c.Sort, c.Key, c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeCommentCursor(token string) (*CommentCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	parts := strings.Split(string(raw), ":")
+	if len(parts) != 3 {
+		return nil, ErrInvalidCursor
+	}
+
+	key, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	id, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &CommentCursor{Sort: parts[0], Key: key, ID: id}, nil
+}
+
+func (q *PaginatedCommentQuery) Parse(r *http.Request) (*PaginatedCommentQuery, error) {
+	params := r.URL.Query()
+
+	limit := params.Get("limit")
+	if limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return nil, err
+		}
+
+		q.Limit = l
+	}
+
+	sort := params.Get("sort")
+	if sort != "" {
+		q.Sort = sort
+	}
+
+	replies := params.Get("replies")
+	if replies != "" {
+		r, err := strconv.Atoi(replies)
+		if err != nil {
+			return nil, err
+		}
+
+		q.Replies = r
+	}
+
+	cursor := params.Get("cursor")
+	if cursor != "" {
+		c, err := DecodeCommentCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+
+		if c.Sort != q.Sort {
+			return nil, ErrInvalidCursor
+		}
+
+		q.Cursor = c
+	}
+
+	return q, nil
+}
+
+// order returns the ORDER BY clause of q over comments aliased as c and the
+// keyset condition that continues after q.Cursor, if any, with the cursor
+// values appended to args.
+func (q *PaginatedCommentQuery) order(args []any) (string, string, []any) {
+	var orderBy, key, op string
+	switch q.Sort {
+	case CommentSortOldest:
+		orderBy, key, op = "c.created_at ASC, c.id ASC", "c.created_at", ">"
+	case CommentSortTop:
+		orderBy, key, op = "c.reply_count DESC, c.id DESC", "c.reply_count", "<"
+	default:
+		orderBy, key, op = "c.created_at DESC, c.id DESC", "c.created_at", "<"
+	}
+
+	if q.Cursor == nil {
+		return orderBy, "", args
+	}
+
+	var keyValue any = q.Cursor.Key
+	if key == "c.created_at" {
+		keyValue = time.Unix(0, q.Cursor.Key)
+	}
+
+	args = append(args, keyValue, q.Cursor.ID)
+	condition := fmt.Sprintf("(%s, c.id) %s ($%d, $%d)", key, op, len(args)-1, len(args))
+
+	return orderBy, condition, args
+}
+
+// NextCursor returns the cursor that continues after the last of page, or
+// nil when the page was not full and there is nothing more to fetch.
+func (q *PaginatedCommentQuery) NextCursor(page []Comment) *CommentCursor {
+	if len(page) == 0 || len(page) < q.Limit {
+		return nil
+	}
+
+	last := page[len(page)-1]
+	cursor := &CommentCursor{Sort: q.Sort, Key: last.CreatedAt.UnixNano(), ID: last.ID}
+	if q.Sort == CommentSortTop {
+		cursor.Key = int64(last.ReplyCount)
+	}
+
+	return cursor
+}
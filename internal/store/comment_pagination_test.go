@@ -0,0 +1,38 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCommentCursor(t *testing.T) {
+	cursor := CommentCursor{Sort: CommentSortOldest, Key: time.Now().UnixNano(), ID: 42}
+
+	decoded, err := DecodeCommentCursor(cursor.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if *decoded != cursor {
+		t.Errorf("Expected %+v. Got %+v", cursor, *decoded)
+	}
+
+	if _, err := DecodeCommentCursor("bm90IGEgY3Vyc29y"); err != ErrInvalidCursor {
+		t.Errorf("Expected ErrInvalidCursor. Got %v", err)
+	}
+}
+
+func TestCommentNextCursor(t *testing.T) {
+	page := []Comment{{ID: 1, ReplyCount: 5}, {ID: 2, ReplyCount: 3}}
+
+	q := &PaginatedCommentQuery{Limit: 2, Sort: CommentSortTop}
+	next := q.NextCursor(page)
+	if next == nil || next.Key != 3 || next.ID != 2 {
+		t.Errorf("Expected a cursor after 3 replies and comment 2. Got %+v", next)
+	}
+
+	q.Limit = 3
+	if next := q.NextCursor(page); next != nil {
+		t.Errorf("Expected no cursor after a partial page. Got %+v", next)
+	}
+}
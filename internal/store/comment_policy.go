@@ -0,0 +1,81 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	CommentPolicyEveryone  = "everyone"
+	CommentPolicyFollowers = "followers"
+	CommentPolicyMentioned = "mentioned"
+	CommentPolicyNobody    = "nobody"
+)
+
+// CommentableBy reports whether the post's comment policy lets user comment
+// on it given whether they follow its author. Authors may always comment on
+// their own posts. Locked threads are not considered here; see
+// SetCommentsLocked.
+func (p *Post) CommentableBy(user *User, follows bool) bool {
+	if p.UserID == user.ID {
+		return true
+	}
+
+	switch p.CommentPolicy {
+	case "", CommentPolicyEveryone:
+		return true
+	case CommentPolicyFollowers:
+		return follows
+	case CommentPolicyMentioned:
+		for _, id := range mentionedUserIDs(p.Entities) {
+			if id == user.ID {
+				return true
+			}
+		}
+		return false
+	default:
+		return false
+	}
+}
+
+// SetCommentsLocked locks or unlocks the comment thread of the post
+// according to post.CommentsLocked, independently of its comment policy, if
+// it is still at post.Version. When a moderator locks someone else's post,
+// entry records the action in the moderation log.
+func (s *PostStore) SetCommentsLocked(ctx context.Context, post *Post, entry *ModerationEntry) error {
+	query := `
+		UPDATE posts SET comments_locked = $1, version = version + 1
+		WHERE id = $2 AND version = $3
+		RETURNING version
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx pgx.Tx) error {
+		if err := lockPost(ctx, tx, post.ID); err != nil {
+			return err
+		}
+
+		err := tx.QueryRow(ctx, query, post.CommentsLocked, post.ID, post.Version).Scan(&post.Version)
+		if err != nil {
+			switch {
+			case errors.Is(err, pgx.ErrNoRows):
+				// The row is locked above, so it exists at another version.
+				return ErrVersionConflict
+			default:
+				return err
+			}
+		}
+
+		if entry == nil {
+			return nil
+		}
+
+		entry.PostID = &post.ID
+		entry.TargetUserID = post.UserID
+		return logModeration(ctx, tx, entry)
+	})
+}
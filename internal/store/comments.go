@@ -2,63 +2,223 @@ package store
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"slices"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type Comment struct {
-	ID        int64     `json:"id"`
-	UserID    int64     `json:"user_id"`
-	PostID    int64     `json:"post_id"`
-	Content   string    `json:"content"`
-	CreatedAt time.Time `json:"created_at"`
-	User      User      `json:"user"`
+	ID           int64      `json:"id"`
+	UserID       int64      `json:"user_id"`
+	PostID       int64      `json:"post_id"`
+	ParentID     *int64     `json:"parent_id"`
+	Depth        int        `json:"depth"`
+	ReplyCount   int        `json:"reply_count"`
+	Content      string     `json:"content"`
+	ContentHTML  string     `json:"content_html"`
+	Entities     []Entity   `json:"entities"`
+	CreatedAt    time.Time  `json:"created_at"`
+	EditedAt     *time.Time `json:"edited_at"`
+	Version      int        `json:"version"`
+	User         User       `json:"user"`
+	Replies      []Comment  `json:"replies"`
+	ReviewStatus string     `json:"-"`
+	SpamReasons  []string   `json:"-"`
 }
 
 type CommentStore struct {
 	db *pgxpool.Pool
 }
 
-func (s *CommentStore) GetCommentsByPostID(ctx context.Context, postID int64) ([]Comment, error) {
+const commentColumns = `
+	c.id, c.post_id, c.user_id, c.parent_id, c.depth, c.reply_count, c.content, c.entities, c.created_at,
+	c.edited_at, c.version, c.review_status, users.username, users.id, users.created_at
+`
+
+// commentVisibleTo restricts a query over comments aliased as c to the ones
+// the user ID given by the SQL expression userID may read: approved
+// comments and their own. Authors cannot tell when theirs are held or
+// hidden by the spam filter.
+func commentVisibleTo(userID string) string {
+	return "(c.review_status = 'approved' OR c.user_id = " + userID + ")"
+}
+
+// GetPage returns a page of the top-level comments of a post that viewerID
+// may read in the order of q, each with up to q.Replies of its readable
+// direct replies, oldest first. The replies are returned without their own
+// replies; their reply counts tell whether to fetch their subtree.
+func (s *CommentStore) GetPage(ctx context.Context, postID, viewerID int64, q *PaginatedCommentQuery) ([]Comment, error) {
+	orderBy, condition, args := q.order([]any{postID, viewerID})
+
 	query := `
-		SELECT c.id, c.post_id, c.user_id, c.content, c.created_at, users.username, users.id, users.created_at
+		SELECT ` + commentColumns + `
 		FROM comments c
 		JOIN users on users.id = c.user_id
-		WHERE c.post_id = $1
-		ORDER BY c.created_at DESC;
+		WHERE c.post_id = $1 AND c.parent_id IS NULL AND ` + commentVisibleTo("$2")
+	if condition != "" {
+		query += " AND " + condition
+	}
+	query += fmt.Sprintf(" ORDER BY %s LIMIT $%d", orderBy, len(args)+1)
+	args = append(args, q.Limit)
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	roots, err := scanComments(rows)
+	if err != nil || len(roots) == 0 {
+		return roots, err
+	}
+
+	ids := make([]int64, len(roots))
+	for i, root := range roots {
+		ids[i] = root.ID
+	}
+
+	if q.Replies == 0 {
+		return roots, nil
+	}
+
+	query = `
+		SELECT ` + commentColumns + `
+		FROM unnest($1::bigint[]) AS roots(id)
+		CROSS JOIN LATERAL (
+			SELECT * FROM comments c
+			WHERE c.parent_id = roots.id AND ` + commentVisibleTo("$2") + `
+			ORDER BY c.created_at, c.id
+			LIMIT $3
+		) c
+		JOIN users on users.id = c.user_id
+		ORDER BY c.created_at, c.id
 	`
 
+	rows, err = s.db.Query(ctx, query, ids, viewerID, q.Replies)
+	if err != nil {
+		return nil, err
+	}
+
+	replies, err := scanComments(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	// The roots come first so they keep the page order.
+	return commentTree(append(roots, replies...), 0), nil
+}
+
+// CountByPostID counts the comments of a post that viewerID may read,
+// replies included.
+func (s *CommentStore) CountByPostID(ctx context.Context, postID, viewerID int64) (int, error) {
+	query := `SELECT count(*) FROM comments c WHERE c.post_id = $1 AND ` + commentVisibleTo("$2")
+
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	rows, err := s.db.Query(
-		ctx,
-		query,
-		postID,
-	)
+	var count int
+	err := s.db.QueryRow(ctx, query, postID, viewerID).Scan(&count)
+	return count, err
+}
+
+func (s *CommentStore) GetByID(ctx context.Context, postID, commentID int64) (*Comment, error) {
+	query := `
+		SELECT ` + commentColumns + `
+		FROM comments c
+		JOIN users on users.id = c.user_id
+		WHERE c.post_id = $1 AND c.id = $2
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.Query(ctx, query, postID, commentID)
+	if err != nil {
+		return nil, err
+	}
+
+	comments, err := scanComments(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(comments) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return &comments[0], nil
+}
+
+// GetSubtree returns a comment of a post with the replies viewerID may read
+// as a tree, oldest first.
+func (s *CommentStore) GetSubtree(ctx context.Context, postID, commentID, viewerID int64) (*Comment, error) {
+	query := `
+		WITH RECURSIVE subtree AS (
+			SELECT id FROM comments WHERE post_id = $1 AND id = $2
+			UNION ALL
+			SELECT c.id FROM comments c JOIN subtree s ON c.parent_id = s.id
+		)
+		SELECT ` + commentColumns + `
+		FROM comments c
+		JOIN subtree ON subtree.id = c.id
+		JOIN users on users.id = c.user_id
+		WHERE ` + commentVisibleTo("$3") + `
+		ORDER BY c.created_at, c.id
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.Query(ctx, query, postID, commentID, viewerID)
+	if err != nil {
+		return nil, err
+	}
 
+	comments, err := scanComments(rows)
 	if err != nil {
 		return nil, err
 	}
 
+	i := slices.IndexFunc(comments, func(c Comment) bool { return c.ID == commentID })
+	if i < 0 {
+		return nil, ErrNotFound
+	}
+
+	root := comments[i]
+	root.Replies = commentTree(comments, root.ID)
+
+	return &root, nil
+}
+
+func scanComments(rows pgx.Rows) ([]Comment, error) {
 	defer rows.Close()
 
-	var comments []Comment
+	comments := []Comment{}
 	for rows.Next() {
 		var comment Comment
-		comment.User = User{}
 		err := rows.Scan(
 			&comment.ID,
 			&comment.PostID,
 			&comment.UserID,
+			&comment.ParentID,
+			&comment.Depth,
+			&comment.ReplyCount,
 			&comment.Content,
+			&comment.Entities,
 			&comment.CreatedAt,
+			&comment.EditedAt,
+			&comment.Version,
+			&comment.ReviewStatus,
 			&comment.User.Username,
 			&comment.User.ID,
 			&comment.User.CreatedAt,
 		)
-
 		if err != nil {
 			return nil, err
 		}
@@ -66,29 +226,275 @@ func (s *CommentStore) GetCommentsByPostID(ctx context.Context, postID int64) ([
 		comments = append(comments, comment)
 	}
 
-	return comments, nil
+	return comments, rows.Err()
 }
 
+// commentTree nests comments under their parents and returns the replies
+// to parentID, where 0 stands for the post itself. The order of comments
+// is kept among siblings.
+func commentTree(comments []Comment, parentID int64) []Comment {
+	children := make(map[int64][]int)
+	for i, c := range comments {
+		var parent int64
+		if c.ParentID != nil {
+			parent = *c.ParentID
+		}
+		children[parent] = append(children[parent], i)
+	}
+
+	var build func(parentID int64) []Comment
+	build = func(parentID int64) []Comment {
+		replies := []Comment{}
+		for _, i := range children[parentID] {
+			reply := comments[i]
+			reply.Replies = build(reply.ID)
+			replies = append(replies, reply)
+		}
+		return replies
+	}
+
+	return build(parentID)
+}
+
+// Create inserts the comment and its mention rows, notifying the mentioned
+// users who can see the post. A reply must be given the depth below its
+// parent, whose reply count is incremented. Comments held or hidden by the
+// spam filter neither notify nor count as replies until they are approved.
 func (s *CommentStore) Create(ctx context.Context, comment *Comment) error {
 	query := `
-		INSERT INTO comments (post_id, user_id, content)
-		VALUES ($1, $2, $3) RETURNING id, created_at
+		INSERT INTO comments (post_id, user_id, content, entities, parent_id, depth, review_status, spam_reasons)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, created_at
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	err := s.db.QueryRow(
-		ctx,
-		query,
-		comment.PostID,
-		comment.UserID,
-		comment.Content,
-	).Scan(&comment.ID, &comment.CreatedAt)
+	if comment.ReviewStatus == "" {
+		comment.ReviewStatus = ReviewApproved
+	}
+
+	if comment.SpamReasons == nil {
+		comment.SpamReasons = []string{}
+	}
+
+	approved := comment.ReviewStatus == ReviewApproved
+	replies := 0
+	if approved {
+		replies = 1
+	}
+
+	return withTx(s.db, ctx, func(tx pgx.Tx) error {
+		if comment.ParentID != nil {
+			result, err := tx.Exec(
+				ctx,
+				`UPDATE comments SET reply_count = reply_count + $3 WHERE id = $1 AND post_id = $2`,
+				*comment.ParentID,
+				comment.PostID,
+				replies,
+			)
+			if err != nil {
+				return err
+			}
+
+			if result.RowsAffected() == 0 {
+				return ErrNotFound
+			}
+		}
+
+		entities, err := resolveMentions(ctx, tx, comment.Entities)
+		if err != nil {
+			return err
+		}
+		comment.Entities = entities
+
+		err = tx.QueryRow(
+			ctx,
+			query,
+			comment.PostID,
+			comment.UserID,
+			comment.Content,
+			comment.Entities,
+			comment.ParentID,
+			comment.Depth,
+			comment.ReviewStatus,
+			comment.SpamReasons,
+		).Scan(&comment.ID, &comment.CreatedAt)
+		if err != nil {
+			return err
+		}
+
+		if _, err := replaceMentions(ctx, tx, comment.PostID, &comment.ID, comment.UserID, comment.Entities); err != nil {
+			return err
+		}
+
+		if !approved {
+			return nil
+		}
+
+		return notifyMentions(ctx, tx, []int64{comment.PostID}, &comment.ID, nil)
+	})
+}
+
+// Update saves the content of the comment if it is still at
+// comment.Version, replacing its mention rows and notifying newly mentioned
+// users. Like for posts, comment.ReviewStatus is only saved if it is
+// stricter than the current review status; a reply that is no longer
+// approved stops counting towards its parent's replies. When a moderator
+// edits someone else's comment, entry records the action in the moderation
+// log.
+func (s *CommentStore) Update(ctx context.Context, comment *Comment, entry *ModerationEntry) error {
+	query := `
+		UPDATE comments SET content = $1, entities = $2, review_status = $6, spam_reasons = COALESCE($7, spam_reasons),
+			edited_at = NOW(), version = version + 1
+		WHERE id = $3 AND post_id = $4 AND version = $5
+		RETURNING version, edited_at
+	`
 
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx pgx.Tx) error {
+		var parentID *int64
+		var reviewStatus string
+		err := tx.QueryRow(
+			ctx,
+			`SELECT parent_id, review_status FROM comments WHERE id = $1 AND post_id = $2 FOR UPDATE`,
+			comment.ID,
+			comment.PostID,
+		).Scan(&parentID, &reviewStatus)
+		if err != nil {
+			switch {
+			case errors.Is(err, pgx.ErrNoRows):
+				return ErrNotFound
+			default:
+				return err
+			}
+		}
+
+		reasons := comment.SpamReasons
+		comment.ReviewStatus = stricterReview(reviewStatus, comment.ReviewStatus)
+		if comment.ReviewStatus == reviewStatus {
+			reasons = nil
+		}
+
+		entities, err := resolveMentions(ctx, tx, comment.Entities)
+		if err != nil {
+			return err
+		}
+		comment.Entities = entities
+
+		err = tx.QueryRow(
+			ctx,
+			query,
+			comment.Content,
+			comment.Entities,
+			comment.ID,
+			comment.PostID,
+			comment.Version,
+			comment.ReviewStatus,
+			reasons,
+		).Scan(&comment.Version, &comment.EditedAt)
+		if err != nil {
+			switch {
+			case errors.Is(err, pgx.ErrNoRows):
+				// The row is locked above, so it exists at another version.
+				return ErrVersionConflict
+			default:
+				return err
+			}
+		}
+
+		if parentID != nil && reviewStatus == ReviewApproved && comment.ReviewStatus != ReviewApproved {
+			_, err := tx.Exec(ctx, `UPDATE comments SET reply_count = reply_count - 1 WHERE id = $1`, *parentID)
+			if err != nil {
+				return err
+			}
+		}
+
+		added, err := replaceMentions(ctx, tx, comment.PostID, &comment.ID, comment.UserID, comment.Entities)
+		if err != nil {
+			return err
+		}
+
+		if comment.ReviewStatus == ReviewApproved {
+			if err := notifyMentions(ctx, tx, []int64{comment.PostID}, &comment.ID, added); err != nil {
+				return err
+			}
+		}
+
+		return logCommentModeration(ctx, tx, comment, entry)
+	})
+}
+
+// Delete deletes the comment, along with its replies, if it is still at
+// comment.Version. When a moderator deletes someone else's comment, entry
+// records the action in the moderation log.
+func (s *CommentStore) Delete(ctx context.Context, comment *Comment, entry *ModerationEntry) error {
+	query := `
+		DELETE FROM comments
+		WHERE id = $1 AND post_id = $2 AND version = $3
+		RETURNING parent_id, review_status
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx pgx.Tx) error {
+		var parentID *int64
+		var reviewStatus string
+		err := tx.QueryRow(ctx, query, comment.ID, comment.PostID, comment.Version).Scan(&parentID, &reviewStatus)
+		if err != nil {
+			switch {
+			case errors.Is(err, pgx.ErrNoRows):
+				return s.missingOrConflict(ctx, tx, comment.PostID, comment.ID)
+			default:
+				return err
+			}
+		}
+
+		if parentID != nil && reviewStatus == ReviewApproved {
+			_, err := tx.Exec(ctx, `UPDATE comments SET reply_count = reply_count - 1 WHERE id = $1`, *parentID)
+			if err != nil {
+				return err
+			}
+		}
+
+		return logCommentModeration(ctx, tx, comment, entry)
+	})
+}
+
+// missingOrConflict tells apart a comment that no longer exists from one
+// that is at another version.
+func (s *CommentStore) missingOrConflict(ctx context.Context, tx pgx.Tx, postID, commentID int64) error {
+	var exists bool
+	err := tx.QueryRow(
+		ctx,
+		`SELECT EXISTS (SELECT 1 FROM comments WHERE id = $1 AND post_id = $2)`,
+		commentID,
+		postID,
+	).Scan(&exists)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	if !exists {
+		return ErrNotFound
+	}
+
+	return ErrVersionConflict
+}
+
+func logCommentModeration(ctx context.Context, tx pgx.Tx, comment *Comment, entry *ModerationEntry) error {
+	if entry == nil {
+		return nil
+	}
+
+	entry.PostID = &comment.PostID
+	entry.TargetUserID = comment.UserID
+	if entry.Details == nil {
+		entry.Details = map[string]any{}
+	}
+	entry.Details["comment_id"] = comment.ID
+
+	return logModeration(ctx, tx, entry)
 }
@@ -0,0 +1,39 @@
+package store
+
+import "testing"
+
+func TestCommentTree(t *testing.T) {
+	id := func(n int64) *int64 { return &n }
+
+	comments := []Comment{
+		{ID: 1},
+		{ID: 2, ParentID: id(1)},
+		{ID: 3},
+		{ID: 4, ParentID: id(2)},
+		{ID: 5, ParentID: id(1)},
+	}
+
+	tree := commentTree(comments, 0)
+
+	if len(tree) != 2 || tree[0].ID != 1 || tree[1].ID != 3 {
+		t.Fatalf("Expected top-level comments 1 and 3. Got %+v", tree)
+	}
+
+	replies := tree[0].Replies
+	if len(replies) != 2 || replies[0].ID != 2 || replies[1].ID != 5 {
+		t.Fatalf("Expected replies 2 and 5 to comment 1. Got %+v", replies)
+	}
+
+	if len(replies[0].Replies) != 1 || replies[0].Replies[0].ID != 4 {
+		t.Errorf("Expected reply 4 to comment 2. Got %+v", replies[0].Replies)
+	}
+
+	if tree[1].Replies == nil || len(tree[1].Replies) != 0 {
+		t.Errorf("Expected an empty reply list for comment 3. Got %+v", tree[1].Replies)
+	}
+
+	subtree := commentTree(comments, 2)
+	if len(subtree) != 1 || subtree[0].ID != 4 {
+		t.Errorf("Expected the subtree of comment 2 to hold reply 4. Got %+v", subtree)
+	}
+}
@@ -0,0 +1,65 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	SensitiveContentHide   = "hide"
+	SensitiveContentExpand = "expand"
+)
+
+// HasWarning reports whether the post carries a content warning or is
+// flagged as sensitive.
+func (p *Post) HasWarning() bool {
+	return p.ContentWarning != "" || p.Sensitive
+}
+
+// CollapsedFor reports whether the post should be hidden behind its warning
+// for viewer. Authors always see their own posts expanded.
+func (p *Post) CollapsedFor(viewer *User) bool {
+	return p.HasWarning() && p.UserID != viewer.ID &&
+		viewer.Preferences.SensitiveContent != SensitiveContentExpand
+}
+
+// SetContentWarning saves the content warning and sensitive flag of the post
+// if it is still at post.Version. When a moderator applies them to someone
+// else's post, entry records the action in the moderation log.
+func (s *PostStore) SetContentWarning(ctx context.Context, post *Post, entry *ModerationEntry) error {
+	query := `
+		UPDATE posts SET content_warning = $1, sensitive = $2, version = version + 1
+		WHERE id = $3 AND version = $4
+		RETURNING version
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx pgx.Tx) error {
+		if err := lockPost(ctx, tx, post.ID); err != nil {
+			return err
+		}
+
+		err := tx.QueryRow(ctx, query, post.ContentWarning, post.Sensitive, post.ID, post.Version).Scan(&post.Version)
+		if err != nil {
+			switch {
+			case errors.Is(err, pgx.ErrNoRows):
+				// The row is locked above, so it exists at another version.
+				return ErrVersionConflict
+			default:
+				return err
+			}
+		}
+
+		if entry == nil {
+			return nil
+		}
+
+		entry.PostID = &post.ID
+		entry.TargetUserID = post.UserID
+		return logModeration(ctx, tx, entry)
+	})
+}
@@ -0,0 +1,148 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Delete soft-deletes the post at version on behalf of deletedBy. Deleted
+// posts are left out of every read until they are restored or purged. The
+// post is also unpinned. ErrVersionConflict is returned when the post was
+// modified since it was loaded.
+func (s *PostStore) Delete(ctx context.Context, postID int64, version int, deletedBy int64) error {
+	query := `
+		UPDATE posts SET deleted_at = NOW(), deleted_by = $2, version = version + 1
+		WHERE id = $1 AND version = $3 AND deleted_at IS NULL
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx pgx.Tx) error {
+		var current int
+		err := tx.QueryRow(ctx, `SELECT version FROM posts WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, postID).Scan(&current)
+		if err != nil {
+			switch {
+			case errors.Is(err, pgx.ErrNoRows):
+				return ErrNotFound
+			default:
+				return err
+			}
+		}
+
+		result, err := tx.Exec(ctx, query, postID, deletedBy, version)
+		if err != nil {
+			return err
+		}
+
+		if result.RowsAffected() == 0 {
+			// The row is locked above, so it exists at another version.
+			return ErrVersionConflict
+		}
+
+		_, err = tx.Exec(ctx, `DELETE FROM pinned_posts WHERE post_id = $1`, postID)
+		return err
+	})
+}
+
+// GetDeletedByID loads a soft-deleted post, for instance to restore it.
+func (s *PostStore) GetDeletedByID(ctx context.Context, postID int64) (*Post, error) {
+	query := `
+		SELECT id, user_id, title, content, status, visibility, version, deleted_at, deleted_by
+		FROM posts
+		WHERE id = $1 AND deleted_at IS NOT NULL
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var post Post
+	err := s.db.QueryRow(ctx, query, postID).Scan(
+		&post.ID,
+		&post.UserID,
+		&post.Title,
+		&post.Content,
+		&post.Status,
+		&post.Visibility,
+		&post.Version,
+		&post.DeletedAt,
+		&post.DeletedBy,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &post, nil
+}
+
+// Restore undoes the soft deletion of the post.
+func (s *PostStore) Restore(ctx context.Context, postID int64) error {
+	query := `
+		UPDATE posts SET deleted_at = NULL, deleted_by = NULL, version = version + 1
+		WHERE id = $1 AND deleted_at IS NOT NULL
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.Exec(ctx, query, postID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// PurgeDeleted permanently deletes up to limit posts that were soft-deleted
+// more than retention ago, along with their comments. It returns the IDs of
+// the purged posts.
+func (s *PostStore) PurgeDeleted(ctx context.Context, retention time.Duration, limit int) ([]int64, error) {
+	query := `
+		SELECT id FROM posts
+		WHERE deleted_at < $1
+		ORDER BY deleted_at
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var ids []int64
+	err := withTx(s.db, ctx, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, query, time.Now().Add(-retention), limit)
+		if err != nil {
+			return err
+		}
+
+		ids, err = pgx.CollectRows(rows, pgx.RowTo[int64])
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+
+		// Comments do not cascade with their post.
+		if _, err := tx.Exec(ctx, `DELETE FROM comments WHERE post_id = ANY($1)`, ids); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `DELETE FROM posts WHERE id = ANY($1)`, ids)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,253 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+const (
+	EntityMention = "mention"
+	EntityHashtag = "hashtag"
+	EntityURL     = "url"
+
+	MaxTagLength   = 50
+	MaxTagsPerPost = 10
+)
+
+var ErrTooManyTags = fmt.Errorf("a post can have at most %d tags", MaxTagsPerPost)
+
+// Entity is a mention, hashtag or URL found in post or comment content. Offset and
+// Length are counted in UTF-16 code units so clients can slice the raw
+// content directly in JavaScript.
+type Entity struct {
+	Type   string `json:"type"`
+	Text   string `json:"text"`
+	Offset int    `json:"offset"`
+	Length int    `json:"length"`
+	UserID int64  `json:"user_id,omitempty"`
+}
+
+// ParseEntities extracts @username mentions, #hashtag and http(s) URL
+// entities from content. A sigil only starts an entity at the beginning of
+// the text or after a non-word character, so e-mail addresses are ignored,
+// and URLs are consumed whole so their fragments are not taken for hashtags.
+// Hashtag texts are normalized; mention and URL texts are kept verbatim.
+func ParseEntities(content string) []Entity {
+	entities := []Entity{}
+
+	var prev rune
+	offset := 0
+	for i := 0; i < len(content); {
+		r, size := utf8.DecodeRuneInString(content[i:])
+
+		if !isWordRune(prev) {
+			if end, ok := urlEnd(content[i:]); ok {
+				entity := Entity{
+					Type:   EntityURL,
+					Text:   content[i : i+end],
+					Offset: offset,
+					Length: utf16Len(content[i : i+end]),
+				}
+				entities = append(entities, entity)
+
+				offset += entity.Length
+				prev, _ = utf8.DecodeLastRuneInString(entity.Text)
+				i += end
+				continue
+			}
+		}
+
+		if (r == '@' || r == '#') && !isWordRune(prev) {
+			end := i + size
+			for end < len(content) {
+				next, nextSize := utf8.DecodeRuneInString(content[end:])
+				if !isWordRune(next) {
+					break
+				}
+				end += nextSize
+			}
+
+			word := content[i+size : end]
+			if entity, ok := newEntity(r, word); ok {
+				entity.Offset = offset
+				entity.Length = utf16Len(content[i:end])
+				entities = append(entities, entity)
+
+				offset += entity.Length
+				prev, _ = utf8.DecodeLastRuneInString(content[:end])
+				i = end
+				continue
+			}
+		}
+
+		offset += utf16.RuneLen(r)
+		prev = r
+		i += size
+	}
+
+	return entities
+}
+
+// urlEnd returns the length of the http(s) URL at the start of s, leaving
+// out trailing punctuation and an unbalanced closing parenthesis.
+func urlEnd(s string) (int, bool) {
+	lower := strings.ToLower(s[:min(len(s), len("https://"))])
+	if !strings.HasPrefix(lower, "http://") && !strings.HasPrefix(lower, "https://") {
+		return 0, false
+	}
+
+	end := strings.IndexFunc(s, unicode.IsSpace)
+	if end < 0 {
+		end = len(s)
+	}
+
+	for end > 0 {
+		last := s[end-1]
+		if strings.IndexByte(".,;:!?'\"", last) >= 0 ||
+			(last == ')' && strings.Count(s[:end], "(") < strings.Count(s[:end], ")")) {
+			end--
+			continue
+		}
+		break
+	}
+
+	u, err := url.Parse(s[:end])
+	if err != nil || u.Host == "" {
+		return 0, false
+	}
+
+	return end, true
+}
+
+// FirstURL returns the first URL entity of entities, if any.
+func FirstURL(entities []Entity) (string, bool) {
+	for _, entity := range entities {
+		if entity.Type == EntityURL {
+			return entity.Text, true
+		}
+	}
+
+	return "", false
+}
+
+func newEntity(sigil rune, word string) (Entity, bool) {
+	if word == "" {
+		return Entity{}, false
+	}
+
+	if sigil == '@' {
+		return Entity{Type: EntityMention, Text: word}, true
+	}
+
+	tag, err := NormalizeTag(word)
+	if err != nil || strings.IndexFunc(tag, unicode.IsLetter) < 0 {
+		return Entity{}, false
+	}
+
+	return Entity{Type: EntityHashtag, Text: tag}, true
+}
+
+// NormalizeTag lower-cases a tag, strips a leading '#' and checks its length
+// and charset.
+func NormalizeTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+
+	if tag == "" {
+		return "", errors.New("tags cannot be empty")
+	}
+
+	if utf8.RuneCountInString(tag) > MaxTagLength {
+		return "", fmt.Errorf("tag %q is longer than %d characters", tag, MaxTagLength)
+	}
+
+	if strings.IndexFunc(tag, func(r rune) bool { return !isWordRune(r) }) >= 0 {
+		return "", fmt.Errorf("tag %q may only contain letters, digits and underscores", tag)
+	}
+
+	return tag, nil
+}
+
+// PostTags merges the explicit tags of a post with the hashtags found in its
+// content, normalized and de-duplicated in order of appearance.
+func PostTags(tags []string, entities []Entity) ([]string, error) {
+	seen := make(map[string]bool)
+	merged := []string{}
+
+	add := func(tag string) {
+		if !seen[tag] {
+			seen[tag] = true
+			merged = append(merged, tag)
+		}
+	}
+
+	for _, tag := range tags {
+		normalized, err := NormalizeTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		add(normalized)
+	}
+
+	for _, entity := range entities {
+		if entity.Type == EntityHashtag {
+			add(entity.Text)
+		}
+	}
+
+	if len(merged) > MaxTagsPerPost {
+		return nil, ErrTooManyTags
+	}
+
+	return merged, nil
+}
+
+// ExplicitTags returns the tags of a post that were not derived from the
+// hashtags in its content.
+func ExplicitTags(tags []string, entities []Entity) []string {
+	hashtags := make(map[string]bool)
+	for _, entity := range entities {
+		if entity.Type == EntityHashtag {
+			hashtags[entity.Text] = true
+		}
+	}
+
+	explicit := []string{}
+	for _, tag := range tags {
+		if !hashtags[tag] {
+			explicit = append(explicit, tag)
+		}
+	}
+
+	return explicit
+}
+
+func mentionedUserIDs(entities []Entity) []int64 {
+	seen := make(map[int64]bool)
+	ids := []int64{}
+
+	for _, entity := range entities {
+		if entity.Type == EntityMention && entity.UserID != 0 && !seen[entity.UserID] {
+			seen[entity.UserID] = true
+			ids = append(ids, entity.UserID)
+		}
+	}
+
+	return ids
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n += utf16.RuneLen(r)
+	}
+	return n
+}
@@ -0,0 +1,69 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseEntities(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []Entity
+	}{
+		{
+			name:    "mentions and hashtags",
+			content: "hi @gopher, loving #GoLang",
+			want: []Entity{
+				{Type: EntityMention, Text: "gopher", Offset: 3, Length: 7},
+				{Type: EntityHashtag, Text: "golang", Offset: 19, Length: 7},
+			},
+		},
+		{
+			name:    "ignores e-mail addresses and numbers",
+			content: "mail me@example.com about issue #42",
+			want:    []Entity{},
+		},
+		{
+			name:    "urls are consumed whole",
+			content: "see https://go.dev/doc#faq. (https://en.wikipedia.org/wiki/Go_(language)) #go",
+			want: []Entity{
+				{Type: EntityURL, Text: "https://go.dev/doc#faq", Offset: 4, Length: 22},
+				{Type: EntityURL, Text: "https://en.wikipedia.org/wiki/Go_(language)", Offset: 29, Length: 43},
+				{Type: EntityHashtag, Text: "go", Offset: 74, Length: 3},
+			},
+		},
+		{
+			name:    "counts offsets in utf-16 code units",
+			content: "🎉 #party",
+			want: []Entity{
+				{Type: EntityHashtag, Text: "party", Offset: 3, Length: 6},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseEntities(tt.content)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %+v. Got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPostTags(t *testing.T) {
+	tags, err := PostTags([]string{"Go", "#news"}, ParseEntities("#go and #Gophers"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"go", "news", "gophers"}
+	if !reflect.DeepEqual(tags, want) {
+		t.Errorf("Expected %v. Got %v", want, tags)
+	}
+
+	if _, err := PostTags([]string{"not a tag"}, nil); err == nil {
+		t.Error("Expected an error for a tag with spaces")
+	}
+}
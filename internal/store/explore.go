@@ -0,0 +1,128 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type TrendingTag struct {
+	Tag         string  `json:"tag"`
+	RecentCount int     `json:"recent_count"`
+	WindowCount int     `json:"window_count"`
+	Velocity    float64 `json:"velocity"`
+}
+
+// TrendingQuery compares how often a tag was used during Recent with its
+// average rate over the longer Window.
+type TrendingQuery struct {
+	Recent    time.Duration
+	Window    time.Duration
+	MinRecent int
+	Limit     int
+}
+
+type ExploreStore struct {
+	db *pgxpool.Pool
+}
+
+// GetTrendingTags ranks the tags of public posts by velocity: the rate at
+// which a tag was used recently divided by its rate over the whole window.
+func (s *ExploreStore) GetTrendingTags(ctx context.Context, q TrendingQuery) ([]TrendingTag, error) {
+	query := `
+		SELECT tag,
+			count(*) FILTER (WHERE p.created_at >= $1) AS recent_count,
+			count(*) AS window_count
+		FROM posts p
+		CROSS JOIN LATERAL unnest(p.tags) AS tag
+		WHERE p.created_at >= $2 AND p.status = 'published' AND p.visibility = 'public' AND p.review_status = 'approved'
+		AND p.deleted_at IS NULL
+		GROUP BY tag
+		HAVING count(*) FILTER (WHERE p.created_at >= $1) >= $3
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	now := time.Now()
+	rows, err := s.db.Query(ctx, query, now.Add(-q.Recent), now.Add(-q.Window), q.MinRecent)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []TrendingTag{}
+	for rows.Next() {
+		var tag TrendingTag
+		if err := rows.Scan(&tag.Tag, &tag.RecentCount, &tag.WindowCount); err != nil {
+			return nil, err
+		}
+
+		tags = append(tags, tag)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return rankTrendingTags(tags, q), nil
+}
+
+// rankTrendingTags computes the velocity of each tag and keeps the q.Limit
+// fastest. Ties go to the tag used most recently, then alphabetically, so
+// the ranking is stable between refreshes.
+func rankTrendingTags(tags []TrendingTag, q TrendingQuery) []TrendingTag {
+	for i := range tags {
+		recentRate := float64(tags[i].RecentCount) / q.Recent.Hours()
+		windowRate := float64(tags[i].WindowCount) / q.Window.Hours()
+		tags[i].Velocity = recentRate / windowRate
+	}
+
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Velocity != tags[j].Velocity {
+			return tags[i].Velocity > tags[j].Velocity
+		}
+		if tags[i].RecentCount != tags[j].RecentCount {
+			return tags[i].RecentCount > tags[j].RecentCount
+		}
+		return tags[i].Tag < tags[j].Tag
+	})
+
+	if len(tags) > q.Limit {
+		tags = tags[:q.Limit]
+	}
+
+	return tags
+}
+
+// GetPopularPosts ranks public posts created within window by comment count,
+// decayed by age so that fresh discussions beat old ones.
+func (s *ExploreStore) GetPopularPosts(ctx context.Context, window time.Duration, limit int) ([]PostWithMetadata, error) {
+	query := `
+		SELECT
+			p.id, p.user_id, p.title, p.content, p.created_at, p.version, p.tags, p.entities, p.visibility, p.content_warning, p.sensitive, p.comment_policy, p.comments_locked,
+			` + postMedia + `, ` + postLinkPreview + `,
+			u.username,
+			count(c.id) AS comments_count
+		FROM posts p
+		JOIN users u ON u.id = p.user_id
+		LEFT JOIN comments c ON c.post_id = p.id AND c.review_status = 'approved'
+		WHERE p.created_at >= $1 AND p.status = 'published' AND p.visibility = 'public' AND p.review_status = 'approved'
+		AND p.deleted_at IS NULL
+		GROUP BY p.id, u.username
+		ORDER BY (count(c.id) + 1) / power(EXTRACT(EPOCH FROM NOW() - p.created_at) / 3600 + 2, 1.5) DESC, p.id DESC
+		LIMIT $2
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.Query(ctx, query, time.Now().Add(-window), limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanPostsWithMetadata(rows)
+}
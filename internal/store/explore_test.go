@@ -0,0 +1,38 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRankTrendingTags(t *testing.T) {
+	tags := []TrendingTag{
+		{Tag: "c", RecentCount: 1, WindowCount: 24},
+		{Tag: "zig", RecentCount: 3, WindowCount: 6},
+		{Tag: "java", RecentCount: 2, WindowCount: 48},
+		{Tag: "rust", RecentCount: 3, WindowCount: 6},
+		{Tag: "go", RecentCount: 6, WindowCount: 12},
+	}
+
+	ranked := rankTrendingTags(tags, TrendingQuery{Recent: time.Hour, Window: 24 * time.Hour, Limit: 4})
+
+	expected := []struct {
+		tag      string
+		velocity float64
+	}{
+		{"go", 12},
+		{"rust", 12},
+		{"zig", 12},
+		{"java", 1},
+	}
+
+	if len(ranked) != len(expected) {
+		t.Fatalf("Expected %d tags. Got %+v", len(expected), ranked)
+	}
+
+	for i, want := range expected {
+		if ranked[i].Tag != want.tag || ranked[i].Velocity != want.velocity {
+			t.Errorf("Expected %s with velocity %v at %d. Got %s with velocity %v", want.tag, want.velocity, i, ranked[i].Tag, ranked[i].Velocity)
+		}
+	}
+}
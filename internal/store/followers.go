@@ -2,7 +2,9 @@ package store
 
 import (
 	"context"
+	"errors"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -17,6 +19,7 @@ type FollowerStore struct {
 	db *pgxpool.Pool
 }
 
+// Follow makes userID follow followerID and counts the new follower.
 func (s *FollowerStore) Follow(ctx context.Context, userID, followerID int64) error {
 	query := `
 		INSERT INTO followers (user_id, follower_id)
@@ -26,16 +29,21 @@ func (s *FollowerStore) Follow(ctx context.Context, userID, followerID int64) er
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	_, err := s.db.Exec(ctx, query, userID, followerID)
-	if err != nil {
-		if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == "23505" {
-			return ErrConflict
+	return withTx(s.db, ctx, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, query, userID, followerID); err != nil {
+			if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == "23505" {
+				return ErrConflict
+			}
+			return err
 		}
-	}
 
-	return err
+		_, err := tx.Exec(ctx, `UPDATE users SET followers_count = followers_count + 1 WHERE id = $1`, followerID)
+		return err
+	})
 }
 
+// Unfollow makes userID stop following followerID and uncounts the
+// follower if there was one.
 func (s *FollowerStore) Unfollow(ctx context.Context, userID, followerID int64) error {
 	query := `
 		DELETE FROM followers
@@ -45,6 +53,91 @@ func (s *FollowerStore) Unfollow(ctx context.Context, userID, followerID int64)
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	_, err := s.db.Exec(ctx, query, userID, followerID)
-	return err
+	return withTx(s.db, ctx, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, query, userID, followerID)
+		if err != nil || tag.RowsAffected() == 0 {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `UPDATE users SET followers_count = followers_count - 1 WHERE id = $1`, followerID)
+		return err
+	})
+}
+
+func (s *FollowerStore) IsFollowing(ctx context.Context, followerID, userID int64) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM followers
+			WHERE user_id = $1 AND follower_id = $2
+		)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var follows bool
+	err := s.db.QueryRow(ctx, query, followerID, userID).Scan(&follows)
+	return follows, err
+}
+
+// CountFollowers returns how many users follow userID.
+func (s *FollowerStore) CountFollowers(ctx context.Context, userID int64) (int, error) {
+	query := `SELECT followers_count FROM users WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var count int
+	err := s.db.QueryRow(ctx, query, userID).Scan(&count)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return 0, ErrNotFound
+		default:
+			return 0, err
+		}
+	}
+
+	return count, nil
+}
+
+// GetFollowerIDs returns up to limit IDs of the users that follow userID,
+// in ascending order and starting after afterID.
+func (s *FollowerStore) GetFollowerIDs(ctx context.Context, userID, afterID int64, limit int) ([]int64, error) {
+	query := `
+		SELECT user_id FROM followers
+		WHERE follower_id = $1 AND user_id > $2
+		ORDER BY user_id
+		LIMIT $3
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.Query(ctx, query, userID, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, pgx.RowTo[int64])
+}
+
+// GetFolloweeIDs returns the IDs of the users followed by userID that have
+// at least minFollowers followers.
+func (s *FollowerStore) GetFolloweeIDs(ctx context.Context, userID int64, minFollowers int) ([]int64, error) {
+	query := `
+		SELECT f.follower_id FROM followers f
+		JOIN users u ON u.id = f.follower_id
+		WHERE f.user_id = $1 AND u.followers_count >= $2
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.Query(ctx, query, userID, minFollowers)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, pgx.RowTo[int64])
 }
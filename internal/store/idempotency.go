@@ -0,0 +1,115 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// IdempotencyRecord is the outcome of a request made with an idempotency
+// key. StatusCode is 0 while the request is still being processed.
+type IdempotencyRecord struct {
+	UserID      int64
+	Key         string
+	Fingerprint string
+	StatusCode  int
+	Header      map[string]string
+	Body        []byte
+	CreatedAt   time.Time
+}
+
+type IdempotencyStore struct {
+	db *pgxpool.Pool
+}
+
+// Reserve claims rec.Key for rec.UserID unless a request with that key was
+// made less than ttl ago, in which case that request's record is returned.
+// Expired keys are claimed again, and so are keys whose request has not
+// completed within lease, since the instance serving it may have crashed.
+func (s *IdempotencyStore) Reserve(ctx context.Context, rec *IdempotencyRecord, ttl, lease time.Duration) (*IdempotencyRecord, error) {
+	query := `
+		INSERT INTO idempotency_keys (user_id, key, fingerprint)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, key) DO UPDATE SET
+			fingerprint = EXCLUDED.fingerprint, status_code = NULL, header = '{}', body = NULL, created_at = NOW()
+		WHERE idempotency_keys.created_at < $4
+			OR (idempotency_keys.status_code IS NULL AND idempotency_keys.created_at < $5)
+		RETURNING created_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	now := time.Now()
+	err := s.db.QueryRow(ctx, query, rec.UserID, rec.Key, rec.Fingerprint, now.Add(-ttl), now.Add(-lease)).Scan(&rec.CreatedAt)
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+
+	query = `
+		SELECT fingerprint, COALESCE(status_code, 0), header, COALESCE(body, ''), created_at
+		FROM idempotency_keys
+		WHERE user_id = $1 AND key = $2
+	`
+
+	existing := &IdempotencyRecord{UserID: rec.UserID, Key: rec.Key}
+	err = s.db.QueryRow(ctx, query, rec.UserID, rec.Key).Scan(
+		&existing.Fingerprint,
+		&existing.StatusCode,
+		&existing.Header,
+		&existing.Body,
+		&existing.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return existing, nil
+}
+
+// Complete saves the response of a reserved request. Nothing is saved if
+// the request outlived its lease and the key was claimed again.
+func (s *IdempotencyStore) Complete(ctx context.Context, rec *IdempotencyRecord) error {
+	query := `
+		UPDATE idempotency_keys SET status_code = $3, header = $4, body = $5
+		WHERE user_id = $1 AND key = $2 AND created_at = $6
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.Exec(ctx, query, rec.UserID, rec.Key, rec.StatusCode, rec.Header, rec.Body, rec.CreatedAt)
+	return err
+}
+
+// Release frees a reserved key so the request can be retried.
+func (s *IdempotencyStore) Release(ctx context.Context, userID int64, key string) error {
+	query := `DELETE FROM idempotency_keys WHERE user_id = $1 AND key = $2 AND status_code IS NULL`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.Exec(ctx, query, userID, key)
+	return err
+}
+
+// DeleteExpired deletes the keys older than ttl.
+func (s *IdempotencyStore) DeleteExpired(ctx context.Context, ttl time.Duration) (int64, error) {
+	query := `DELETE FROM idempotency_keys WHERE created_at < $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.Exec(ctx, query, time.Now().Add(-ttl))
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected(), nil
+}
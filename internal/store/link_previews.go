@@ -0,0 +1,112 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	LinkPreviewOK     = "ok"
+	LinkPreviewFailed = "failed"
+
+	// maxLinkPreviewAttempts is how many times a failing URL is fetched
+	// before it is given up on.
+	maxLinkPreviewAttempts = 3
+)
+
+type LinkPreview struct {
+	URL         string `json:"url"`
+	Status      string `json:"-"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	ImageURL    string `json:"image_url"`
+	SiteName    string `json:"site_name"`
+}
+
+// postLinkPreview selects the cached preview of the first link of the post
+// aliased p as JSON, or NULL when there is none yet.
+const postLinkPreview = `
+	(SELECT jsonb_build_object(
+		'url', lp.url, 'title', lp.title, 'description', lp.description,
+		'image_url', lp.image_url, 'site_name', lp.site_name
+	) FROM link_previews lp WHERE lp.url = p.link_url AND lp.status = 'ok')`
+
+type LinkPreviewStore struct {
+	db *pgxpool.Pool
+}
+
+// GetPending lists up to limit URLs linked from posts that have no preview
+// yet, or whose last fetch failed more than retryAfter ago.
+func (s *LinkPreviewStore) GetPending(ctx context.Context, retryAfter time.Duration, limit int) ([]string, error) {
+	query := `
+		SELECT DISTINCT p.link_url
+		FROM posts p
+		LEFT JOIN link_previews lp ON lp.url = p.link_url
+		WHERE p.link_url IS NOT NULL AND p.deleted_at IS NULL
+		AND (
+			lp.url IS NULL
+			OR (lp.status = 'failed' AND lp.attempts < $1 AND lp.fetched_at < $2)
+		)
+		LIMIT $3
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.Query(ctx, query, maxLinkPreviewAttempts, time.Now().Add(-retryAfter), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	urls := []string{}
+	for rows.Next() {
+		var url string
+		if err := rows.Scan(&url); err != nil {
+			return nil, err
+		}
+		urls = append(urls, url)
+	}
+
+	return urls, rows.Err()
+}
+
+// Save caches the outcome of fetching preview.URL. A failure never replaces
+// a preview that was fetched successfully before.
+func (s *LinkPreviewStore) Save(ctx context.Context, preview *LinkPreview) error {
+	query := `
+		INSERT INTO link_previews (url, status, title, description, image_url, site_name)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (url) DO UPDATE
+		SET status = EXCLUDED.status, title = EXCLUDED.title, description = EXCLUDED.description,
+			image_url = EXCLUDED.image_url, site_name = EXCLUDED.site_name,
+			attempts = link_previews.attempts + 1, fetched_at = NOW()
+		WHERE link_previews.status = 'failed' OR EXCLUDED.status = 'ok'
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.Exec(
+		ctx,
+		query,
+		preview.URL,
+		preview.Status,
+		preview.Title,
+		preview.Description,
+		preview.ImageURL,
+		preview.SiteName,
+	)
+	return err
+}
+
+// linkURL is the URL of the post that gets a preview: its first link.
+func linkURL(entities []Entity) *string {
+	if url, ok := FirstURL(entities); ok {
+		return &url
+	}
+
+	return nil
+}
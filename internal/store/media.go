@@ -0,0 +1,211 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const MaxMediaPerPost = 4
+
+var ErrInvalidMedia = errors.New("media must be your own uploads that are not attached to another post")
+
+// Media is an uploaded image. It belongs to a post once attached; Position
+// orders the attachments of a post.
+type Media struct {
+	ID           int64     `json:"id"`
+	UserID       int64     `json:"-"`
+	PostID       *int64    `json:"-"`
+	Position     int       `json:"position"`
+	Key          string    `json:"-"`
+	ThumbnailKey string    `json:"-"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+	ContentType  string    `json:"content_type"`
+	Width        int       `json:"width"`
+	Height       int       `json:"height"`
+	Size         int64     `json:"size"`
+	AltText      string    `json:"alt_text"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// postMedia selects the attachments of the post aliased p as a JSON array,
+// decoded into Post.Media on scan.
+const postMedia = `
+	(SELECT COALESCE(jsonb_agg(jsonb_build_object(
+		'id', m.id, 'position', m.position, 'url', m.url, 'thumbnail_url', m.thumbnail_url,
+		'content_type', m.content_type, 'width', m.width, 'height', m.height, 'size', m.size,
+		'alt_text', m.alt_text, 'created_at', m.created_at
+	) ORDER BY m.position), '[]') FROM media m WHERE m.post_id = p.id)`
+
+type MediaStore struct {
+	db *pgxpool.Pool
+}
+
+func (s *MediaStore) Create(ctx context.Context, userID int64, media *Media) error {
+	query := `
+		INSERT INTO media (user_id, key, thumbnail_key, url, thumbnail_url, content_type, width, height, size)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id, created_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return s.db.QueryRow(
+		ctx,
+		query,
+		userID,
+		media.Key,
+		media.ThumbnailKey,
+		media.URL,
+		media.ThumbnailURL,
+		media.ContentType,
+		media.Width,
+		media.Height,
+		media.Size,
+	).Scan(&media.ID, &media.CreatedAt)
+}
+
+// GetByKey returns the upload that an image or thumbnail file belongs to.
+func (s *MediaStore) GetByKey(ctx context.Context, key string) (*Media, error) {
+	query := `
+		SELECT id, user_id, post_id, key, thumbnail_key, content_type
+		FROM media
+		WHERE key = $1 OR thumbnail_key = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var m Media
+	err := s.db.QueryRow(ctx, query, key).Scan(&m.ID, &m.UserID, &m.PostID, &m.Key, &m.ThumbnailKey, &m.ContentType)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &m, nil
+}
+
+// DeleteOrphans removes up to limit uploads that were never attached to a
+// post, or were detached from one, and are older than ttl. The deleted rows
+// are returned so their files can be removed from storage.
+func (s *MediaStore) DeleteOrphans(ctx context.Context, ttl time.Duration, limit int) ([]Media, error) {
+	query := `
+		DELETE FROM media
+		WHERE id IN (
+			SELECT id FROM media
+			WHERE post_id IS NULL AND created_at < $1
+			ORDER BY created_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, key, thumbnail_key
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.Query(ctx, query, time.Now().Add(-ttl), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orphans := []Media{}
+	for rows.Next() {
+		var m Media
+		if err := rows.Scan(&m.ID, &m.Key, &m.ThumbnailKey); err != nil {
+			return nil, err
+		}
+		orphans = append(orphans, m)
+	}
+
+	return orphans, rows.Err()
+}
+
+// attachMedia makes media, in order, the attachments of the post. Only the
+// ID and AltText of each item are read; the slice is filled in from the
+// stored rows. Attachments that are left out are detached and later cleaned
+// up as orphans.
+func attachMedia(ctx context.Context, tx pgx.Tx, post *Post) error {
+	if len(post.Media) > MaxMediaPerPost {
+		return fmt.Errorf("%w: a post can have at most %d attachments", ErrInvalidMedia, MaxMediaPerPost)
+	}
+
+	ids := make([]int64, len(post.Media))
+	altTexts := make([]string, len(post.Media))
+	for i, m := range post.Media {
+		ids[i] = m.ID
+		altTexts[i] = m.AltText
+	}
+
+	query := `UPDATE media SET post_id = NULL, position = NULL WHERE post_id = $1 AND id <> ALL($2::bigint[])`
+	if _, err := tx.Exec(ctx, query, post.ID, ids); err != nil {
+		return err
+	}
+
+	if len(ids) == 0 {
+		post.Media = []Media{}
+		return nil
+	}
+
+	query = `
+		UPDATE media m
+		SET post_id = $1, position = a.position - 1, alt_text = a.alt_text
+		FROM unnest($2::bigint[], $3::text[]) WITH ORDINALITY AS a(id, alt_text, position)
+		WHERE m.id = a.id AND m.user_id = $4 AND (m.post_id IS NULL OR m.post_id = $1)
+		RETURNING m.id, m.position, m.key, m.thumbnail_key, m.url, m.thumbnail_url,
+			m.content_type, m.width, m.height, m.size, m.alt_text, m.created_at
+	`
+
+	rows, err := tx.Query(ctx, query, post.ID, ids, altTexts, post.UserID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	attached := []Media{}
+	for rows.Next() {
+		var m Media
+		if err := rows.Scan(
+			&m.ID,
+			&m.Position,
+			&m.Key,
+			&m.ThumbnailKey,
+			&m.URL,
+			&m.ThumbnailURL,
+			&m.ContentType,
+			&m.Width,
+			&m.Height,
+			&m.Size,
+			&m.AltText,
+			&m.CreatedAt,
+		); err != nil {
+			return err
+		}
+		attached = append(attached, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if len(attached) != len(ids) {
+		return ErrInvalidMedia
+	}
+
+	slices.SortFunc(attached, func(a, b Media) int { return a.Position - b.Position })
+	post.Media = attached
+
+	return nil
+}
@@ -0,0 +1,146 @@
+package store
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// resolveMentions fills in the user ID of every mention entity whose
+// username belongs to an active user and drops the mentions that do not.
+func resolveMentions(ctx context.Context, tx pgx.Tx, entities []Entity) ([]Entity, error) {
+	var usernames []string
+	for _, entity := range entities {
+		if entity.Type == EntityMention {
+			usernames = append(usernames, entity.Text)
+		}
+	}
+
+	if len(usernames) == 0 {
+		if entities == nil {
+			entities = []Entity{}
+		}
+		return entities, nil
+	}
+
+	query := `
+		SELECT id, username FROM users
+		WHERE username = ANY($1) AND is_active = true
+	`
+
+	rows, err := tx.Query(ctx, query, usernames)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make(map[string]int64)
+	for rows.Next() {
+		var id int64
+		var username string
+		if err := rows.Scan(&id, &username); err != nil {
+			return nil, err
+		}
+		ids[username] = id
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	resolved := []Entity{}
+	for _, entity := range entities {
+		if entity.Type == EntityMention {
+			id, ok := ids[entity.Text]
+			if !ok {
+				continue
+			}
+			entity.UserID = id
+		}
+		resolved = append(resolved, entity)
+	}
+
+	return resolved, nil
+}
+
+func replacePostTags(ctx context.Context, tx pgx.Tx, postID int64, tags []string) error {
+	if tags == nil {
+		tags = []string{}
+	}
+
+	query := `DELETE FROM post_tags WHERE post_id = $1 AND tag <> ALL($2::text[])`
+	if _, err := tx.Exec(ctx, query, postID, tags); err != nil {
+		return err
+	}
+
+	query = `
+		INSERT INTO post_tags (post_id, tag)
+		SELECT $1, unnest($2::text[])
+		ON CONFLICT DO NOTHING
+	`
+	_, err := tx.Exec(ctx, query, postID, tags)
+	return err
+}
+
+// replaceMentions makes the mention edges of a post (or of one of its
+// comments when commentID is set) match entities, and returns the IDs of
+// the users that were not mentioned before.
+func replaceMentions(ctx context.Context, tx pgx.Tx, postID int64, commentID *int64, authorID int64, entities []Entity) ([]int64, error) {
+	userIDs := mentionedUserIDs(entities)
+
+	query := `
+		DELETE FROM mentions
+		WHERE post_id = $1 AND comment_id IS NOT DISTINCT FROM $2::bigint AND user_id <> ALL($3::bigint[])
+	`
+	if _, err := tx.Exec(ctx, query, postID, commentID, userIDs); err != nil {
+		return nil, err
+	}
+
+	query = `
+		INSERT INTO mentions (user_id, author_id, post_id, comment_id)
+		SELECT unnest($3::bigint[]), $4, $1, $2::bigint
+		ON CONFLICT DO NOTHING
+		RETURNING user_id
+	`
+
+	rows, err := tx.Query(ctx, query, postID, commentID, userIDs, authorID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	added := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		added = append(added, id)
+	}
+
+	return added, rows.Err()
+}
+
+// notifyMentions creates a mention notification for every mention edge of
+// postIDs that targets one of userIDs (or all of them when userIDs is nil),
+// skipping self-mentions and users who cannot see the post.
+func notifyMentions(ctx context.Context, tx pgx.Tx, postIDs []int64, commentID *int64, userIDs []int64) error {
+	if len(postIDs) == 0 || (userIDs != nil && len(userIDs) == 0) {
+		return nil
+	}
+
+	query := `
+		INSERT INTO notifications (user_id, actor_id, type, post_id, comment_id)
+		SELECT m.user_id, m.author_id, $4, m.post_id, m.comment_id
+		FROM mentions m
+		JOIN posts p ON p.id = m.post_id
+		WHERE m.post_id = ANY($1)
+		AND m.comment_id IS NOT DISTINCT FROM $2::bigint
+		AND ($3::bigint[] IS NULL OR m.user_id = ANY($3::bigint[]))
+		AND m.user_id <> m.author_id
+		AND p.status = 'published' AND p.review_status = 'approved' AND p.deleted_at IS NULL
+		AND ` + visibleTo("m.user_id")
+
+	_, err := tx.Exec(ctx, query, postIDs, commentID, userIDs, NotificationMention)
+	return err
+}
@@ -2,14 +2,29 @@ package store
 
 import (
 	"context"
+	"sync"
 	"time"
 
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/spam"
 	"github.com/jackc/pgx/v5"
 )
 
 func NewMockStore() Storage {
 	return Storage{
-		Users: &MockUserStore{},
+		Users:         &MockUserStore{},
+		Posts:         &MockPostStore{},
+		Comments:      &MockCommentStore{},
+		Followers:     &MockFollowerStore{},
+		Roles:         &MockRoleStore{},
+		Polls:         &MockPollStore{},
+		Media:         &MockMediaStore{},
+		LinkPreviews:  &MockLinkPreviewStore{},
+		Explore:       &MockExploreStore{},
+		Notifications: &MockNotificationStore{},
+		Stats:         &MockStatsStore{},
+		Idempotency:   &MockIdempotencyStore{},
+		Spam:          &MockSpamStore{},
+		Timelines:     &MockTimelineStore{},
 	}
 }
 
@@ -38,3 +53,326 @@ func (m *MockUserStore) Activate(context.Context, string) error {
 func (m *MockUserStore) Delete(context.Context, int64) error {
 	return nil
 }
+
+func (m *MockUserStore) UpdatePreferences(context.Context, int64, *UserPreferences) error {
+	return nil
+}
+
+// MockPostStore returns a public, published post of user 0 from GetByID
+// unless it is given another post to return.
+type MockPostStore struct {
+	mu    sync.Mutex
+	post  *Post
+	loads int
+}
+
+// NewMockPostStore returns a MockPostStore whose GetByID returns post.
+func NewMockPostStore(post Post) *MockPostStore {
+	m := &MockPostStore{}
+	m.SetPost(post)
+	return m
+}
+
+// SetPost makes GetByID return copies of post with the requested ID. It is
+// safe to call while requests are being served.
+func (m *MockPostStore) SetPost(post Post) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.post = &post
+}
+
+// Loads counts the calls to GetByID.
+func (m *MockPostStore) Loads() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.loads
+}
+
+func (m *MockPostStore) Create(context.Context, *Post) error {
+	return nil
+}
+
+func (m *MockPostStore) GetByID(_ context.Context, id int64) (*Post, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.loads++
+	if m.post == nil {
+		return &Post{ID: id, Status: PostStatusPublished, Visibility: PostVisibilityPublic, Version: 1}, nil
+	}
+
+	post := *m.post
+	post.ID = id
+	return &post, nil
+}
+
+func (m *MockPostStore) Delete(context.Context, int64, int, int64) error {
+	return nil
+}
+
+func (m *MockPostStore) GetDeletedByID(_ context.Context, id int64) (*Post, error) {
+	deletedAt := time.Now()
+	deletedBy := int64(0)
+	return &Post{ID: id, Status: PostStatusPublished, Version: 2, DeletedAt: &deletedAt, DeletedBy: &deletedBy}, nil
+}
+
+func (m *MockPostStore) SetContentWarning(context.Context, *Post, *ModerationEntry) error {
+	return nil
+}
+
+func (m *MockPostStore) SetCommentsLocked(context.Context, *Post, *ModerationEntry) error {
+	return nil
+}
+
+func (m *MockPostStore) Restore(context.Context, int64) error {
+	return nil
+}
+
+func (m *MockPostStore) PurgeDeleted(context.Context, time.Duration, int) ([]int64, error) {
+	return []int64{}, nil
+}
+
+func (m *MockPostStore) Update(_ context.Context, post *Post) error {
+	post.Version++
+	return nil
+}
+
+func (m *MockPostStore) GetUserFeed(context.Context, int64, *PaginatedFeedQuery) ([]PostWithMetadata, error) {
+	return []PostWithMetadata{}, nil
+}
+
+func (m *MockPostStore) GetRankedFeed(context.Context, int64, time.Time, FeedRanking, *PaginatedFeedQuery) ([]PostWithMetadata, error) {
+	return []PostWithMetadata{}, nil
+}
+
+func (m *MockPostStore) GetByUserID(context.Context, int64, int64, *PaginatedFeedQuery) ([]PostWithMetadata, error) {
+	return []PostWithMetadata{}, nil
+}
+
+func (m *MockPostStore) GetPinnedByUserID(context.Context, int64, int64, *PaginatedFeedQuery) ([]PostWithMetadata, error) {
+	return []PostWithMetadata{}, nil
+}
+
+func (m *MockPostStore) Pin(context.Context, int64, int64, int, int) error {
+	return nil
+}
+
+func (m *MockPostStore) Unpin(context.Context, int64, int64) error {
+	return nil
+}
+
+func (m *MockPostStore) GetDraftsByUserID(context.Context, int64) ([]Post, error) {
+	return []Post{}, nil
+}
+
+func (m *MockPostStore) PublishDue(context.Context, int) ([]int64, error) {
+	return nil, nil
+}
+
+func (m *MockPostStore) GetTimelineEntries(context.Context, []int64, int) ([]TimelineEntry, error) {
+	return nil, nil
+}
+
+func (m *MockPostStore) GetFeedByIDs(context.Context, int64, []int64) ([]PostWithMetadata, error) {
+	return []PostWithMetadata{}, nil
+}
+
+func (m *MockPostStore) GetByAuthorIDs(context.Context, int64, []int64, *PaginatedFeedQuery) ([]PostWithMetadata, error) {
+	return []PostWithMetadata{}, nil
+}
+
+type MockCommentStore struct{}
+
+func (m *MockCommentStore) Create(context.Context, *Comment) error {
+	return nil
+}
+
+func (m *MockCommentStore) GetPage(context.Context, int64, int64, *PaginatedCommentQuery) ([]Comment, error) {
+	return []Comment{}, nil
+}
+
+func (m *MockCommentStore) CountByPostID(context.Context, int64, int64) (int, error) {
+	return 0, nil
+}
+
+func (m *MockCommentStore) GetByID(_ context.Context, postID, commentID int64) (*Comment, error) {
+	return &Comment{ID: commentID, PostID: postID, Replies: []Comment{}}, nil
+}
+
+func (m *MockCommentStore) Update(context.Context, *Comment, *ModerationEntry) error {
+	return nil
+}
+
+func (m *MockCommentStore) Delete(context.Context, *Comment, *ModerationEntry) error {
+	return nil
+}
+
+func (m *MockCommentStore) GetSubtree(_ context.Context, postID, commentID, _ int64) (*Comment, error) {
+	return &Comment{ID: commentID, PostID: postID, Replies: []Comment{}}, nil
+}
+
+type MockFollowerStore struct{}
+
+func (m *MockFollowerStore) Follow(context.Context, int64, int64) error {
+	return nil
+}
+
+func (m *MockFollowerStore) Unfollow(context.Context, int64, int64) error {
+	return nil
+}
+
+func (m *MockFollowerStore) IsFollowing(context.Context, int64, int64) (bool, error) {
+	return false, nil
+}
+
+func (m *MockFollowerStore) CountFollowers(context.Context, int64) (int, error) {
+	return 0, nil
+}
+
+func (m *MockFollowerStore) GetFollowerIDs(context.Context, int64, int64, int) ([]int64, error) {
+	return nil, nil
+}
+
+func (m *MockFollowerStore) GetFolloweeIDs(context.Context, int64, int) ([]int64, error) {
+	return nil, nil
+}
+
+type MockRoleStore struct{}
+
+func (m *MockRoleStore) GetByName(_ context.Context, name string) (*Role, error) {
+	levels := map[string]int{"user": 1, "moderator": 2, "admin": 3}
+	return &Role{Name: name, Level: levels[name]}, nil
+}
+
+type MockNotificationStore struct{}
+
+func (m *MockNotificationStore) GetByUserID(context.Context, int64, int) ([]Notification, error) {
+	return []Notification{}, nil
+}
+
+func (m *MockNotificationStore) MarkRead(context.Context, int64, int64) error {
+	return nil
+}
+
+type MockExploreStore struct{}
+
+func (m *MockExploreStore) GetTrendingTags(context.Context, TrendingQuery) ([]TrendingTag, error) {
+	return []TrendingTag{}, nil
+}
+
+func (m *MockExploreStore) GetPopularPosts(context.Context, time.Duration, int) ([]PostWithMetadata, error) {
+	return []PostWithMetadata{}, nil
+}
+
+type MockPollStore struct{}
+
+func (m *MockPollStore) GetByPostID(context.Context, int64, int64) (*Poll, error) {
+	return nil, ErrNotFound
+}
+
+func (m *MockPollStore) Vote(context.Context, int64, int64, []int64) error {
+	return nil
+}
+
+type MockMediaStore struct{}
+
+func (m *MockMediaStore) Create(_ context.Context, _ int64, media *Media) error {
+	media.ID = 1
+	return nil
+}
+
+func (m *MockMediaStore) GetByKey(_ context.Context, key string) (*Media, error) {
+	return &Media{ID: 1, Key: key}, nil
+}
+
+func (m *MockMediaStore) DeleteOrphans(context.Context, time.Duration, int) ([]Media, error) {
+	return []Media{}, nil
+}
+
+type MockLinkPreviewStore struct{}
+
+func (m *MockLinkPreviewStore) GetPending(context.Context, time.Duration, int) ([]string, error) {
+	return []string{}, nil
+}
+
+func (m *MockLinkPreviewStore) Save(context.Context, *LinkPreview) error {
+	return nil
+}
+
+type MockStatsStore struct{}
+
+func (m *MockStatsStore) RecordViews(context.Context, []PostView, time.Duration) error {
+	return nil
+}
+
+func (m *MockStatsStore) PruneViewDedup(context.Context, time.Duration) (int64, error) {
+	return 0, nil
+}
+
+func (m *MockStatsStore) GetPostStats(_ context.Context, postID int64, _ time.Time) (*PostStats, error) {
+	return &PostStats{PostID: postID, Hourly: []StatsBucket{}}, nil
+}
+
+func (m *MockStatsStore) GetUserStats(context.Context, int64, time.Time, int) (*UserStats, error) {
+	return &UserStats{Hourly: []StatsBucket{}, TopPosts: []PostStatsSummary{}}, nil
+}
+
+type MockIdempotencyStore struct{}
+
+func (m *MockIdempotencyStore) Reserve(context.Context, *IdempotencyRecord, time.Duration, time.Duration) (*IdempotencyRecord, error) {
+	return nil, nil
+}
+
+func (m *MockIdempotencyStore) Complete(context.Context, *IdempotencyRecord) error {
+	return nil
+}
+
+func (m *MockIdempotencyStore) Release(context.Context, int64, string) error {
+	return nil
+}
+
+func (m *MockIdempotencyStore) DeleteExpired(context.Context, time.Duration) (int64, error) {
+	return 0, nil
+}
+
+type MockSpamStore struct{}
+
+func (m *MockSpamStore) CountDuplicates(context.Context, int64, string, time.Time, spam.Edit) (int, error) {
+	return 0, nil
+}
+
+func (m *MockSpamStore) CountRecent(context.Context, int64, time.Time, spam.Edit) (int, error) {
+	return 0, nil
+}
+
+func (m *MockSpamStore) GetHeld(context.Context, string, int) ([]HeldContent, error) {
+	return []HeldContent{}, nil
+}
+
+func (m *MockSpamStore) Review(context.Context, string, int64, string, *ModerationEntry) error {
+	return nil
+}
+
+type MockTimelineStore struct{}
+
+func (m *MockTimelineStore) Add(context.Context, TimelineEntry, []int64) error {
+	return nil
+}
+
+func (m *MockTimelineStore) Backfill(context.Context, int64, []TimelineEntry) error {
+	return nil
+}
+
+func (m *MockTimelineStore) Remove(context.Context, []TimelineEntry, []int64) error {
+	return nil
+}
+
+func (m *MockTimelineStore) Page(context.Context, int64, *Cursor, int) ([]TimelineEntry, error) {
+	return []TimelineEntry{}, nil
+}
+
+func (m *MockTimelineStore) Trim(context.Context, int) (int64, error) {
+	return 0, nil
+}
@@ -0,0 +1,53 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	ModerationContentWarning = "content_warning"
+	ModerationCommentEdit    = "comment_edit"
+	ModerationCommentDelete  = "comment_delete"
+	ModerationCommentsLock   = "comments_lock"
+	ModerationCommentsUnlock = "comments_unlock"
+	ModerationSpamApprove    = "spam_approve"
+	ModerationSpamHide       = "spam_hide"
+)
+
+// ModerationEntry records an action a moderator took on someone else's
+// content.
+type ModerationEntry struct {
+	ID           int64          `json:"id"`
+	ModeratorID  int64          `json:"moderator_id"`
+	Action       string         `json:"action"`
+	TargetUserID int64          `json:"target_user_id"`
+	PostID       *int64         `json:"post_id"`
+	Reason       string         `json:"reason"`
+	Details      map[string]any `json:"details"`
+	CreatedAt    time.Time      `json:"created_at"`
+}
+
+func logModeration(ctx context.Context, tx pgx.Tx, entry *ModerationEntry) error {
+	query := `
+		INSERT INTO moderation_log (moderator_id, action, target_user_id, post_id, reason, details)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at
+	`
+
+	if entry.Details == nil {
+		entry.Details = map[string]any{}
+	}
+
+	return tx.QueryRow(
+		ctx,
+		query,
+		entry.ModeratorID,
+		entry.Action,
+		entry.TargetUserID,
+		entry.PostID,
+		entry.Reason,
+		entry.Details,
+	).Scan(&entry.ID, &entry.CreatedAt)
+}
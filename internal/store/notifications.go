@@ -0,0 +1,85 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const NotificationMention = "mention"
+
+type Notification struct {
+	ID        int64      `json:"id"`
+	UserID    int64      `json:"user_id"`
+	ActorID   int64      `json:"actor_id"`
+	Type      string     `json:"type"`
+	PostID    *int64     `json:"post_id"`
+	CommentID *int64     `json:"comment_id"`
+	ReadAt    *time.Time `json:"read_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	Actor     User       `json:"actor"`
+}
+
+type NotificationStore struct {
+	db *pgxpool.Pool
+}
+
+func (s *NotificationStore) GetByUserID(ctx context.Context, userID int64, limit int) ([]Notification, error) {
+	query := `
+		SELECT n.id, n.user_id, n.actor_id, n.type, n.post_id, n.comment_id, n.read_at, n.created_at,
+			u.id, u.username
+		FROM notifications n
+		JOIN users u ON u.id = n.actor_id
+		LEFT JOIN posts p ON p.id = n.post_id
+		WHERE n.user_id = $1 AND p.deleted_at IS NULL
+		ORDER BY n.created_at DESC, n.id DESC
+		LIMIT $2
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.Query(ctx, query, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := []Notification{}
+	for rows.Next() {
+		var n Notification
+		if err := rows.Scan(
+			&n.ID,
+			&n.UserID,
+			&n.ActorID,
+			&n.Type,
+			&n.PostID,
+			&n.CommentID,
+			&n.ReadAt,
+			&n.CreatedAt,
+			&n.Actor.ID,
+			&n.Actor.Username,
+		); err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, n)
+	}
+
+	return notifications, rows.Err()
+}
+
+// MarkRead marks the unread notifications of userID up to and including
+// upTo as read.
+func (s *NotificationStore) MarkRead(ctx context.Context, userID, upTo int64) error {
+	query := `
+		UPDATE notifications SET read_at = NOW()
+		WHERE user_id = $1 AND id <= $2 AND read_at IS NULL
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.Exec(ctx, query, userID, upTo)
+	return err
+}
@@ -1,20 +1,72 @@
 package store
 
 import (
+	"encoding/base64"
+	"errors"
+	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 )
 
+var ErrInvalidCursor = errors.New("invalid cursor")
+
 type PaginatedFeedQuery struct {
-	Limit  int       `json:"limit" validate:"gte=1,lte=20"`
-	Offset int       `json:"offset" validate:"gte=0"`
-	Sort   string    `json:"sort" validate:"oneof=asc desc"`
-	Tags   []string  `json:"tags" validate:"max=5"`
-	Search string    `json:"search" validate:"max=100"`
-	Since  time.Time `json:"since"`
-	Until  time.Time `json:"until"`
+	Limit  int           `json:"limit" validate:"gte=1,lte=20"`
+	Offset int           `json:"offset" validate:"gte=0"`
+	Sort   string        `json:"sort" validate:"oneof=asc desc"`
+	Tags   []string      `json:"tags" validate:"max=5"`
+	Search string        `json:"search" validate:"max=100"`
+	Since  time.Time     `json:"since"`
+	Until  time.Time     `json:"until"`
+	Cursor *Cursor       `json:"cursor"`
+	Mode   string        `json:"mode" validate:"omitempty,oneof=chronological ranked"`
+	Ranked *RankedCursor `json:"-"`
+}
+
+// Cursor is a keyset position in a list ordered by (created_at, id). It is
+// handed to clients as an opaque token. A cursor with Prev set fetches the
+// page before the position rather than the one after it.
+type Cursor struct {
+	CreatedAt time.Time
+	ID        int64
+	Prev      bool
+}
+
+func (c Cursor) Encode() string {
+	raw := fmt.Sprintf("%d:%d", c.CreatedAt.UnixNano(), c.ID)
+	if c.Prev {
+		raw += ":prev"
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeCursor(token string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	position, prev := strings.CutSuffix(string(raw), ":prev")
+
+	nanos, id, ok := strings.Cut(position, ":")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	i, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &Cursor{CreatedAt: time.Unix(0, n), ID: i, Prev: prev}, nil
 }
 
 func (p *PaginatedFeedQuery) Parse(r *http.Request) (*PaginatedFeedQuery, error) {
@@ -48,6 +100,13 @@ func (p *PaginatedFeedQuery) Parse(r *http.Request) (*PaginatedFeedQuery, error)
 	tags := q.Get("tags")
 	if tags != "" {
 		p.Tags = strings.Split(tags, ",")
+		for i, tag := range p.Tags {
+			normalized, err := NormalizeTag(tag)
+			if err != nil {
+				return nil, err
+			}
+			p.Tags[i] = normalized
+		}
 	}
 
 	search := q.Get("search")
@@ -75,9 +134,144 @@ func (p *PaginatedFeedQuery) Parse(r *http.Request) (*PaginatedFeedQuery, error)
 		p.Until = until
 	}
 
+	mode := q.Get("mode")
+	if mode != "" {
+		p.Mode = mode
+	}
+
+	cursor := q.Get("cursor")
+	if cursor != "" && p.Mode == FeedModeRanked {
+		c, err := DecodeRankedCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+
+		p.Ranked = c
+	} else if cursor != "" {
+		c, err := DecodeCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+
+		p.Cursor = c
+	}
+
+	if (p.Cursor != nil || p.Ranked != nil) && p.Offset > 0 {
+		return nil, errors.New("offset cannot be combined with cursor")
+	}
+
+	if p.Mode == FeedModeRanked && (p.Offset > 0 || p.Sort == "asc") {
+		return nil, errors.New("the ranked feed cannot be sorted or paged by offset")
+	}
+
 	return p, nil
 }
 
+// filters appends the search, tag and date filters of p to args and returns
+// the matching SQL conditions over posts aliased as p.
+func (p *PaginatedFeedQuery) filters(args []any) ([]string, []any) {
+	var conditions []string
+
+	// Filter Search
+	if p.Search != "" {
+		args = append(args, p.Search)
+		conditions = append(conditions, fmt.Sprintf(
+			"to_tsvector('english', p.title || ' ' || p.content) @@ plainto_tsquery('english', $%d::text)",
+			len(args)))
+	}
+
+	// Filter Tags
+	if len(p.Tags) > 0 {
+		args = append(args, p.Tags)
+		conditions = append(conditions, fmt.Sprintf("p.tags @> $%d", len(args)))
+	}
+
+	// Filter Since
+	if !p.Since.IsZero() {
+		args = append(args, p.Since)
+		conditions = append(conditions, fmt.Sprintf("p.created_at >= $%d", len(args)))
+	}
+
+	// Filter Until
+	if !p.Until.IsZero() {
+		args = append(args, p.Until)
+		conditions = append(conditions, fmt.Sprintf("p.created_at <= $%d", len(args)))
+	}
+
+	return conditions, args
+}
+
+// order returns the direction to sort (created_at, id) in for the query.
+// Pages before a cursor are fetched in reverse and flipped back by
+// pageOrder.
+func (p *PaginatedFeedQuery) order() string {
+	backward := p.Cursor != nil && p.Cursor.Prev
+	if (p.Sort == "asc") != backward {
+		return "ASC"
+	}
+	return "DESC"
+}
+
+// pageOrder puts a page fetched in the order of p.order back in the order
+// of p.Sort.
+func (p *PaginatedFeedQuery) pageOrder(page []PostWithMetadata) []PostWithMetadata {
+	if p.Cursor != nil && p.Cursor.Prev {
+		slices.Reverse(page)
+	}
+	return page
+}
+
+// cursorCondition restricts a (created_at, id) ordered query over posts
+// aliased as p to the rows after p.Cursor in the direction of p.order.
+func (p *PaginatedFeedQuery) cursorCondition(conditions []string, args []any) ([]string, []any) {
+	if p.Cursor == nil {
+		return conditions, args
+	}
+
+	op := "<"
+	if p.order() == "ASC" {
+		op = ">"
+	}
+
+	args = append(args, p.Cursor.CreatedAt, p.Cursor.ID)
+	conditions = append(conditions, fmt.Sprintf("(p.created_at, p.id) %s ($%d, $%d)", op, len(args)-1, len(args)))
+
+	return conditions, args
+}
+
+// NextCursor returns the cursor that continues after the last of page, or
+// nil when the page was not full and there is nothing more to fetch.
+func (p *PaginatedFeedQuery) NextCursor(page []PostWithMetadata) *Cursor {
+	if p.Cursor != nil && p.Cursor.Prev {
+		// A page before a cursor is always followed by what the cursor
+		// came from.
+		if len(page) == 0 {
+			return &Cursor{CreatedAt: p.Cursor.CreatedAt, ID: p.Cursor.ID}
+		}
+	} else if len(page) == 0 || len(page) < p.Limit {
+		return nil
+	}
+
+	last := page[len(page)-1]
+	return &Cursor{CreatedAt: last.CreatedAt, ID: last.ID}
+}
+
+// PrevCursor returns the cursor that goes back before the first of page, or
+// nil on the first page.
+func (p *PaginatedFeedQuery) PrevCursor(page []PostWithMetadata) *Cursor {
+	switch {
+	case p.Cursor == nil:
+		return nil
+	case p.Cursor.Prev && len(page) < p.Limit:
+		return nil
+	case len(page) == 0:
+		return &Cursor{CreatedAt: p.Cursor.CreatedAt, ID: p.Cursor.ID, Prev: true}
+	}
+
+	first := page[0]
+	return &Cursor{CreatedAt: first.CreatedAt, ID: first.ID, Prev: true}
+}
+
 func parseTime(s string) (time.Time, error) {
 	layout := "2006-01-02"
 	t, err := time.Parse(layout, s)
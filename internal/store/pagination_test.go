@@ -0,0 +1,147 @@
+package store
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCursor(t *testing.T) {
+	for _, cursor := range []Cursor{
+		{CreatedAt: time.Unix(0, 1700000000123456789), ID: 7},
+		{CreatedAt: time.Unix(0, 1700000000123456789), ID: 7, Prev: true},
+	} {
+		decoded, err := DecodeCursor(cursor.Encode())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !decoded.CreatedAt.Equal(cursor.CreatedAt) || decoded.ID != cursor.ID || decoded.Prev != cursor.Prev {
+			t.Errorf("Expected %+v. Got %+v", cursor, *decoded)
+		}
+	}
+
+	if _, err := DecodeCursor("!!"); err != ErrInvalidCursor {
+		t.Errorf("Expected ErrInvalidCursor. Got %v", err)
+	}
+}
+
+func TestFeedPageCursors(t *testing.T) {
+	now := time.Now()
+	page := []PostWithMetadata{
+		{Post: Post{ID: 3, CreatedAt: now}},
+		{Post: Post{ID: 2, CreatedAt: now.Add(-time.Minute)}},
+	}
+	at := &Cursor{CreatedAt: now.Add(time.Minute), ID: 4}
+
+	tests := []struct {
+		name     string
+		cursor   *Cursor
+		page     []PostWithMetadata
+		wantNext *int64
+		wantPrev *int64
+	}{
+		{name: "first full page", page: page, wantNext: ptr(int64(2))},
+		{name: "first partial page", page: page[:1]},
+		{name: "page after a cursor", cursor: at, page: page, wantNext: ptr(int64(2)), wantPrev: ptr(int64(3))},
+		{name: "empty page after a cursor", cursor: at, wantPrev: ptr(int64(4))},
+		{name: "full page before a cursor", cursor: &Cursor{CreatedAt: at.CreatedAt, ID: 4, Prev: true}, page: page, wantNext: ptr(int64(2)), wantPrev: ptr(int64(3))},
+		{name: "partial page before a cursor", cursor: &Cursor{CreatedAt: at.CreatedAt, ID: 4, Prev: true}, page: page[:1], wantNext: ptr(int64(3))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := &PaginatedFeedQuery{Limit: 2, Sort: "desc", Cursor: tt.cursor}
+
+			next, prev := q.NextCursor(tt.page), q.PrevCursor(tt.page)
+			checkCursor(t, "next", next, tt.wantNext, false)
+			checkCursor(t, "prev", prev, tt.wantPrev, true)
+		})
+	}
+}
+
+func TestFeedOrder(t *testing.T) {
+	q := &PaginatedFeedQuery{Sort: "desc"}
+	if q.order() != "DESC" {
+		t.Errorf("Expected DESC. Got %s", q.order())
+	}
+
+	q.Cursor = &Cursor{Prev: true}
+	if q.order() != "ASC" {
+		t.Errorf("Expected a page before a cursor to be fetched in reverse. Got %s", q.order())
+	}
+
+	page := q.pageOrder([]PostWithMetadata{{Post: Post{ID: 1}}, {Post: Post{ID: 2}}})
+	if page[0].ID != 2 {
+		t.Errorf("Expected the page to be flipped back. Got %d first", page[0].ID)
+	}
+}
+
+func checkCursor(t *testing.T, name string, got *Cursor, wantID *int64, wantPrev bool) {
+	t.Helper()
+
+	switch {
+	case wantID == nil && got != nil:
+		t.Errorf("Expected no %s cursor. Got %+v", name, *got)
+	case wantID != nil && got == nil:
+		t.Errorf("Expected a %s cursor at %d. Got none", name, *wantID)
+	case wantID != nil && (got.ID != *wantID || got.Prev != wantPrev):
+		t.Errorf("Expected a %s cursor at %d. Got %+v", name, *wantID, *got)
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestMergePages(t *testing.T) {
+	now := time.Now()
+	post := func(id int64, age time.Duration) PostWithMetadata {
+		return PostWithMetadata{Post: Post{ID: id, CreatedAt: now.Add(-age)}}
+	}
+
+	timeline := []PostWithMetadata{post(5, 0), post(3, 2*time.Minute), post(1, 4*time.Minute)}
+	large := []PostWithMetadata{post(4, time.Minute), post(3, 2*time.Minute), post(2, 3*time.Minute)}
+
+	tests := []struct {
+		name   string
+		cursor *Cursor
+		want   []int64
+	}{
+		{"next page keeps the newest", nil, []int64{5, 4, 3}},
+		{"prev page keeps the ones before the cursor", &Cursor{CreatedAt: now.Add(-5 * time.Minute), ID: 0, Prev: true}, []int64{3, 2, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &PaginatedFeedQuery{Limit: 3, Sort: "desc", Cursor: tt.cursor}
+
+			var got []int64
+			for _, post := range p.MergePages(timeline, large) {
+				got = append(got, post.ID)
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Expected %v. Got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRankedCursor(t *testing.T) {
+	cursor := RankedCursor{AsOf: time.Unix(0, 1700000000123456789), Score: 1.0 / 3, ID: 7}
+
+	decoded, err := DecodeRankedCursor(cursor.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !decoded.AsOf.Equal(cursor.AsOf) || decoded.Score != cursor.Score || decoded.ID != cursor.ID {
+		t.Errorf("Expected %+v. Got %+v", cursor, *decoded)
+	}
+
+	chronological := Cursor{CreatedAt: time.Now(), ID: 7}.Encode()
+	if _, err := DecodeRankedCursor(chronological); err != ErrInvalidCursor {
+		t.Errorf("Expected ErrInvalidCursor. Got %v", err)
+	}
+}
@@ -0,0 +1,159 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrPinLimit = errors.New("pinned posts limit reached")
+
+// pinsLockClass namespaces the advisory locks taken on pin changes so they
+// cannot collide with locks other features take on the same IDs.
+const pinsLockClass = 1
+
+// lockPins serializes pin changes of userID until the transaction ends, so
+// concurrent requests cannot exceed the limit or interleave positions.
+// IDs beyond the 32-bit lock key share a lock with another author, which
+// only serializes their unrelated changes.
+func lockPins(ctx context.Context, tx pgx.Tx, userID int64) error {
+	_, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1, $2)`, pinsLockClass, int32(userID))
+	return err
+}
+
+// Pin pins the post to the top of its author's profile at position (0 is
+// the top), or moves it there if it is already pinned. An author can have
+// at most limit pinned posts.
+func (s *PostStore) Pin(ctx context.Context, userID, postID int64, position, limit int) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx pgx.Tx) error {
+		if err := lockPins(ctx, tx, userID); err != nil {
+			return err
+		}
+
+		pinned, err := s.pinnedIDs(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+
+		pinned, err = placePin(pinned, postID, position, limit)
+		if err != nil {
+			return err
+		}
+
+		return s.savePins(ctx, tx, userID, pinned)
+	})
+}
+
+// placePin returns the pinned post IDs with postID moved, or added, at
+// position. Positions past the end place it last.
+func placePin(pinned []int64, postID int64, position, limit int) ([]int64, error) {
+	pinned = slices.DeleteFunc(pinned, func(id int64) bool { return id == postID })
+	if len(pinned) >= limit {
+		return nil, ErrPinLimit
+	}
+
+	position = min(max(position, 0), len(pinned))
+
+	return slices.Insert(pinned, position, postID), nil
+}
+
+func (s *PostStore) Unpin(ctx context.Context, userID, postID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx pgx.Tx) error {
+		if err := lockPins(ctx, tx, userID); err != nil {
+			return err
+		}
+
+		pinned, err := s.pinnedIDs(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+
+		if !slices.Contains(pinned, postID) {
+			return ErrNotFound
+		}
+
+		pinned = slices.DeleteFunc(pinned, func(id int64) bool { return id == postID })
+		return s.savePins(ctx, tx, userID, pinned)
+	})
+}
+
+// GetPinnedByUserID lists the pinned posts of authorID that viewerID may see
+// and that match the filters of p, in pin order.
+func (s *PostStore) GetPinnedByUserID(ctx context.Context, authorID, viewerID int64, p *PaginatedFeedQuery) ([]PostWithMetadata, error) {
+	query := `
+	SELECT
+		p.id, p.user_id, p.title, p.content, p.created_at, p.version, p.tags, p.entities, p.visibility, p.content_warning, p.sensitive, p.comment_policy, p.comments_locked,
+		` + postMedia + `, ` + postLinkPreview + `,
+		u.username,
+		count(c.id) AS comments_count
+	FROM pinned_posts pp
+	JOIN posts p ON p.id = pp.post_id
+	JOIN users u ON p.user_id = u.id
+	LEFT JOIN comments c ON c.post_id = p.id AND ` + commentVisibleTo("$1") + `
+	WHERE pp.user_id = $2 AND p.status = 'published' AND p.deleted_at IS NULL AND ` + visibleToViewer
+
+	args := []any{viewerID, authorID}
+
+	conditions, args := p.filters(args)
+	if len(conditions) > 0 {
+		query += " AND " + strings.Join(conditions, " AND ")
+	}
+
+	query += " GROUP BY p.id, u.username, pp.position ORDER BY pp.position"
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	posts, err := scanPostsWithMetadata(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range posts {
+		posts[i].Pinned = true
+	}
+
+	return posts, nil
+}
+
+func (s *PostStore) pinnedIDs(ctx context.Context, tx pgx.Tx, userID int64) ([]int64, error) {
+	rows, err := tx.Query(ctx, `SELECT post_id FROM pinned_posts WHERE user_id = $1 ORDER BY position`, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, pgx.RowTo[int64])
+}
+
+func (s *PostStore) savePins(ctx context.Context, tx pgx.Tx, userID int64, postIDs []int64) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM pinned_posts WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO pinned_posts (user_id, post_id, position)
+		SELECT $1, t.post_id, t.ord - 1
+		FROM unnest($2::bigint[]) WITH ORDINALITY AS t(post_id, ord)
+	`
+
+	if _, err := tx.Exec(ctx, query, userID, postIDs); err != nil {
+		return fmt.Errorf("saving pinned posts: %w", err)
+	}
+
+	return nil
+}
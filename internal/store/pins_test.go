@@ -0,0 +1,37 @@
+package store
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestPlacePin(t *testing.T) {
+	tests := []struct {
+		name     string
+		pinned   []int64
+		postID   int64
+		position int
+		expected []int64
+		err      error
+	}{
+		{"first pin", nil, 1, 0, []int64{1}, nil},
+		{"new pin on top", []int64{1, 2}, 3, 0, []int64{3, 1, 2}, nil},
+		{"position past the end", []int64{1}, 2, 5, []int64{1, 2}, nil},
+		{"move a pinned post down", []int64{1, 2, 3}, 1, 2, []int64{2, 3, 1}, nil},
+		{"move a pinned post up at the limit", []int64{1, 2, 3}, 3, 0, []int64{3, 1, 2}, nil},
+		{"new pin at the limit", []int64{1, 2, 3}, 4, 0, nil, ErrPinLimit},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pinned, err := placePin(slices.Clone(tt.pinned), tt.postID, tt.position, 3)
+			if err != tt.err {
+				t.Fatalf("Expected error %v. Got %v", tt.err, err)
+			}
+
+			if !slices.Equal(pinned, tt.expected) {
+				t.Errorf("Expected %v. Got %v", tt.expected, pinned)
+			}
+		})
+	}
+}
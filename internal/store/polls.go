@@ -0,0 +1,228 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrAlreadyVoted      = errors.New("user already voted in this poll")
+	ErrPollClosed        = errors.New("poll is closed")
+	ErrInvalidPollOption = errors.New("invalid poll option")
+)
+
+type Poll struct {
+	ID             int64        `json:"id"`
+	PostID         int64        `json:"post_id"`
+	MultipleChoice bool         `json:"multiple_choice"`
+	HideResults    bool         `json:"hide_results"`
+	ClosesAt       time.Time    `json:"closes_at"`
+	Closed         bool         `json:"closed"`
+	Options        []PollOption `json:"options"`
+	// TotalVoters and the per option votes are left out while the results
+	// are hidden from the viewer.
+	TotalVoters    *int    `json:"total_voters,omitempty"`
+	VotedOptionIDs []int64 `json:"voted_option_ids"`
+}
+
+type PollOption struct {
+	ID    int64  `json:"id"`
+	Text  string `json:"text"`
+	Votes *int   `json:"votes,omitempty"`
+}
+
+type PollStore struct {
+	db *pgxpool.Pool
+}
+
+// GetByPostID loads the poll attached to a post with its live tallies as
+// seen by viewerID. Results of a poll with HideResults are only shown to
+// the post author, to users who voted and once the poll has closed.
+func (s *PollStore) GetByPostID(ctx context.Context, postID, viewerID int64) (*Poll, error) {
+	query := `
+		SELECT pl.id, pl.post_id, pl.multiple_choice, pl.hide_results, pl.closes_at, pl.closes_at <= NOW(),
+			p.user_id,
+			(SELECT count(*) FROM poll_voters pv WHERE pv.poll_id = pl.id)
+		FROM polls pl
+		JOIN posts p ON p.id = pl.post_id
+		WHERE pl.post_id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var poll Poll
+	var authorID int64
+	var totalVoters int
+	err := s.db.QueryRow(ctx, query, postID).Scan(
+		&poll.ID,
+		&poll.PostID,
+		&poll.MultipleChoice,
+		&poll.HideResults,
+		&poll.ClosesAt,
+		&poll.Closed,
+		&authorID,
+		&totalVoters,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	query = `
+		SELECT o.id, o.text, count(v.user_id)
+		FROM poll_options o
+		LEFT JOIN poll_votes v ON v.option_id = o.id
+		WHERE o.poll_id = $1
+		GROUP BY o.id
+		ORDER BY o.position
+	`
+
+	rows, err := s.db.Query(ctx, query, poll.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	poll.Options, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (PollOption, error) {
+		var option PollOption
+		var votes int
+		err := row.Scan(&option.ID, &option.Text, &votes)
+		option.Votes = &votes
+		return option, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err = s.db.Query(ctx, `SELECT option_id FROM poll_votes WHERE poll_id = $1 AND user_id = $2`, poll.ID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	poll.VotedOptionIDs, err = pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return nil, err
+	}
+
+	poll.TotalVoters = &totalVoters
+
+	if poll.HideResults && !poll.Closed && authorID != viewerID && len(poll.VotedOptionIDs) == 0 {
+		poll.TotalVoters = nil
+		for i := range poll.Options {
+			poll.Options[i].Votes = nil
+		}
+	}
+
+	return &poll, nil
+}
+
+// Vote records the single ballot of userID. optionIDs must be options of
+// the poll; single choice polls take exactly one.
+func (s *PollStore) Vote(ctx context.Context, pollID, userID int64, optionIDs []int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	optionIDs = slices.Compact(slices.Sorted(slices.Values(optionIDs)))
+
+	return withTx(s.db, ctx, func(tx pgx.Tx) error {
+		var closed, multipleChoice bool
+		err := tx.QueryRow(
+			ctx,
+			`SELECT closes_at <= NOW(), multiple_choice FROM polls WHERE id = $1`,
+			pollID,
+		).Scan(&closed, &multipleChoice)
+		if err != nil {
+			switch {
+			case errors.Is(err, pgx.ErrNoRows):
+				return ErrNotFound
+			default:
+				return err
+			}
+		}
+
+		if closed {
+			return ErrPollClosed
+		}
+
+		if len(optionIDs) == 0 || (!multipleChoice && len(optionIDs) > 1) {
+			return ErrInvalidPollOption
+		}
+
+		result, err := tx.Exec(
+			ctx,
+			`INSERT INTO poll_voters (poll_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`,
+			pollID,
+			userID,
+		)
+		if err != nil {
+			return err
+		}
+
+		if result.RowsAffected() == 0 {
+			return ErrAlreadyVoted
+		}
+
+		query := `
+			INSERT INTO poll_votes (poll_id, option_id, user_id)
+			SELECT $1, o.id, $2
+			FROM poll_options o
+			WHERE o.poll_id = $1 AND o.id = ANY($3)
+		`
+
+		result, err = tx.Exec(ctx, query, pollID, userID, optionIDs)
+		if err != nil {
+			return err
+		}
+
+		if result.RowsAffected() != int64(len(optionIDs)) {
+			return ErrInvalidPollOption
+		}
+
+		return nil
+	})
+}
+
+func createPoll(ctx context.Context, tx pgx.Tx, postID int64, poll *Poll) error {
+	query := `
+		INSERT INTO polls (post_id, multiple_choice, hide_results, closes_at)
+		VALUES ($1, $2, $3, $4) RETURNING id
+	`
+
+	err := tx.QueryRow(ctx, query, postID, poll.MultipleChoice, poll.HideResults, poll.ClosesAt).Scan(&poll.ID)
+	if err != nil {
+		return err
+	}
+
+	poll.PostID = postID
+
+	for i := range poll.Options {
+		err := tx.QueryRow(
+			ctx,
+			`INSERT INTO poll_options (poll_id, position, text) VALUES ($1, $2, $3) RETURNING id`,
+			poll.ID,
+			i,
+			poll.Options[i].Text,
+		).Scan(&poll.Options[i].ID)
+		if err != nil {
+			return err
+		}
+
+		votes := 0
+		poll.Options[i].Votes = &votes
+	}
+
+	totalVoters := 0
+	poll.TotalVoters = &totalVoters
+	poll.VotedOptionIDs = []int64{}
+
+	return nil
+}
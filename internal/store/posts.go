@@ -2,67 +2,224 @@ package store
 
 import (
 	"context"
-	"database/sql"
 	"errors"
 	"fmt"
 	"strings"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+const (
+	PostStatusDraft     = "draft"
+	PostStatusScheduled = "scheduled"
+	PostStatusPublished = "published"
+
+	PostVisibilityPublic    = "public"
+	PostVisibilityFollowers = "followers"
+	PostVisibilityMentioned = "mentioned"
+	PostVisibilityPrivate   = "private"
+)
+
+// visibleToViewer restricts a query over posts aliased as p to the rows the
+// viewer bound to $1 may read. It mirrors Post.VisibleTo.
+var visibleToViewer = visibleTo("$1")
+
+// visibleTo builds the visibility filter over posts aliased as p for the
+// user ID given by the SQL expression userID.
+func visibleTo(userID string) string {
+	return strings.NewReplacer("$viewer", userID).Replace(`(
+	p.user_id = $viewer
+	OR p.review_status = 'approved' AND (
+		p.visibility = 'public'
+		OR (p.visibility = 'followers' AND EXISTS (
+			SELECT 1 FROM followers vf WHERE vf.user_id = $viewer AND vf.follower_id = p.user_id
+		))
+		OR (p.visibility = 'mentioned' AND EXISTS (
+			SELECT 1 FROM mentions vm WHERE vm.post_id = p.id AND vm.comment_id IS NULL AND vm.user_id = $viewer
+		))
+	)
+)`)
+}
+
 type Post struct {
-	ID        int64     `json:"id"`
-	Content   string    `json:"content"`
-	Title     string    `json:"title"`
-	UserID    int64     `json:"user_id"`
-	Tags      []string  `json:"tags"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Version   int       `json:"version"`
-	Comments  []Comment `json:"comments"`
-	User      User      `json:"user"`
+	ID             int64        `json:"id"`
+	Content        string       `json:"content"`
+	ContentHTML    string       `json:"content_html"`
+	Title          string       `json:"title"`
+	UserID         int64        `json:"user_id"`
+	Tags           []string     `json:"tags"`
+	Entities       []Entity     `json:"entities"`
+	Media          []Media      `json:"media"`
+	LinkPreview    *LinkPreview `json:"link_preview"`
+	Status         string       `json:"status"`
+	Visibility     string       `json:"visibility"`
+	ContentWarning string       `json:"content_warning"`
+	Sensitive      bool         `json:"sensitive"`
+	CommentPolicy  string       `json:"comment_policy"`
+	CommentsLocked bool         `json:"comments_locked"`
+	ReviewStatus   string       `json:"-"`
+	SpamReasons    []string     `json:"-"`
+	Collapsed      bool         `json:"collapsed"`
+	PublishAt      *time.Time   `json:"publish_at"`
+	CreatedAt      time.Time    `json:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+	Version        int          `json:"version"`
+	Comments       []Comment    `json:"comments"`
+	CommentsTotal  int          `json:"comments_total"`
+	CommentsCursor string       `json:"comments_next_cursor,omitempty"`
+	User           User         `json:"user"`
+	Poll           *Poll        `json:"poll,omitempty"`
+	DeletedAt      *time.Time   `json:"deleted_at,omitempty"`
+	DeletedBy      *int64       `json:"deleted_by,omitempty"`
+}
+
+// VisibleTo reports whether viewer may read the post given whether they follow
+// its author. It mirrors the visibleToViewer SQL filter.
+func (p *Post) VisibleTo(viewer *User, follows bool) bool {
+	if p.UserID == viewer.ID {
+		return true
+	}
+
+	if !p.IsPublished() || (p.ReviewStatus != "" && p.ReviewStatus != ReviewApproved) {
+		return false
+	}
+
+	switch p.Visibility {
+	case "", PostVisibilityPublic:
+		return true
+	case PostVisibilityFollowers:
+		return follows
+	case PostVisibilityMentioned:
+		for _, id := range mentionedUserIDs(p.Entities) {
+			if id == viewer.ID {
+				return true
+			}
+		}
+		return false
+	default:
+		return false
+	}
+}
+
+// IsPublished reports whether the post is visible to users other than its
+// author.
+func (p *Post) IsPublished() bool {
+	return p.Status == "" || p.Status == PostStatusPublished
 }
 
 type PostWithMetadata struct {
 	Post
-	CommentCount int `json:"comments_count"`
+	CommentCount int     `json:"comments_count"`
+	Pinned       bool    `json:"pinned"`
+	Score        float64 `json:"score,omitempty"`
 }
 
 type PostStore struct {
 	db *pgxpool.Pool
 }
 
+// Create inserts the post together with its tag, mention and poll rows and
+// attaches the uploads listed in post.Media. Mention
+// entities are resolved to user IDs and mentioned users are notified once
+// the post is published and approved.
 func (s *PostStore) Create(ctx context.Context, post *Post) error {
 	query := `
-		INSERT INTO posts (content, title, user_id, tags)
-		VALUES ($1, $2, $3, $4) RETURNING id, created_at, updated_at
+		INSERT INTO posts (
+			content, title, user_id, tags, status, publish_at, visibility, entities, link_url, content_warning, sensitive, comment_policy,
+			review_status, spam_reasons
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14) RETURNING id, created_at, updated_at
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	err := s.db.QueryRow(
-		ctx,
-		query,
-		post.Content,
-		post.Title,
-		post.UserID,
-		post.Tags,
-	).Scan(&post.ID, &post.CreatedAt, &post.UpdatedAt)
+	if post.Status == "" {
+		post.Status = PostStatusPublished
+	}
 
-	if err != nil {
-		return err
+	if post.Visibility == "" {
+		post.Visibility = PostVisibilityPublic
 	}
 
-	return nil
+	if post.CommentPolicy == "" {
+		post.CommentPolicy = CommentPolicyEveryone
+	}
+
+	if post.ReviewStatus == "" {
+		post.ReviewStatus = ReviewApproved
+	}
+
+	if post.SpamReasons == nil {
+		post.SpamReasons = []string{}
+	}
+
+	return withTx(s.db, ctx, func(tx pgx.Tx) error {
+		entities, err := resolveMentions(ctx, tx, post.Entities)
+		if err != nil {
+			return err
+		}
+		post.Entities = entities
+
+		err = tx.QueryRow(
+			ctx,
+			query,
+			post.Content,
+			post.Title,
+			post.UserID,
+			post.Tags,
+			post.Status,
+			post.PublishAt,
+			post.Visibility,
+			post.Entities,
+			linkURL(post.Entities),
+			post.ContentWarning,
+			post.Sensitive,
+			post.CommentPolicy,
+			post.ReviewStatus,
+			post.SpamReasons,
+		).Scan(&post.ID, &post.CreatedAt, &post.UpdatedAt)
+		if err != nil {
+			return err
+		}
+
+		if err := replacePostTags(ctx, tx, post.ID, post.Tags); err != nil {
+			return err
+		}
+
+		if _, err := replaceMentions(ctx, tx, post.ID, nil, post.UserID, post.Entities); err != nil {
+			return err
+		}
+
+		if post.Poll != nil {
+			if err := createPoll(ctx, tx, post.ID, post.Poll); err != nil {
+				return err
+			}
+		}
+
+		if len(post.Media) > 0 {
+			if err := attachMedia(ctx, tx, post); err != nil {
+				return err
+			}
+		}
+
+		if !post.IsPublished() {
+			return nil
+		}
+
+		return notifyMentions(ctx, tx, []int64{post.ID}, nil, nil)
+	})
 }
 
 func (s *PostStore) GetByID(ctx context.Context, id int64) (*Post, error) {
 	query := `
-		SELECT id, user_id, title, content, tags, created_at, updated_at, version
-		FROM posts
-		WHERE id = $1
+		SELECT p.id, p.user_id, p.title, p.content, p.tags, p.entities, ` + postMedia + `, ` + postLinkPreview + `,
+			p.status, p.visibility, p.content_warning, p.sensitive, p.comment_policy, p.comments_locked, p.review_status,
+			p.publish_at, p.created_at, p.updated_at, p.version
+		FROM posts p
+		WHERE p.id = $1 AND p.deleted_at IS NULL
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
@@ -79,6 +236,17 @@ func (s *PostStore) GetByID(ctx context.Context, id int64) (*Post, error) {
 		&post.Title,
 		&post.Content,
 		&post.Tags,
+		&post.Entities,
+		&post.Media,
+		&post.LinkPreview,
+		&post.Status,
+		&post.Visibility,
+		&post.ContentWarning,
+		&post.Sensitive,
+		&post.CommentPolicy,
+		&post.CommentsLocked,
+		&post.ReviewStatus,
+		&post.PublishAt,
 		&post.CreatedAt,
 		&post.UpdatedAt,
 		&post.Version,
@@ -86,59 +254,133 @@ func (s *PostStore) GetByID(ctx context.Context, id int64) (*Post, error) {
 
 	if err != nil {
 		switch {
-		case errors.Is(err, sql.ErrNoRows):
+		case errors.Is(err, pgx.ErrNoRows):
 			return nil, ErrNotFound
 		default:
-			return nil, nil
+			return nil, err
 		}
 	}
 
 	return &post, nil
 }
 
-func (s *PostStore) Delete(ctx context.Context, id int64) error {
-	query := `DELETE FROM posts WHERE id = $1`
-
+// Update saves the post if it is still at post.Version, replacing its tag
+// and mention rows and, unless post.Media is nil, its attachments. Newly
+// mentioned users are notified, as is everyone mentioned when the update
+// publishes the post. post.ReviewStatus is the verdict of the spam filter
+// on the edit: it is only saved, along with post.SpamReasons, if it is
+// stricter than the current review status.
+func (s *PostStore) Update(ctx context.Context, post *Post) error {
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	result, err := s.db.Exec(ctx, query, id)
-	if err != nil {
-		return err
-	}
+	return withTx(s.db, ctx, func(tx pgx.Tx) error {
+		var wasPublished bool
+		var reviewStatus string
+		err := tx.QueryRow(
+			ctx,
+			`SELECT status = 'published', review_status FROM posts WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`,
+			post.ID,
+		).Scan(&wasPublished, &reviewStatus)
+		if err != nil {
+			switch {
+			case errors.Is(err, pgx.ErrNoRows):
+				return ErrNotFound
+			default:
+				return err
+			}
+		}
 
-	rows := result.RowsAffected()
-	if rows == 0 {
-		return ErrNotFound
-	}
+		reasons := post.SpamReasons
+		post.ReviewStatus = stricterReview(reviewStatus, post.ReviewStatus)
+		if post.ReviewStatus == reviewStatus {
+			reasons = nil
+		}
 
-	return nil
-}
+		entities, err := resolveMentions(ctx, tx, post.Entities)
+		if err != nil {
+			return err
+		}
+		post.Entities = entities
+
+		// A post that becomes published through an update shows up in feeds as
+		// of now rather than as of when its draft was first saved.
+		query := `
+			UPDATE posts
+			SET title = $1, content = $2, tags = $3, updated_at = $4, status = $5, publish_at = $6,
+				visibility = $7, entities = $8, link_url = $11, content_warning = $12, sensitive = $13,
+				comment_policy = $14, review_status = $15, spam_reasons = COALESCE($16, spam_reasons),
+				created_at = CASE WHEN status <> 'published' AND $5 = 'published' THEN NOW() ELSE created_at END,
+				version = version + 1
+			WHERE id = $9 AND version = $10
+			RETURNING version, created_at
+		`
+
+		err = tx.QueryRow(
+			ctx,
+			query,
+			post.Title,
+			post.Content,
+			post.Tags,
+			post.UpdatedAt,
+			post.Status,
+			post.PublishAt,
+			post.Visibility,
+			post.Entities,
+			post.ID,
+			post.Version,
+			linkURL(post.Entities),
+			post.ContentWarning,
+			post.Sensitive,
+			post.CommentPolicy,
+			post.ReviewStatus,
+			reasons,
+		).Scan(&post.Version, &post.CreatedAt)
+		if err != nil {
+			switch {
+			case errors.Is(err, pgx.ErrNoRows):
+				// The row is locked above, so it exists at another version.
+				return ErrVersionConflict
+			default:
+				return err
+			}
+		}
 
-func (s *PostStore) Update(ctx context.Context, post *Post) error {
-	query := `
-		UPDATE posts
-		SET title = $1, content = $2, tags = $3, updated_at = $4, version = version + 1
-		WHERE id = $5 AND version = $6
-		RETURNING version
-	`
+		if err := replacePostTags(ctx, tx, post.ID, post.Tags); err != nil {
+			return err
+		}
 
-	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
-	defer cancel()
+		added, err := replaceMentions(ctx, tx, post.ID, nil, post.UserID, post.Entities)
+		if err != nil {
+			return err
+		}
 
-	err := s.db.QueryRow(
-		ctx,
-		query,
-		post.Title,
-		post.Content,
-		post.Tags,
-		post.UpdatedAt,
-		post.ID,
-		post.Version,
-	).Scan(&post.Version)
+		if post.Media != nil {
+			if err := attachMedia(ctx, tx, post); err != nil {
+				return err
+			}
+		}
+
+		switch {
+		case !post.IsPublished():
+			return nil
+		case !wasPublished:
+			return notifyMentions(ctx, tx, []int64{post.ID}, nil, nil)
+		default:
+			return notifyMentions(ctx, tx, []int64{post.ID}, nil, added)
+		}
+	})
+}
+
+// lockPost locks the row of a post that is not deleted until tx ends, so
+// that a version-checked update that matches no row afterwards can only be
+// a version conflict.
+func lockPost(ctx context.Context, tx pgx.Tx, postID int64) error {
+	var id int64
+	err := tx.QueryRow(ctx, `SELECT id FROM posts WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, postID).Scan(&id)
 	if err != nil {
 		switch {
-		case errors.Is(err, sql.ErrNoRows):
+		case errors.Is(err, pgx.ErrNoRows):
 			return ErrNotFound
 		default:
 			return err
@@ -148,66 +390,82 @@ func (s *PostStore) Update(ctx context.Context, post *Post) error {
 	return nil
 }
 
+// GetUserFeed lists the published posts of userID and of the users they
+// follow, newest first unless p.Sort says otherwise. Pages are keyed by
+// p.Cursor, or by the deprecated p.Offset.
 func (s *PostStore) GetUserFeed(ctx context.Context, userID int64, p *PaginatedFeedQuery) ([]PostWithMetadata, error) {
-	var conditions []string
-	var args []interface{}
-	argIndex := 1 // Mulai dari $1 untuk parameter pertama
-
 	// Query dasar
 	query := `
 	SELECT 
-		p.id, p.user_id, p.title, p.content, p.created_at, p.version, p.tags,
+		p.id, p.user_id, p.title, p.content, p.created_at, p.version, p.tags, p.entities, p.visibility, p.content_warning, p.sensitive, p.comment_policy, p.comments_locked,
+		` + postMedia + `, ` + postLinkPreview + `,
 		u.username,
 		count(c.id) AS comments_count
 	FROM posts p
-	LEFT JOIN comments c ON c.post_id = p.id
+	LEFT JOIN comments c ON c.post_id = p.id AND ` + commentVisibleTo("$1") + `
 	LEFT JOIN users u ON p.user_id = u.id
 	LEFT JOIN followers f ON f.follower_id = p.user_id
-	WHERE (f.user_id = $1 OR p.user_id = $1)
-	`
-
-	args = append(args, userID)
+	WHERE (f.user_id = $1 OR p.user_id = $1) AND p.status = 'published' AND p.deleted_at IS NULL AND ` + visibleToViewer
 
-	// Filter Search
-	if p.Search != "" {
-		conditions = append(conditions, fmt.Sprintf(
-			"to_tsvector('english', p.title || ' ' || p.content) @@ plainto_tsquery('english', $%d::text)",
-			argIndex+1))
-		args = append(args, p.Search)
-		argIndex++
-	}	
+	args := []any{userID}
 
-	// Filter Tags
-	if len(p.Tags) > 0 {
-		conditions = append(conditions, fmt.Sprintf("p.tags @> $%d", argIndex+1))
-		args = append(args, p.Tags)
-		argIndex++
+	// Tambahkan kondisi ke query jika ada filter
+	conditions, args := p.filters(args)
+	conditions, args = p.cursorCondition(conditions, args)
+	if len(conditions) > 0 {
+		query += " AND " + strings.Join(conditions, " AND ")
 	}
 
-	// Filter Since
-	if !p.Since.IsZero() {
-		conditions = append(conditions, fmt.Sprintf("p.created_at >= $%d", argIndex+1))
-		args = append(args, p.Since)
-		argIndex++
+	// Tambahkan ORDER, LIMIT, dan OFFSET. Offset is only kept for clients
+	// that have not moved to cursors yet.
+	query += fmt.Sprintf(" GROUP BY p.id, u.username ORDER BY p.created_at %[1]s, p.id %[1]s LIMIT $%[2]d OFFSET $%[3]d", p.order(), len(args)+1, len(args)+2)
+	args = append(args, p.Limit, p.Offset)
+
+	// Eksekusi Query
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
 	}
 
-	// Filter Until
-	if !p.Until.IsZero() {
-		conditions = append(conditions, fmt.Sprintf("p.created_at <= $%d", argIndex+1))
-		args = append(args, p.Until)
-		argIndex++
+	posts, err := scanPostsWithMetadata(rows)
+	if err != nil {
+		return nil, err
 	}
 
-	// Tambahkan kondisi ke query jika ada filter
+	return p.pageOrder(posts), nil
+}
+
+// GetByUserID lists the published posts of authorID that viewerID may see,
+// newest first unless p.Sort says otherwise. Pages are keyed by p.Cursor.
+// Pinned posts are left out; see GetPinnedByUserID.
+func (s *PostStore) GetByUserID(ctx context.Context, authorID, viewerID int64, p *PaginatedFeedQuery) ([]PostWithMetadata, error) {
+	query := `
+	SELECT
+		p.id, p.user_id, p.title, p.content, p.created_at, p.version, p.tags, p.entities, p.visibility, p.content_warning, p.sensitive, p.comment_policy, p.comments_locked,
+		` + postMedia + `, ` + postLinkPreview + `,
+		u.username,
+		count(c.id) AS comments_count
+	FROM posts p
+	JOIN users u ON p.user_id = u.id
+	LEFT JOIN comments c ON c.post_id = p.id AND ` + commentVisibleTo("$1") + `
+	WHERE p.user_id = $2 AND p.status = 'published' AND p.deleted_at IS NULL
+	AND NOT EXISTS (SELECT 1 FROM pinned_posts pp WHERE pp.post_id = p.id)
+	AND ` + visibleToViewer
+
+	args := []any{viewerID, authorID}
+
+	conditions, args := p.filters(args)
+	conditions, args = p.cursorCondition(conditions, args)
 	if len(conditions) > 0 {
 		query += " AND " + strings.Join(conditions, " AND ")
 	}
 
-	// Tambahkan ORDER, LIMIT, dan OFFSET
-	query += fmt.Sprintf(" GROUP BY p.id, u.username ORDER BY p.created_at %s LIMIT $%d OFFSET $%d", p.Sort, argIndex+1, argIndex+2)
-	args = append(args, p.Limit, p.Offset)
+	query += fmt.Sprintf(" GROUP BY p.id, u.username ORDER BY p.created_at %[1]s, p.id %[1]s LIMIT $%[2]d", p.order(), len(args)+1)
+	args = append(args, p.Limit)
 
-	// Eksekusi Query
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
@@ -215,27 +473,145 @@ func (s *PostStore) GetUserFeed(ctx context.Context, userID int64, p *PaginatedF
 	if err != nil {
 		return nil, err
 	}
+
+	posts, err := scanPostsWithMetadata(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.pageOrder(posts), nil
+}
+
+func scanPostsWithMetadata(rows pgx.Rows) ([]PostWithMetadata, error) {
 	defer rows.Close()
 
 	// Scan hasil ke dalam struct
-	var feeds []PostWithMetadata
+	feeds := []PostWithMetadata{}
 	for rows.Next() {
 		var feed PostWithMetadata
+		if err := rows.Scan(feed.scanFields()...); err != nil {
+			return nil, err
+		}
+		feed.User.ID = feed.UserID
+		feed.Status = PostStatusPublished
+		feeds = append(feeds, feed)
+	}
+
+	return feeds, rows.Err()
+}
+
+// scanFields returns the destinations of the columns the feed queries
+// select, in order.
+func (feed *PostWithMetadata) scanFields() []any {
+	return []any{
+		&feed.ID,
+		&feed.UserID,
+		&feed.Title,
+		&feed.Content,
+		&feed.CreatedAt,
+		&feed.Version,
+		&feed.Tags,
+		&feed.Entities,
+		&feed.Visibility,
+		&feed.ContentWarning,
+		&feed.Sensitive,
+		&feed.CommentPolicy,
+		&feed.CommentsLocked,
+		&feed.Media,
+		&feed.LinkPreview,
+		&feed.User.Username,
+		&feed.CommentCount,
+	}
+}
+
+func (s *PostStore) GetDraftsByUserID(ctx context.Context, userID int64) ([]Post, error) {
+	query := `
+		SELECT p.id, p.user_id, p.title, p.content, p.tags, p.entities, ` + postMedia + `, ` + postLinkPreview + `,
+			p.status, p.visibility, p.content_warning, p.sensitive, p.comment_policy, p.comments_locked, p.publish_at, p.created_at, p.updated_at, p.version
+		FROM posts p
+		WHERE p.user_id = $1 AND p.status <> 'published' AND p.deleted_at IS NULL
+		ORDER BY COALESCE(p.publish_at, p.updated_at) DESC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	drafts := []Post{}
+	for rows.Next() {
+		var post Post
 		if err := rows.Scan(
-			&feed.ID,
-			&feed.UserID,
-			&feed.Title,
-			&feed.Content,
-			&feed.CreatedAt,
-			&feed.Version,
-			&feed.Tags,
-			&feed.User.Username,
-			&feed.CommentCount,
+			&post.ID,
+			&post.UserID,
+			&post.Title,
+			&post.Content,
+			&post.Tags,
+			&post.Entities,
+			&post.Media,
+			&post.LinkPreview,
+			&post.Status,
+			&post.Visibility,
+			&post.ContentWarning,
+			&post.Sensitive,
+			&post.CommentPolicy,
+			&post.CommentsLocked,
+			&post.PublishAt,
+			&post.CreatedAt,
+			&post.UpdatedAt,
+			&post.Version,
 		); err != nil {
 			return nil, err
 		}
-		feeds = append(feeds, feed)
+		drafts = append(drafts, post)
+	}
+
+	return drafts, rows.Err()
+}
+
+// PublishDue publishes up to limit scheduled posts whose publish_at has
+// passed and notifies the users they mention. Rows are claimed with SKIP
+// LOCKED so concurrent API instances never publish the same post twice.
+func (s *PostStore) PublishDue(ctx context.Context, limit int) ([]int64, error) {
+	query := `
+		WITH due AS (
+			SELECT id FROM posts
+			WHERE status = 'scheduled' AND publish_at <= NOW() AND deleted_at IS NULL
+			ORDER BY publish_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE posts p
+		SET status = 'published', created_at = p.publish_at, version = p.version + 1
+		FROM due
+		WHERE p.id = due.id
+		RETURNING p.id
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var ids []int64
+	err := withTx(s.db, ctx, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, query, limit)
+		if err != nil {
+			return err
+		}
+
+		ids, err = pgx.CollectRows(rows, pgx.RowTo[int64])
+		if err != nil {
+			return err
+		}
+
+		return notifyMentions(ctx, tx, ids, nil, nil)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return feeds, nil
+	return ids, nil
 }
@@ -0,0 +1,217 @@
+package store
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FeedModeChronological = "chronological"
+	FeedModeRanked        = "ranked"
+)
+
+// FeedRanking weighs the signals the ranked feed scores posts by. Each
+// signal is scaled to [0, 1) before it is weighed:
+//
+//   - recency halves every HalfLife,
+//   - author affinity grows with the viewer's comments on and poll votes in
+//     the author's posts within AffinityWindow,
+//   - engagement grows with the comments on and poll votes in the post,
+//   - tag affinity grows with how often the viewer wrote or commented on
+//     posts with the same tags within AffinityWindow.
+//
+// Only posts from the last CandidateWindow are ranked.
+type FeedRanking struct {
+	RecencyWeight    float64
+	AffinityWeight   float64
+	EngagementWeight float64
+	TagWeight        float64
+	HalfLife         time.Duration
+	AffinityWindow   time.Duration
+	CandidateWindow  time.Duration
+}
+
+// RankedCursor is a position in a ranked feed. Scores are computed as of
+// AsOf, the time the first page was ranked at, and only from activity up
+// to then, so every page of a session ranks the same snapshot.
+type RankedCursor struct {
+	AsOf  time.Time
+	Score float64
+	ID    int64
+}
+
+func (c RankedCursor) Encode() string {
+	raw := fmt.Sprintf("ranked:%d:%s:%d", c.AsOf.UnixNano(), strconv.FormatFloat(c.Score, 'g', -1, 64), c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeRankedCursor(token string) (*RankedCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	parts := strings.Split(string(raw), ":")
+	if len(parts) != 4 || parts[0] != "ranked" {
+		return nil, ErrInvalidCursor
+	}
+
+	nanos, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	score, err := strconv.ParseFloat(parts[2], 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	id, err := strconv.ParseInt(parts[3], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &RankedCursor{AsOf: time.Unix(0, nanos), Score: score, ID: id}, nil
+}
+
+// NextRankedCursor returns the cursor that continues after the last of a
+// ranked page, or nil when the page was not full.
+func (p *PaginatedFeedQuery) NextRankedCursor(asOf time.Time, page []PostWithMetadata) *RankedCursor {
+	if len(page) == 0 || len(page) < p.Limit {
+		return nil
+	}
+
+	last := page[len(page)-1]
+	return &RankedCursor{AsOf: asOf, Score: last.Score, ID: last.ID}
+}
+
+// GetRankedFeed returns a page of the feed of userID ordered by score as of
+// asOf, highest first, with ties broken by ID. p.Ranked positions the page;
+// the search, tag and date filters of p narrow the candidates.
+func (s *PostStore) GetRankedFeed(ctx context.Context, userID int64, asOf time.Time, r FeedRanking, p *PaginatedFeedQuery) ([]PostWithMetadata, error) {
+	args := []any{
+		userID,
+		asOf,
+		asOf.Add(-r.CandidateWindow),
+		asOf.Add(-r.AffinityWindow),
+		r.HalfLife.Seconds(),
+		r.RecencyWeight,
+		r.AffinityWeight,
+		r.EngagementWeight,
+		r.TagWeight,
+	}
+
+	conditions, args := p.filters(args)
+	filters := ""
+	if len(conditions) > 0 {
+		filters = " AND " + strings.Join(conditions, " AND ")
+	}
+
+	cursor := ""
+	if p.Ranked != nil {
+		args = append(args, p.Ranked.Score, p.Ranked.ID)
+		cursor = fmt.Sprintf("WHERE (s.score, s.id) < ($%d::float8, $%d)", len(args)-1, len(args))
+	}
+
+	args = append(args, p.Limit)
+
+	query := `
+	WITH candidates AS (
+		SELECT p.id, p.user_id, p.created_at, p.tags
+		FROM posts p
+		WHERE (p.user_id = $1 OR EXISTS (
+			SELECT 1 FROM followers f WHERE f.user_id = $1 AND f.follower_id = p.user_id
+		))
+		AND p.status = 'published' AND p.deleted_at IS NULL
+		AND p.created_at <= $2 AND p.created_at > $3
+		AND ` + visibleToViewer + filters + `
+	),
+	affinity AS (
+		SELECT p.user_id AS author_id, count(*) AS interactions
+		FROM (
+			SELECT c.post_id FROM comments c
+			WHERE c.user_id = $1 AND c.created_at <= $2 AND c.created_at > $4
+			UNION ALL
+			SELECT pl.post_id FROM poll_voters v
+			JOIN polls pl ON pl.id = v.poll_id
+			WHERE v.user_id = $1 AND v.created_at <= $2 AND v.created_at > $4
+		) i
+		JOIN posts p ON p.id = i.post_id
+		WHERE p.user_id <> $1
+		GROUP BY p.user_id
+	),
+	tag_affinity AS (
+		SELECT tag, count(*) AS uses
+		FROM (
+			SELECT unnest(p.tags) AS tag FROM posts p
+			WHERE p.user_id = $1 AND p.created_at <= $2 AND p.created_at > $4
+			UNION ALL
+			SELECT unnest(p.tags) FROM comments c
+			JOIN posts p ON p.id = c.post_id
+			WHERE c.user_id = $1 AND c.created_at <= $2 AND c.created_at > $4
+		) t
+		GROUP BY tag
+	),
+	scored AS (
+		SELECT cd.id,
+			$6::float8 * power(0.5, extract(epoch FROM $2 - cd.created_at)::float8 / $5::float8)
+			+ $7::float8 * a.n / (1 + a.n)
+			+ $8::float8 * ln(1 + e.n) / (1 + ln(1 + e.n))
+			+ $9::float8 * t.n / (1 + t.n) AS score
+		FROM candidates cd
+		CROSS JOIN LATERAL (
+			SELECT coalesce(max(interactions), 0)::float8 AS n FROM affinity WHERE author_id = cd.user_id
+		) a
+		CROSS JOIN LATERAL (
+			SELECT (
+				(SELECT count(*) FROM comments c
+				WHERE c.post_id = cd.id AND c.review_status = 'approved' AND c.created_at <= $2)
+				+ (SELECT count(*) FROM poll_voters v
+				JOIN polls pl ON pl.id = v.poll_id
+				WHERE pl.post_id = cd.id AND v.created_at <= $2)
+			)::float8 AS n
+		) e
+		CROSS JOIN LATERAL (
+			SELECT coalesce(sum(uses), 0)::float8 AS n FROM tag_affinity WHERE tag = ANY(cd.tags)
+		) t
+	)
+	SELECT
+		p.id, p.user_id, p.title, p.content, p.created_at, p.version, p.tags, p.entities, p.visibility, p.content_warning, p.sensitive, p.comment_policy, p.comments_locked,
+		` + postMedia + `, ` + postLinkPreview + `,
+		u.username,
+		count(c.id) AS comments_count,
+		s.score
+	FROM (SELECT * FROM scored s ` + cursor + ` ORDER BY s.score DESC, s.id DESC LIMIT $` + strconv.Itoa(len(args)) + `) s
+	JOIN posts p ON p.id = s.id
+	JOIN users u ON u.id = p.user_id
+	LEFT JOIN comments c ON c.post_id = p.id AND ` + commentVisibleTo("$1") + `
+	GROUP BY p.id, u.username, s.score
+	ORDER BY s.score DESC, p.id DESC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	feed := []PostWithMetadata{}
+	for rows.Next() {
+		var post PostWithMetadata
+		if err := rows.Scan(append(post.scanFields(), &post.Score)...); err != nil {
+			return nil, err
+		}
+		post.User.ID = post.UserID
+		post.Status = PostStatusPublished
+		feed = append(feed, post)
+	}
+
+	return feed, rows.Err()
+}
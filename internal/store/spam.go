@@ -0,0 +1,223 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/spam"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Review statuses of posts and comments. Held content waits for a
+// moderator; hidden content is only shown to its author.
+const (
+	ReviewApproved = "approved"
+	ReviewHeld     = "held"
+	ReviewHidden   = "hidden"
+)
+
+// reviewSeverity orders the review statuses from shown to hidden.
+var reviewSeverity = map[string]int{ReviewApproved: 0, ReviewHeld: 1, ReviewHidden: 2}
+
+// stricterReview returns screened if it hides content more than current.
+// Edits are screened again, but only moderators approve content.
+func stricterReview(current, screened string) string {
+	if reviewSeverity[screened] > reviewSeverity[current] {
+		return screened
+	}
+
+	return current
+}
+
+const (
+	ContentKindPost    = "post"
+	ContentKindComment = "comment"
+)
+
+// HeldContent is a post or comment the spam filter held or hid.
+type HeldContent struct {
+	Kind      string    `json:"kind"`
+	ID        int64     `json:"id"`
+	PostID    int64     `json:"post_id"`
+	UserID    int64     `json:"user_id"`
+	Username  string    `json:"username"`
+	Content   string    `json:"content"`
+	Status    string    `json:"status"`
+	Reasons   []string  `json:"reasons"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type SpamStore struct {
+	db *pgxpool.Pool
+}
+
+// CountDuplicates counts the posts and comments authorID created since
+// since whose content matches text, ignoring case and surrounding
+// whitespace. The post or comment being edited is not counted.
+func (s *SpamStore) CountDuplicates(ctx context.Context, authorID int64, text string, since time.Time, edit spam.Edit) (int, error) {
+	query := `
+		SELECT
+			(SELECT count(*) FROM comments
+			WHERE user_id = $1 AND created_at >= $2 AND lower(btrim(content)) = lower(btrim($3)) AND id <> $5)
+			+
+			(SELECT count(*) FROM posts
+			WHERE user_id = $1 AND created_at >= $2 AND deleted_at IS NULL AND lower(btrim(content)) = lower(btrim($3)) AND id <> $4)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var count int
+	err := s.db.QueryRow(ctx, query, authorID, since, text, edit.PostID, edit.CommentID).Scan(&count)
+	return count, err
+}
+
+// CountRecent counts the posts and comments authorID created since since,
+// leaving out the post or comment being edited.
+func (s *SpamStore) CountRecent(ctx context.Context, authorID int64, since time.Time, edit spam.Edit) (int, error) {
+	query := `
+		SELECT
+			(SELECT count(*) FROM comments WHERE user_id = $1 AND created_at >= $2 AND id <> $4)
+			+
+			(SELECT count(*) FROM posts WHERE user_id = $1 AND created_at >= $2 AND deleted_at IS NULL AND id <> $3)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var count int
+	err := s.db.QueryRow(ctx, query, authorID, since, edit.PostID, edit.CommentID).Scan(&count)
+	return count, err
+}
+
+// GetHeld lists up to limit posts and comments in the given review status,
+// oldest first.
+func (s *SpamStore) GetHeld(ctx context.Context, status string, limit int) ([]HeldContent, error) {
+	query := `
+		SELECT 'post', p.id, p.id, p.user_id, u.username, p.content, p.review_status, p.spam_reasons, p.created_at
+		FROM posts p
+		JOIN users u ON u.id = p.user_id
+		WHERE p.review_status = $1 AND p.deleted_at IS NULL
+		UNION ALL
+		SELECT 'comment', c.id, c.post_id, c.user_id, u.username, c.content, c.review_status, c.spam_reasons, c.created_at
+		FROM comments c
+		JOIN users u ON u.id = c.user_id
+		JOIN posts p ON p.id = c.post_id
+		WHERE c.review_status = $1 AND p.deleted_at IS NULL
+		ORDER BY 9, 2
+		LIMIT $2
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.Query(ctx, query, status, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	held := []HeldContent{}
+	for rows.Next() {
+		var h HeldContent
+		if err := rows.Scan(
+			&h.Kind,
+			&h.ID,
+			&h.PostID,
+			&h.UserID,
+			&h.Username,
+			&h.Content,
+			&h.Status,
+			&h.Reasons,
+			&h.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		held = append(held, h)
+	}
+
+	return held, rows.Err()
+}
+
+// Review moves a held or hidden post or comment to status. Approved
+// content notifies the users it mentions and, for replies, counts towards
+// its parent's replies. entry records the decision in the moderation log.
+func (s *SpamStore) Review(ctx context.Context, kind string, id int64, status string, entry *ModerationEntry) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx pgx.Tx) error {
+		switch kind {
+		case ContentKindPost:
+			return reviewPost(ctx, tx, id, status, entry)
+		case ContentKindComment:
+			return reviewComment(ctx, tx, id, status, entry)
+		default:
+			return ErrNotFound
+		}
+	})
+}
+
+func reviewPost(ctx context.Context, tx pgx.Tx, id int64, status string, entry *ModerationEntry) error {
+	query := `
+		UPDATE posts SET review_status = $1
+		WHERE id = $2 AND review_status <> 'approved' AND deleted_at IS NULL
+		RETURNING user_id, status = 'published'
+	`
+
+	var published bool
+	err := tx.QueryRow(ctx, query, status, id).Scan(&entry.TargetUserID, &published)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return ErrNotFound
+		default:
+			return err
+		}
+	}
+
+	if status == ReviewApproved && published {
+		if err := notifyMentions(ctx, tx, []int64{id}, nil, nil); err != nil {
+			return err
+		}
+	}
+
+	entry.PostID = &id
+	return logModeration(ctx, tx, entry)
+}
+
+func reviewComment(ctx context.Context, tx pgx.Tx, id int64, status string, entry *ModerationEntry) error {
+	query := `
+		UPDATE comments SET review_status = $1
+		WHERE id = $2 AND review_status <> 'approved'
+		RETURNING post_id, user_id, parent_id
+	`
+
+	comment := &Comment{ID: id}
+	err := tx.QueryRow(ctx, query, status, id).Scan(&comment.PostID, &comment.UserID, &comment.ParentID)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return ErrNotFound
+		default:
+			return err
+		}
+	}
+
+	if status == ReviewApproved {
+		if comment.ParentID != nil {
+			_, err := tx.Exec(ctx, `UPDATE comments SET reply_count = reply_count + 1 WHERE id = $1`, *comment.ParentID)
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := notifyMentions(ctx, tx, []int64{comment.PostID}, &comment.ID, nil); err != nil {
+			return err
+		}
+	}
+
+	return logCommentModeration(ctx, tx, comment, entry)
+}
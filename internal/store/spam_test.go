@@ -0,0 +1,23 @@
+package store
+
+import "testing"
+
+func TestStricterReview(t *testing.T) {
+	tests := []struct {
+		current, screened, expected string
+	}{
+		{ReviewApproved, ReviewApproved, ReviewApproved},
+		{ReviewApproved, ReviewHeld, ReviewHeld},
+		{ReviewApproved, ReviewHidden, ReviewHidden},
+		{ReviewHeld, ReviewApproved, ReviewHeld},
+		{ReviewHeld, ReviewHidden, ReviewHidden},
+		{ReviewHidden, ReviewHeld, ReviewHidden},
+		{ReviewHidden, "", ReviewHidden},
+	}
+
+	for _, tt := range tests {
+		if got := stricterReview(tt.current, tt.screened); got != tt.expected {
+			t.Errorf("Expected %s for %s screened as %q. Got %s", tt.expected, tt.current, tt.screened, got)
+		}
+	}
+}
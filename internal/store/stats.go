@@ -0,0 +1,215 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// ViewImpression is a post shown in a feed; ViewDetail is a post opened
+	// on its own.
+	ViewImpression = "impression"
+	ViewDetail     = "view"
+)
+
+// PostView is one impression or detail view of a post by a user.
+type PostView struct {
+	PostID int64
+	UserID int64
+	Kind   string
+	SeenAt time.Time
+}
+
+type StatsBucket struct {
+	Hour        time.Time `json:"hour"`
+	Impressions int64     `json:"impressions"`
+	Views       int64     `json:"views"`
+}
+
+type PostStats struct {
+	PostID      int64         `json:"post_id"`
+	Impressions int64         `json:"impressions"`
+	Views       int64         `json:"views"`
+	Hourly      []StatsBucket `json:"hourly"`
+}
+
+type PostStatsSummary struct {
+	PostID      int64  `json:"post_id"`
+	Title       string `json:"title"`
+	Impressions int64  `json:"impressions"`
+	Views       int64  `json:"views"`
+}
+
+type UserStats struct {
+	Impressions int64              `json:"impressions"`
+	Views       int64              `json:"views"`
+	Hourly      []StatsBucket      `json:"hourly"`
+	TopPosts    []PostStatsSummary `json:"top_posts"`
+}
+
+type StatsStore struct {
+	db *pgxpool.Pool
+}
+
+// RecordViews adds views to the hourly buckets of their posts. A view is
+// only counted if the same user was not counted for the same post and kind
+// within window. Views of posts that no longer exist are dropped.
+func (s *StatsStore) RecordViews(ctx context.Context, views []PostView, window time.Duration) error {
+	if len(views) == 0 {
+		return nil
+	}
+
+	query := `
+		WITH events AS (
+			SELECT DISTINCT ON (e.post_id, e.user_id, e.kind) e.post_id, e.user_id, e.kind, e.seen_at
+			FROM unnest($1::bigint[], $2::bigint[], $3::text[], $4::timestamptz[]) AS e(post_id, user_id, kind, seen_at)
+			JOIN posts p ON p.id = e.post_id
+			ORDER BY e.post_id, e.user_id, e.kind, e.seen_at
+		), counted AS (
+			INSERT INTO post_view_dedup (post_id, user_id, kind, seen_at)
+			SELECT post_id, user_id, kind, seen_at FROM events
+			ON CONFLICT (post_id, user_id, kind) DO UPDATE SET seen_at = EXCLUDED.seen_at
+			WHERE post_view_dedup.seen_at <= EXCLUDED.seen_at - $5::float8 * interval '1 second'
+			RETURNING post_id, kind, seen_at
+		)
+		INSERT INTO post_stats_hourly (post_id, hour, impressions, views)
+		SELECT
+			post_id,
+			date_trunc('hour', seen_at),
+			count(*) FILTER (WHERE kind = 'impression'),
+			count(*) FILTER (WHERE kind = 'view')
+		FROM counted
+		GROUP BY 1, 2
+		ON CONFLICT (post_id, hour) DO UPDATE SET
+			impressions = post_stats_hourly.impressions + EXCLUDED.impressions,
+			views = post_stats_hourly.views + EXCLUDED.views
+	`
+
+	postIDs := make([]int64, len(views))
+	userIDs := make([]int64, len(views))
+	kinds := make([]string, len(views))
+	seenAt := make([]time.Time, len(views))
+	for i, v := range views {
+		postIDs[i], userIDs[i], kinds[i], seenAt[i] = v.PostID, v.UserID, v.Kind, v.SeenAt
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.Exec(ctx, query, postIDs, userIDs, kinds, seenAt, window.Seconds())
+	return err
+}
+
+// PruneViewDedup forgets the views older than window, which can no longer
+// suppress a new one.
+func (s *StatsStore) PruneViewDedup(ctx context.Context, window time.Duration) (int64, error) {
+	query := `DELETE FROM post_view_dedup WHERE seen_at < $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.Exec(ctx, query, time.Now().Add(-window))
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected(), nil
+}
+
+// GetPostStats returns the all-time totals of the post and its hourly
+// buckets since the given time.
+func (s *StatsStore) GetPostStats(ctx context.Context, postID int64, since time.Time) (*PostStats, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	stats := &PostStats{PostID: postID}
+
+	query := `
+		SELECT COALESCE(sum(impressions), 0)::bigint, COALESCE(sum(views), 0)::bigint
+		FROM post_stats_hourly
+		WHERE post_id = $1
+	`
+	if err := s.db.QueryRow(ctx, query, postID).Scan(&stats.Impressions, &stats.Views); err != nil {
+		return nil, err
+	}
+
+	query = `
+		SELECT hour, impressions, views
+		FROM post_stats_hourly
+		WHERE post_id = $1 AND hour >= $2
+		ORDER BY hour
+	`
+	rows, err := s.db.Query(ctx, query, postID, since)
+	if err != nil {
+		return nil, err
+	}
+
+	stats.Hourly, err = pgx.CollectRows(rows, pgx.RowToStructByPos[StatsBucket])
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// GetUserStats returns the all-time totals over the posts of userID, their
+// combined hourly buckets since the given time and the limit posts with the
+// most views.
+func (s *StatsStore) GetUserStats(ctx context.Context, userID int64, since time.Time, limit int) (*UserStats, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	stats := &UserStats{}
+
+	query := `
+		SELECT COALESCE(sum(s.impressions), 0)::bigint, COALESCE(sum(s.views), 0)::bigint
+		FROM post_stats_hourly s
+		JOIN posts p ON p.id = s.post_id
+		WHERE p.user_id = $1 AND p.deleted_at IS NULL
+	`
+	if err := s.db.QueryRow(ctx, query, userID).Scan(&stats.Impressions, &stats.Views); err != nil {
+		return nil, err
+	}
+
+	query = `
+		SELECT s.hour, sum(s.impressions)::bigint, sum(s.views)::bigint
+		FROM post_stats_hourly s
+		JOIN posts p ON p.id = s.post_id
+		WHERE p.user_id = $1 AND p.deleted_at IS NULL AND s.hour >= $2
+		GROUP BY s.hour
+		ORDER BY s.hour
+	`
+	rows, err := s.db.Query(ctx, query, userID, since)
+	if err != nil {
+		return nil, err
+	}
+
+	stats.Hourly, err = pgx.CollectRows(rows, pgx.RowToStructByPos[StatsBucket])
+	if err != nil {
+		return nil, err
+	}
+
+	query = `
+		SELECT p.id, p.title, sum(s.impressions)::bigint, sum(s.views)::bigint
+		FROM post_stats_hourly s
+		JOIN posts p ON p.id = s.post_id
+		WHERE p.user_id = $1 AND p.deleted_at IS NULL
+		GROUP BY p.id
+		ORDER BY sum(s.views) DESC, sum(s.impressions) DESC, p.id DESC
+		LIMIT $2
+	`
+	rows, err = s.db.Query(ctx, query, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	stats.TopPosts, err = pgx.CollectRows(rows, pgx.RowToStructByPos[PostStatsSummary])
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
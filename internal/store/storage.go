@@ -5,6 +5,7 @@ import (
 	"errors"
 	"time"
 
+	"github.com/AlfanDutaPamungkas/Go-Social/internal/spam"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -12,6 +13,7 @@ import (
 var (
 	ErrNotFound          = errors.New("record not found")
 	ErrConflict          = errors.New("resource already exists")
+	ErrVersionConflict   = errors.New("resource was modified by another request")
 	QueryTimeoutDuration = time.Second * 5
 )
 
@@ -19,9 +21,24 @@ type Storage struct {
 	Posts interface {
 		Create(context.Context, *Post) error
 		GetByID(context.Context, int64) (*Post, error)
-		Delete(context.Context, int64) error
+		Delete(ctx context.Context, postID int64, version int, deletedBy int64) error
+		GetDeletedByID(context.Context, int64) (*Post, error)
+		Restore(context.Context, int64) error
+		PurgeDeleted(ctx context.Context, retention time.Duration, limit int) ([]int64, error)
 		Update(context.Context, *Post) error
+		SetContentWarning(context.Context, *Post, *ModerationEntry) error
+		SetCommentsLocked(context.Context, *Post, *ModerationEntry) error
 		GetUserFeed(context.Context, int64, *PaginatedFeedQuery) ([]PostWithMetadata, error)
+		GetRankedFeed(ctx context.Context, userID int64, asOf time.Time, r FeedRanking, p *PaginatedFeedQuery) ([]PostWithMetadata, error)
+		GetByUserID(context.Context, int64, int64, *PaginatedFeedQuery) ([]PostWithMetadata, error)
+		GetPinnedByUserID(context.Context, int64, int64, *PaginatedFeedQuery) ([]PostWithMetadata, error)
+		Pin(ctx context.Context, userID, postID int64, position, limit int) error
+		Unpin(ctx context.Context, userID, postID int64) error
+		GetDraftsByUserID(context.Context, int64) ([]Post, error)
+		PublishDue(context.Context, int) ([]int64, error)
+		GetTimelineEntries(ctx context.Context, authorIDs []int64, limit int) ([]TimelineEntry, error)
+		GetFeedByIDs(ctx context.Context, viewerID int64, ids []int64) ([]PostWithMetadata, error)
+		GetByAuthorIDs(ctx context.Context, viewerID int64, authorIDs []int64, p *PaginatedFeedQuery) ([]PostWithMetadata, error)
 	}
 
 	Users interface {
@@ -31,30 +48,104 @@ type Storage struct {
 		Activate(context.Context, string) error
 		Delete(context.Context, int64) error
 		GetByEmail(context.Context, string) (*User, error)
+		UpdatePreferences(context.Context, int64, *UserPreferences) error
 	}
 
 	Comments interface {
 		Create(context.Context, *Comment) error
-		GetCommentsByPostID(ctx context.Context, postID int64) ([]Comment, error)
+		GetPage(ctx context.Context, postID, viewerID int64, q *PaginatedCommentQuery) ([]Comment, error)
+		CountByPostID(ctx context.Context, postID, viewerID int64) (int, error)
+		GetByID(ctx context.Context, postID, commentID int64) (*Comment, error)
+		GetSubtree(ctx context.Context, postID, commentID, viewerID int64) (*Comment, error)
+		Update(context.Context, *Comment, *ModerationEntry) error
+		Delete(context.Context, *Comment, *ModerationEntry) error
 	}
 
 	Followers interface {
 		Follow(ctx context.Context, followerID, userID int64) error
 		Unfollow(ctx context.Context, followerID, userID int64) error
+		IsFollowing(ctx context.Context, followerID, userID int64) (bool, error)
+		CountFollowers(ctx context.Context, userID int64) (int, error)
+		GetFollowerIDs(ctx context.Context, userID, afterID int64, limit int) ([]int64, error)
+		GetFolloweeIDs(ctx context.Context, userID int64, minFollowers int) ([]int64, error)
 	}
 
 	Roles interface {
 		GetByName(context.Context, string) (*Role, error)
 	}
+
+	Polls interface {
+		GetByPostID(ctx context.Context, postID, viewerID int64) (*Poll, error)
+		Vote(ctx context.Context, pollID, userID int64, optionIDs []int64) error
+	}
+
+	Media interface {
+		Create(ctx context.Context, userID int64, media *Media) error
+		GetByKey(ctx context.Context, key string) (*Media, error)
+		DeleteOrphans(ctx context.Context, ttl time.Duration, limit int) ([]Media, error)
+	}
+
+	LinkPreviews interface {
+		GetPending(ctx context.Context, retryAfter time.Duration, limit int) ([]string, error)
+		Save(context.Context, *LinkPreview) error
+	}
+
+	Explore interface {
+		GetTrendingTags(context.Context, TrendingQuery) ([]TrendingTag, error)
+		GetPopularPosts(context.Context, time.Duration, int) ([]PostWithMetadata, error)
+	}
+
+	Notifications interface {
+		GetByUserID(context.Context, int64, int) ([]Notification, error)
+		MarkRead(context.Context, int64, int64) error
+	}
+
+	Stats interface {
+		RecordViews(context.Context, []PostView, time.Duration) error
+		PruneViewDedup(context.Context, time.Duration) (int64, error)
+		GetPostStats(ctx context.Context, postID int64, since time.Time) (*PostStats, error)
+		GetUserStats(ctx context.Context, userID int64, since time.Time, limit int) (*UserStats, error)
+	}
+
+	Idempotency interface {
+		Reserve(context.Context, *IdempotencyRecord, time.Duration, time.Duration) (*IdempotencyRecord, error)
+		Complete(context.Context, *IdempotencyRecord) error
+		Release(ctx context.Context, userID int64, key string) error
+		DeleteExpired(context.Context, time.Duration) (int64, error)
+	}
+
+	Spam interface {
+		CountDuplicates(ctx context.Context, authorID int64, text string, since time.Time, edit spam.Edit) (int, error)
+		CountRecent(ctx context.Context, authorID int64, since time.Time, edit spam.Edit) (int, error)
+		GetHeld(ctx context.Context, status string, limit int) ([]HeldContent, error)
+		Review(ctx context.Context, kind string, id int64, status string, entry *ModerationEntry) error
+	}
+
+	Timelines interface {
+		Add(ctx context.Context, entry TimelineEntry, userIDs []int64) error
+		Backfill(ctx context.Context, userID int64, entries []TimelineEntry) error
+		Remove(ctx context.Context, entries []TimelineEntry, userIDs []int64) error
+		Page(ctx context.Context, userID int64, cursor *Cursor, limit int) ([]TimelineEntry, error)
+		Trim(ctx context.Context, maxLength int) (int64, error)
+	}
 }
 
 func NewStorage(db *pgxpool.Pool) Storage {
 	return Storage{
-		Posts:     &PostStore{db},
-		Users:     &UsersStore{db},
-		Comments:  &CommentStore{db},
-		Followers: &FollowerStore{db},
-		Roles:     &RoleStore{db},
+		Posts:         &PostStore{db},
+		Users:         &UsersStore{db},
+		Comments:      &CommentStore{db},
+		Followers:     &FollowerStore{db},
+		Roles:         &RoleStore{db},
+		Polls:         &PollStore{db},
+		Media:         &MediaStore{db},
+		LinkPreviews:  &LinkPreviewStore{db},
+		Explore:       &ExploreStore{db},
+		Notifications: &NotificationStore{db},
+		Stats:         &StatsStore{db},
+		Idempotency:   &IdempotencyStore{db},
+		Spam:          &SpamStore{db},
+		Timelines:     &TimelineStore{db},
 	}
 }
 
@@ -0,0 +1,274 @@
+package store
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// TimelineEntry is a post in a home timeline. Timelines are ordered by
+// (CreatedAt, PostID) like the feed, so cursors work across both.
+type TimelineEntry struct {
+	PostID    int64
+	CreatedAt time.Time
+}
+
+// TimelineStore keeps home timelines in Postgres. Storage.Timelines is
+// swapped for the Redis store when Redis is enabled.
+type TimelineStore struct {
+	db *pgxpool.Pool
+}
+
+// Add puts entry in the timelines of userIDs.
+func (s *TimelineStore) Add(ctx context.Context, entry TimelineEntry, userIDs []int64) error {
+	query := `
+		INSERT INTO timeline_entries (user_id, post_id, created_at)
+		SELECT unnest($1::bigint[]), $2, $3
+		ON CONFLICT DO NOTHING
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.Exec(ctx, query, userIDs, entry.PostID, entry.CreatedAt)
+	return err
+}
+
+// Backfill puts entries in the timeline of userID.
+func (s *TimelineStore) Backfill(ctx context.Context, userID int64, entries []TimelineEntry) error {
+	query := `
+		INSERT INTO timeline_entries (user_id, post_id, created_at)
+		SELECT $1, unnest($2::bigint[]), unnest($3::timestamptz[])
+		ON CONFLICT DO NOTHING
+	`
+
+	postIDs := make([]int64, len(entries))
+	createdAt := make([]time.Time, len(entries))
+	for i, entry := range entries {
+		postIDs[i] = entry.PostID
+		createdAt[i] = entry.CreatedAt
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.Exec(ctx, query, userID, postIDs, createdAt)
+	return err
+}
+
+// Remove takes entries out of the timelines of userIDs.
+func (s *TimelineStore) Remove(ctx context.Context, entries []TimelineEntry, userIDs []int64) error {
+	query := `DELETE FROM timeline_entries WHERE user_id = ANY($1) AND post_id = ANY($2)`
+
+	postIDs := make([]int64, len(entries))
+	for i, entry := range entries {
+		postIDs[i] = entry.PostID
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.Exec(ctx, query, userIDs, postIDs)
+	return err
+}
+
+// Page returns up to limit entries of the timeline of userID after cursor,
+// newest first. A Prev cursor returns the entries just before it instead.
+func (s *TimelineStore) Page(ctx context.Context, userID int64, cursor *Cursor, limit int) ([]TimelineEntry, error) {
+	query := `SELECT post_id, created_at FROM timeline_entries WHERE user_id = $1`
+	args := []any{userID}
+
+	order := "DESC"
+	if cursor != nil {
+		op := "<"
+		if cursor.Prev {
+			op, order = ">", "ASC"
+		}
+
+		args = append(args, cursor.CreatedAt, cursor.ID)
+		query += fmt.Sprintf(" AND (created_at, post_id) %s ($2, $3)", op)
+	}
+
+	query += fmt.Sprintf(" ORDER BY created_at %[1]s, post_id %[1]s LIMIT $%[2]d", order, len(args)+1)
+	args = append(args, limit)
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := pgx.CollectRows(rows, scanTimelineEntry)
+	if err != nil {
+		return nil, err
+	}
+
+	if cursor != nil && cursor.Prev {
+		slices.Reverse(entries)
+	}
+
+	return entries, nil
+}
+
+func scanTimelineEntry(row pgx.CollectableRow) (TimelineEntry, error) {
+	var entry TimelineEntry
+	err := row.Scan(&entry.PostID, &entry.CreatedAt)
+	return entry, err
+}
+
+// Trim drops the entries past the newest maxLength of every timeline and
+// returns how many it dropped.
+func (s *TimelineStore) Trim(ctx context.Context, maxLength int) (int64, error) {
+	query := `
+		DELETE FROM timeline_entries t
+		USING (
+			SELECT user_id, post_id, row_number() OVER (
+				PARTITION BY user_id ORDER BY created_at DESC, post_id DESC
+			) AS position
+			FROM timeline_entries
+		) ranked
+		WHERE ranked.position > $1 AND t.user_id = ranked.user_id AND t.post_id = ranked.post_id
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.Exec(ctx, query, maxLength)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected(), nil
+}
+
+// GetTimelineEntries returns the latest limit published posts of
+// authorIDs that are not awaiting review, to backfill timelines with.
+func (s *PostStore) GetTimelineEntries(ctx context.Context, authorIDs []int64, limit int) ([]TimelineEntry, error) {
+	query := `
+		SELECT id, created_at FROM posts
+		WHERE user_id = ANY($1) AND status = 'published' AND deleted_at IS NULL AND review_status = 'approved'
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.Query(ctx, query, authorIDs, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, scanTimelineEntry)
+}
+
+// GetFeedByIDs loads the posts of a timeline page, leaving out the ones
+// that were deleted or that viewerID may not see. The posts come back in
+// no particular order.
+func (s *PostStore) GetFeedByIDs(ctx context.Context, viewerID int64, ids []int64) ([]PostWithMetadata, error) {
+	query := `
+	SELECT
+		p.id, p.user_id, p.title, p.content, p.created_at, p.version, p.tags, p.entities, p.visibility, p.content_warning, p.sensitive, p.comment_policy, p.comments_locked,
+		` + postMedia + `, ` + postLinkPreview + `,
+		u.username,
+		count(c.id) AS comments_count
+	FROM posts p
+	JOIN users u ON p.user_id = u.id
+	LEFT JOIN comments c ON c.post_id = p.id AND ` + commentVisibleTo("$1") + `
+	WHERE p.id = ANY($2) AND p.status = 'published' AND p.deleted_at IS NULL AND ` + visibleToViewer + `
+	GROUP BY p.id, u.username
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.Query(ctx, query, viewerID, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanPostsWithMetadata(rows)
+}
+
+// GetByAuthorIDs is GetUserFeed restricted to the posts of authorIDs. It
+// reads the accounts that are too large to be fanned out into timelines.
+func (s *PostStore) GetByAuthorIDs(ctx context.Context, viewerID int64, authorIDs []int64, p *PaginatedFeedQuery) ([]PostWithMetadata, error) {
+	query := `
+	SELECT
+		p.id, p.user_id, p.title, p.content, p.created_at, p.version, p.tags, p.entities, p.visibility, p.content_warning, p.sensitive, p.comment_policy, p.comments_locked,
+		` + postMedia + `, ` + postLinkPreview + `,
+		u.username,
+		count(c.id) AS comments_count
+	FROM posts p
+	JOIN users u ON p.user_id = u.id
+	LEFT JOIN comments c ON c.post_id = p.id AND ` + commentVisibleTo("$1") + `
+	WHERE p.user_id = ANY($2) AND p.status = 'published' AND p.deleted_at IS NULL AND ` + visibleToViewer
+
+	args := []any{viewerID, authorIDs}
+
+	conditions, args := p.filters(args)
+	conditions, args = p.cursorCondition(conditions, args)
+	if len(conditions) > 0 {
+		query += " AND " + strings.Join(conditions, " AND ")
+	}
+
+	query += fmt.Sprintf(" GROUP BY p.id, u.username ORDER BY p.created_at %[1]s, p.id %[1]s LIMIT $%[2]d", p.order(), len(args)+1)
+	args = append(args, p.Limit)
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	posts, err := scanPostsWithMetadata(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.pageOrder(posts), nil
+}
+
+// MergePages merges pages fetched for p from different sources into one
+// page of at most p.Limit posts, in the order of p.Sort. A post found in
+// several pages is kept once.
+func (p *PaginatedFeedQuery) MergePages(pages ...[]PostWithMetadata) []PostWithMetadata {
+	seen := make(map[int64]bool)
+	merged := []PostWithMetadata{}
+	for _, page := range pages {
+		for _, post := range page {
+			if !seen[post.ID] {
+				seen[post.ID] = true
+				merged = append(merged, post)
+			}
+		}
+	}
+
+	slices.SortFunc(merged, func(a, b PostWithMetadata) int {
+		c := cmp.Or(a.CreatedAt.Compare(b.CreatedAt), cmp.Compare(a.ID, b.ID))
+		if p.Sort == "asc" {
+			return c
+		}
+		return -c
+	})
+
+	if len(merged) <= p.Limit {
+		return merged
+	}
+
+	// A page before a cursor ends at the cursor.
+	if p.Cursor != nil && p.Cursor.Prev {
+		return merged[len(merged)-p.Limit:]
+	}
+	return merged[:p.Limit]
+}
@@ -26,6 +26,14 @@ type User struct {
 	IsActive  bool      `json:"is_active"`
 	Role_id   int64     `json:"role_id"`
 	Role      Role      `json:"role"`
+	// Preferences are the user's own settings. They are only returned by
+	// the preferences endpoints.
+	Preferences UserPreferences `json:"-"`
+}
+
+type UserPreferences struct {
+	// SensitiveContent is SensitiveContentHide or SensitiveContentExpand.
+	SensitiveContent string `json:"sensitive_content"`
 }
 
 type password struct {
@@ -93,7 +101,7 @@ func (s *UsersStore) Create(ctx context.Context, tx pgx.Tx, user *User) error {
 
 func (s *UsersStore) GetByID(ctx context.Context, id int64) (*User, error) {
 	query := `
-		SELECT users.id, username, email, created_at, roles.*
+		SELECT users.id, username, email, created_at, sensitive_content, roles.*
 		FROM users
 		JOIN roles ON (users.role_id = roles.id)
 		WHERE users.id = $1 AND is_active = true
@@ -112,6 +120,7 @@ func (s *UsersStore) GetByID(ctx context.Context, id int64) (*User, error) {
 		&user.Username,
 		&user.Email,
 		&user.CreatedAt,
+		&user.Preferences.SensitiveContent,
 		&user.Role.ID,
 		&user.Role.Name,
 		&user.Role.Level,
@@ -130,6 +139,24 @@ func (s *UsersStore) GetByID(ctx context.Context, id int64) (*User, error) {
 	return &user, err
 }
 
+func (s *UsersStore) UpdatePreferences(ctx context.Context, userID int64, prefs *UserPreferences) error {
+	query := `UPDATE users SET sensitive_content = $1 WHERE id = $2 AND is_active = true`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.Exec(ctx, query, prefs.SensitiveContent, userID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
 func (s *UsersStore) CreateAndInvite(ctx context.Context, user *User, token string, invitationExp time.Duration) error {
 	return withTx(s.db, ctx, func(tx pgx.Tx) error {
 		if err := s.Create(ctx, tx, user); err != nil {
@@ -0,0 +1,194 @@
+package unfurl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/og", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, `<!doctype html><html><head>
+			<title>Fallback title</title>
+			<meta property="og:title" content="Gophers">
+			<meta name="twitter:title" content="Twitter title">
+			<meta name="twitter:description" content="All about gophers">
+			<meta property="og:image" content="/img/gopher.png">
+			<meta property="og:site_name" content="Go">
+			</head><body><meta property="og:description" content="in body"></body></html>`)
+	})
+	mux.HandleFunc("/plain", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><head><title>Plain page</title><meta name="description" content="Just a page"></head></html>`)
+	})
+	mux.HandleFunc("/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{}`)
+	})
+	mux.HandleFunc("/large", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html><head>"+strings.Repeat("<!-- padding -->", 1000)+`<title>Too far</title></head></html>`)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	})
+	mux.HandleFunc("/redirect/{n}", func(w http.ResponseWriter, r *http.Request) {
+		var n int
+		fmt.Sscan(r.PathValue("n"), &n)
+		if n == 0 {
+			http.Redirect(w, r, "/plain", http.StatusFound)
+			return
+		}
+		http.Redirect(w, r, fmt.Sprintf("/redirect/%d", n-1), http.StatusFound)
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func newTestUnfurler() *Unfurler {
+	return New(Config{
+		Timeout:              200 * time.Millisecond,
+		MaxBodyBytes:         4 << 10,
+		MaxRedirects:         3,
+		AllowPrivateNetworks: true,
+	})
+}
+
+func TestUnfurl(t *testing.T) {
+	srv := newTestServer(t)
+	u := newTestUnfurler()
+	ctx := context.Background()
+
+	t.Run("should prefer open graph tags", func(t *testing.T) {
+		preview, err := u.Unfurl(ctx, srv.URL+"/og")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := Preview{
+			URL:         srv.URL + "/og",
+			Title:       "Gophers",
+			Description: "All about gophers",
+			ImageURL:    srv.URL + "/img/gopher.png",
+			SiteName:    "Go",
+		}
+		if *preview != want {
+			t.Errorf("Expected %+v. Got %+v", want, *preview)
+		}
+	})
+
+	t.Run("should fall back to the title and description", func(t *testing.T) {
+		preview, err := u.Unfurl(ctx, srv.URL+"/plain")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if preview.Title != "Plain page" || preview.Description != "Just a page" {
+			t.Errorf("Expected the plain title and description. Got %+v", *preview)
+		}
+	})
+
+	t.Run("should follow a few redirects", func(t *testing.T) {
+		preview, err := u.Unfurl(ctx, srv.URL+"/redirect/2")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if preview.Title != "Plain page" {
+			t.Errorf("Expected %q. Got %q", "Plain page", preview.Title)
+		}
+	})
+
+	t.Run("should stop after too many redirects", func(t *testing.T) {
+		_, err := u.Unfurl(ctx, srv.URL+"/redirect/5")
+		if !errors.Is(err, ErrTooManyRedirects) {
+			t.Errorf("Expected %v. Got %v", ErrTooManyRedirects, err)
+		}
+	})
+
+	t.Run("should reject responses that are not html", func(t *testing.T) {
+		_, err := u.Unfurl(ctx, srv.URL+"/json")
+		if !errors.Is(err, ErrNotHTML) {
+			t.Errorf("Expected %v. Got %v", ErrNotHTML, err)
+		}
+	})
+
+	t.Run("should only read up to the body size cap", func(t *testing.T) {
+		preview, err := u.Unfurl(ctx, srv.URL+"/large")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if preview.Title != "" {
+			t.Errorf("Expected no title past the size cap. Got %q", preview.Title)
+		}
+	})
+
+	t.Run("should time out on slow servers", func(t *testing.T) {
+		if _, err := u.Unfurl(ctx, srv.URL+"/slow"); err == nil {
+			t.Error("Expected a timeout error")
+		}
+	})
+
+	t.Run("should reject other schemes", func(t *testing.T) {
+		_, err := u.Unfurl(ctx, "file:///etc/passwd")
+		if !errors.Is(err, ErrUnsupportedURL) {
+			t.Errorf("Expected %v. Got %v", ErrUnsupportedURL, err)
+		}
+	})
+}
+
+func TestUnfurlBlocksPrivateNetworks(t *testing.T) {
+	srv := newTestServer(t)
+
+	u := New(Config{
+		Timeout:      200 * time.Millisecond,
+		MaxBodyBytes: 4 << 10,
+		MaxRedirects: 3,
+	})
+
+	_, err := u.Unfurl(context.Background(), srv.URL+"/og")
+	if !errors.Is(err, ErrBlockedAddress) {
+		t.Errorf("Expected %v. Got %v", ErrBlockedAddress, err)
+	}
+}
+
+func TestIsPublic(t *testing.T) {
+	tests := map[string]bool{
+		"8.8.8.8":         true,
+		"2001:4860::8888": true,
+		"127.0.0.1":       false,
+		"10.1.2.3":        false,
+		"172.16.0.1":      false,
+		"192.168.1.1":     false,
+		"169.254.169.254": false,
+		"100.64.0.1":      false,
+		"0.0.0.0":         false,
+		"::1":             false,
+		"fe80::1":         false,
+		"fc00::1":         false,
+		"::ffff:10.0.0.1": false,
+	}
+
+	for addr, want := range tests {
+		if got := isPublic(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("isPublic(%s) = %v. Expected %v", addr, got, want)
+		}
+	}
+}